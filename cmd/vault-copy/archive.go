@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"vault-copy/internal/archive"
+	"vault-copy/internal/logger"
+	"vault-copy/internal/render"
+	"vault-copy/internal/sync"
+)
+
+// formatArchive is the export format of an encrypted archive
+const formatArchive = "archive"
+
+// passphraseEnv is the environment variable holding the archive passphrase when --passphrase-file is not given
+const passphraseEnv = "VAULT_COPY_PASSPHRASE"
+
+// runExport writes the source subtree to an encrypted archive, or to a flattened document with --format.
+// The archive is written to a temporary file renamed on success, so an aborted export leaves nothing behind.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	conn := registerConnectionFlags(fs)
+	file := fs.String("file", "", "File to write (required for an archive, standard output for other formats by default)")
+	passphraseFile := fs.String("passphrase-file", "", "File with the archive passphrase (environment variable "+passphraseEnv+" will be used by default)")
+	format := fs.String("format", formatArchive, "Output format: archive, dotenv, json, yaml, properties or k8s")
+	keyPrefixPath := fs.Bool("key-prefix-path", false, "Prefix keys with the secret path relative to --src-path")
+	keyPrefix := fs.String("key-prefix", "", "Prefix prepended to every key")
+	keyUpper := fs.Bool("key-upper", false, "Upper-case the keys")
+	keySeparator := fs.String("key-separator", render.DefaultKeySeparator, "Separator between the path segments and the field of a key")
+	outDir := fs.String("out-dir", "", "Directory to write one Kubernetes manifest per secret to, for the k8s format")
+	k8sNamespace := fs.String("k8s-namespace", "", "Namespace of the Kubernetes Secrets")
+	k8sNamePrefix := fs.String("k8s-name-prefix", "", "Prefix of the Kubernetes Secret names")
+	k8sLabels := fs.String("k8s-labels", "", "Comma-separated key=value labels added to the Kubernetes Secrets")
+	verbose := fs.Bool("v", false, "Enable verbose output")
+
+	fs.Parse(args)
+
+	if *format == render.FormatKubernetes {
+		if *conn.srcPath == "" {
+			fmt.Fprintln(os.Stderr, "export requires --src-path")
+			fs.Usage()
+			os.Exit(exitConfigError)
+		}
+		labels, err := parseLabels(*k8sLabels)
+		if err != nil {
+			fatal(exitConfigError, "Configuration error: %v", err)
+		}
+		exportManifests(conn, *file, *outDir, *verbose, render.ManifestOptions{
+			Namespace:  *k8sNamespace,
+			NamePrefix: *k8sNamePrefix,
+			Labels:     labels,
+		})
+		return
+	}
+
+	if *format != formatArchive {
+		if *conn.srcPath == "" {
+			fmt.Fprintln(os.Stderr, "export requires --src-path")
+			fs.Usage()
+			os.Exit(exitConfigError)
+		}
+		exportDocument(conn, *format, *file, *verbose, render.KeyOptions{
+			PrefixPath: *keyPrefixPath,
+			Prefix:     *keyPrefix,
+			Upper:      *keyUpper,
+			Separator:  *keySeparator,
+		})
+		return
+	}
+
+	if *conn.srcPath == "" || *file == "" {
+		fmt.Fprintln(os.Stderr, "export requires --src-path and --file")
+		fs.Usage()
+		os.Exit(exitConfigError)
+	}
+
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		fatal(exitConfigError, "Configuration error: %v", err)
+	}
+
+	cfg, err := conn.newSourceConfig(*verbose)
+	if err != nil {
+		fatal(exitConfigError, "Configuration error: %v", err)
+	}
+
+	sourceClient, err := newSourceClient(cfg)
+	if err != nil {
+		fatal(exitConfigError, "%v", err)
+	}
+
+	tmpFile := *file + ".tmp"
+	out, err := os.OpenFile(tmpFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		fatal(exitFailure, "Error creating archive: %v", err)
+	}
+
+	// The first SIGINT/SIGTERM stops the export, a second one exits at once
+	ctx, stop := signalContext(func() {
+		out.Close()
+		os.Remove(tmpFile)
+	})
+	stats, err := archive.Export(ctx, sourceClient, archive.Info{
+		SourceAddr:      cfg.SourceAddr,
+		SourceNamespace: cfg.SourceNamespace,
+		RootPath:        cfg.SourcePath,
+	}, out, passphrase, logger.NewLogger(cfg))
+	interrupted := ctx.Err() != nil
+	stop()
+
+	if closeErr := out.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("error writing archive: %v", closeErr)
+	}
+	if err == nil {
+		err = os.Rename(tmpFile, *file)
+	}
+	if err != nil {
+		os.Remove(tmpFile)
+		if interrupted {
+			fatal(exitInterrupted, "Export interrupted, no archive was written")
+		}
+		fatal(exitFailure, "Export error: %v", err)
+	}
+
+	fmt.Printf("\nExport completed:\n")
+	fmt.Printf("Archive: %s\n", *file)
+	fmt.Printf("Secrets exported: %d\n", stats.SecretsExported)
+	fmt.Printf("Secrets with metadata: %d\n", stats.MetadataExported)
+}
+
+// runImport restores an archive written by export into the destination Vault.
+// The archive is decrypted and verified completely before anything is written,
+// then its secrets go through the same write path as a copy whose source is the archive.
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	conn := registerConnectionFlags(fs)
+	file := fs.String("file", "", "Archive file to restore (required)")
+	passphraseFile := fs.String("passphrase-file", "", "File with the archive passphrase (environment variable "+passphraseEnv+" will be used by default)")
+	dryRun := fs.Bool("dry-run", false, "Show what would be restored without actually writing")
+	overwrite := fs.Bool("overwrite", false, "Overwrite existing secrets (disabled by default)")
+	parallel := fs.Int("parallel", 5, "Number of parallel operations")
+	copyMetadata := fs.Bool("copy-metadata", false, "Restore the KV v2 metadata stored in the archive")
+	failOnSkip := fs.Bool("fail-on-skip", false, "Exit with the partial failure code when secrets were skipped because they already exist")
+	verbose := fs.Bool("v", false, "Enable verbose output")
+
+	fs.Parse(args)
+
+	if *file == "" || *conn.dstPath == "" {
+		fmt.Fprintln(os.Stderr, "import requires --file and --dst-path")
+		fs.Usage()
+		os.Exit(exitConfigError)
+	}
+
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		fatal(exitConfigError, "Configuration error: %v", err)
+	}
+
+	source, err := openArchive(*file, passphrase)
+	if err != nil {
+		fatal(exitFailure, "Error reading archive: %v", err)
+	}
+	info := source.Info()
+	log.Printf("Archive %s: %d secrets exported from %s at %s", *file, source.Count(), info.RootPath, info.CreatedAt.Format(time.RFC3339))
+
+	// The whole archive is restored unless --src-path selects a part of it
+	sourcePath := *conn.srcPath
+	if sourcePath == "" {
+		sourcePath = info.RootPath
+	}
+
+	cfg, err := conn.newDestinationConfig(sourcePath, *dryRun, *overwrite, *verbose, *parallel)
+	if err != nil {
+		fatal(exitConfigError, "Configuration error: %v", err)
+	}
+	cfg.SourceAddr = *file
+	cfg.CopyMetadata = *copyMetadata
+	if err := cfg.Validate(); err != nil {
+		fatal(exitConfigError, "Configuration error: %v", err)
+	}
+
+	destClient, err := newDestinationClient(cfg)
+	if err != nil {
+		fatal(exitConfigError, "%v", err)
+	}
+
+	syncManager := sync.NewManager(source, destClient, cfg)
+
+	// The first SIGINT/SIGTERM lets in-flight secrets finish, a second one exits at once
+	ctx, stop := signalContext(func() {
+		if stats := syncManager.Progress(); stats != nil {
+			fmt.Printf("\nImport aborted:\n")
+			printStats(cfg, stats)
+		}
+	})
+	stats, err := syncManager.Sync(ctx)
+	stop()
+
+	if stats == nil {
+		stats = syncManager.Progress()
+	}
+	code := copyExitCode(stats, err, *failOnSkip)
+
+	if errors.Is(err, sync.ErrInterrupted) {
+		fmt.Printf("\nImport interrupted:\n")
+		printStats(cfg, stats)
+		os.Exit(code)
+	}
+	if err != nil {
+		fatal(code, "Import error: %v", err)
+	}
+
+	fmt.Printf("\nImport completed:\n")
+	printStats(cfg, stats)
+	if *dryRun {
+		fmt.Println("\nDry-run mode - nothing was written")
+	}
+	os.Exit(code)
+}
+
+// openArchive decrypts and verifies the archive in filename
+func openArchive(filename, passphrase string) (*archive.Source, error) {
+	in, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	r, err := archive.NewReader(in, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return archive.NewSource(r)
+}
+
+// readPassphrase returns the archive passphrase from filename, or from VAULT_COPY_PASSPHRASE when filename is empty.
+// A trailing newline in the file is ignored.
+func readPassphrase(filename string) (string, error) {
+	if filename == "" {
+		passphrase := os.Getenv(passphraseEnv)
+		if passphrase == "" {
+			return "", errors.New("archive passphrase not found. Set --passphrase-file or " + passphraseEnv)
+		}
+		return passphrase, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("error reading passphrase file: %v", err)
+	}
+
+	passphrase := strings.TrimRight(string(data), "\r\n")
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase file %s is empty", filename)
+	}
+	return passphrase, nil
+}
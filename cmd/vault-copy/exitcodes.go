@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"vault-copy/internal/sync"
+)
+
+// Exit codes of vault-copy, documented in the README
+const (
+	// exitSuccess means every secret was copied, or the diff found no differences
+	exitSuccess = 0
+	// exitFailure means the run was aborted by an error or no secret could be copied
+	exitFailure = 1
+	// exitConfigError means invalid flags or configuration, or a failed Vault login
+	exitConfigError = 2
+	// exitPartialFailure means some secrets failed, or were skipped with --fail-on-skip
+	exitPartialFailure = 3
+	// exitDifferences means the diff found differences between the subtrees
+	exitDifferences = 4
+	// exitInterrupted means the run was stopped by SIGINT or SIGTERM, as for shells
+	exitInterrupted = 130
+)
+
+// copyExitCode returns the exit code of a copy that ended with stats and the error returned by Sync
+func copyExitCode(stats *sync.SyncStats, err error, failOnSkip bool) int {
+	switch {
+	case errors.Is(err, sync.ErrInterrupted):
+		return exitInterrupted
+	case err != nil:
+		return exitFailure
+	case stats == nil:
+		return exitSuccess
+	case stats.Errors > 0 && stats.Completed() == 0:
+		return exitFailure
+	case stats.Errors > 0:
+		return exitPartialFailure
+	case failOnSkip && stats.SecretsSkipped > 0:
+		return exitPartialFailure
+	}
+	return exitSuccess
+}
+
+// fatal logs the message and exits with code
+func fatal(code int, format string, v ...interface{}) {
+	log.Printf(format, v...)
+	os.Exit(code)
+}
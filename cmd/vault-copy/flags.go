@@ -0,0 +1,382 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/filesystem"
+	"vault-copy/internal/vault"
+)
+
+// connectionFlags holds the command line flags shared by all commands:
+// the paths and the connection and auth settings of both Vaults.
+type connectionFlags struct {
+	configFile *string
+	srcPath    *string
+	dstPath    *string
+	src        *string
+	dst        *string
+
+	sourceAddr          *string
+	sourceToken         *string
+	sourceNamespace     *string
+	sourceAuthMethod    *string
+	sourceAuthMount     *string
+	sourceRoleID        *string
+	sourceSecretID      *string
+	sourceSecretIDFile  *string
+	sourceK8sRole       *string
+	sourceJWTPath       *string
+	sourceCACert        *string
+	sourceCAPath        *string
+	sourceClientCert    *string
+	sourceClientKey     *string
+	sourceTLSServerName *string
+	sourceTLSSkipVerify *bool
+
+	destAddr          *string
+	destToken         *string
+	destNamespace     *string
+	destAuthMethod    *string
+	destAuthMount     *string
+	destRoleID        *string
+	destSecretID      *string
+	destSecretIDFile  *string
+	destK8sRole       *string
+	destJWTPath       *string
+	destCACert        *string
+	destCAPath        *string
+	destClientCert    *string
+	destClientKey     *string
+	destTLSServerName *string
+	destTLSSkipVerify *bool
+
+	retryMaxAttempts *int
+	retryBaseDelay   *time.Duration
+	retryMaxDelay    *time.Duration
+	retryJitter      *float64
+	retryOn          *string
+
+	rate        *float64
+	sourceRate  *float64
+	destRate    *float64
+	readWorkers *int
+}
+
+// registerConnectionFlags defines the shared flags on fs
+func registerConnectionFlags(fs *flag.FlagSet) *connectionFlags {
+	f := &connectionFlags{}
+
+	f.configFile = fs.String("config", "config.yaml", "Path to config file")
+	f.srcPath = fs.String("src-path", "", "Source secret or directory path (required)")
+	f.dstPath = fs.String("dst-path", "", "Destination path in target Vault (required)")
+	f.src = fs.String("src", "", "Source backend URL: vault://host:port or vault+http://host:port for a Vault, file://dir for a directory of JSON or YAML secret files; replaces --src-addr")
+	f.dst = fs.String("dst", "", "Destination backend URL: vault://host:port or vault+http://host:port for a Vault, file://dir[?format=yaml] for a directory of secret files; replaces --dst-addr")
+
+	// Source Vault flags
+	f.sourceAddr = fs.String("src-addr", "", "Source Vault URL (environment variable VAULT_SOURCE_ADDR will be used by default)")
+	f.sourceToken = fs.String("src-token", "", "Source Vault token (environment variable VAULT_SOURCE_TOKEN will be used by default)")
+	f.sourceNamespace = fs.String("src-namespace", "", "Source Vault Enterprise namespace (environment variable VAULT_SOURCE_NAMESPACE will be used by default)")
+	f.sourceAuthMethod = fs.String("src-auth-method", "", "Source Vault auth method: token, approle or kubernetes (environment variable VAULT_SOURCE_AUTH_METHOD will be used by default)")
+	f.sourceAuthMount = fs.String("src-auth-mount", "", "Source Vault auth method mount path, defaults to the method name (environment variable VAULT_SOURCE_AUTH_MOUNT will be used by default)")
+	f.sourceRoleID = fs.String("src-role-id", "", "Source Vault AppRole role_id (environment variable VAULT_SOURCE_ROLE_ID will be used by default)")
+	f.sourceSecretID = fs.String("src-secret-id", "", "Source Vault AppRole secret_id (environment variable VAULT_SOURCE_SECRET_ID will be used by default)")
+	f.sourceSecretIDFile = fs.String("src-secret-id-file", "", "File with the source Vault AppRole secret_id (environment variable VAULT_SOURCE_SECRET_ID_FILE will be used by default)")
+	f.sourceK8sRole = fs.String("src-k8s-role", "", "Source Vault Kubernetes auth role (environment variable VAULT_SOURCE_KUBERNETES_ROLE will be used by default)")
+	f.sourceJWTPath = fs.String("src-k8s-jwt-path", "", "Source Vault Kubernetes service account token path (environment variable VAULT_SOURCE_KUBERNETES_JWT_PATH will be used by default)")
+	f.sourceCACert = fs.String("src-ca-cert", "", "PEM CA bundle to verify the source Vault certificate (environment variable VAULT_SOURCE_CACERT will be used by default)")
+	f.sourceCAPath = fs.String("src-ca-path", "", "Directory of PEM CA certificates to verify the source Vault certificate (environment variable VAULT_SOURCE_CAPATH will be used by default)")
+	f.sourceClientCert = fs.String("src-client-cert", "", "Client certificate for mutual TLS with the source Vault (environment variable VAULT_SOURCE_CLIENT_CERT will be used by default)")
+	f.sourceClientKey = fs.String("src-client-key", "", "Client certificate key for mutual TLS with the source Vault (environment variable VAULT_SOURCE_CLIENT_KEY will be used by default)")
+	f.sourceTLSServerName = fs.String("src-tls-server-name", "", "SNI host name for the source Vault (environment variable VAULT_SOURCE_TLS_SERVER_NAME will be used by default)")
+	f.sourceTLSSkipVerify = fs.Bool("src-tls-skip-verify", false, "Disable source Vault certificate verification (environment variable VAULT_SOURCE_SKIP_VERIFY will be used by default)")
+
+	// Destination Vault flags
+	f.destAddr = fs.String("dst-addr", "", "Destination Vault URL (environment variable VAULT_DEST_ADDR will be used by default)")
+	f.destToken = fs.String("dst-token", "", "Destination Vault token (environment variable VAULT_DEST_TOKEN will be used by default)")
+	f.destNamespace = fs.String("dst-namespace", "", "Destination Vault Enterprise namespace (environment variable VAULT_DEST_NAMESPACE will be used by default)")
+	f.destAuthMethod = fs.String("dst-auth-method", "", "Destination Vault auth method: token, approle or kubernetes (environment variable VAULT_DEST_AUTH_METHOD will be used by default)")
+	f.destAuthMount = fs.String("dst-auth-mount", "", "Destination Vault auth method mount path, defaults to the method name (environment variable VAULT_DEST_AUTH_MOUNT will be used by default)")
+	f.destRoleID = fs.String("dst-role-id", "", "Destination Vault AppRole role_id (environment variable VAULT_DEST_ROLE_ID will be used by default)")
+	f.destSecretID = fs.String("dst-secret-id", "", "Destination Vault AppRole secret_id (environment variable VAULT_DEST_SECRET_ID will be used by default)")
+	f.destSecretIDFile = fs.String("dst-secret-id-file", "", "File with the destination Vault AppRole secret_id (environment variable VAULT_DEST_SECRET_ID_FILE will be used by default)")
+	f.destK8sRole = fs.String("dst-k8s-role", "", "Destination Vault Kubernetes auth role (environment variable VAULT_DEST_KUBERNETES_ROLE will be used by default)")
+	f.destJWTPath = fs.String("dst-k8s-jwt-path", "", "Destination Vault Kubernetes service account token path (environment variable VAULT_DEST_KUBERNETES_JWT_PATH will be used by default)")
+	f.destCACert = fs.String("dst-ca-cert", "", "PEM CA bundle to verify the destination Vault certificate (environment variable VAULT_DEST_CACERT will be used by default)")
+	f.destCAPath = fs.String("dst-ca-path", "", "Directory of PEM CA certificates to verify the destination Vault certificate (environment variable VAULT_DEST_CAPATH will be used by default)")
+	f.destClientCert = fs.String("dst-client-cert", "", "Client certificate for mutual TLS with the destination Vault (environment variable VAULT_DEST_CLIENT_CERT will be used by default)")
+	f.destClientKey = fs.String("dst-client-key", "", "Client certificate key for mutual TLS with the destination Vault (environment variable VAULT_DEST_CLIENT_KEY will be used by default)")
+	f.destTLSServerName = fs.String("dst-tls-server-name", "", "SNI host name for the destination Vault (environment variable VAULT_DEST_TLS_SERVER_NAME will be used by default)")
+	f.destTLSSkipVerify = fs.Bool("dst-tls-skip-verify", false, "Disable destination Vault certificate verification (environment variable VAULT_DEST_SKIP_VERIFY will be used by default)")
+
+	// Retry flags, shared by both Vaults
+	retry := config.DefaultRetryConfig()
+	f.retryMaxAttempts = fs.Int("retry-max-attempts", retry.MaxAttempts, "Total attempts per Vault request on transient errors, 1 disables retries")
+	f.retryBaseDelay = fs.Duration("retry-base-delay", retry.BaseDelay, "Delay before the first retry, doubled on every further retry")
+	f.retryMaxDelay = fs.Duration("retry-max-delay", retry.MaxDelay, "Maximum delay between retries, also caps Retry-After")
+	f.retryJitter = fs.Float64("retry-jitter", retry.Jitter, "Fraction (0-1) of each retry delay that is randomised")
+	f.retryOn = fs.String("retry-on", strings.Join(retry.RetryOn, ","), "Comma-separated retryable status codes and classes: codes like 503, 5xx and network")
+
+	f.readWorkers = fs.Int("read-parallel", config.DefaultReadWorkers, "Number of concurrent readers listing folders and reading secrets while walking a folder")
+	f.rate = fs.Float64("rate", 0, "Maximum requests per second sent to each Vault, 0 disables the limit")
+	f.sourceRate = fs.Float64("src-rate", 0, "Maximum requests per second sent to the source Vault, 0 uses --rate (environment variable VAULT_SOURCE_RATE will be used by default)")
+	f.destRate = fs.Float64("dst-rate", 0, "Maximum requests per second sent to the destination Vault, 0 uses --rate (environment variable VAULT_DEST_RATE will be used by default)")
+
+	return f
+}
+
+// newConfig creates the configuration from the shared flags and the given per-command settings
+func (f *connectionFlags) newConfig(recursive, dryRun, overwrite, verbose bool, parallel int) (*config.Config, error) {
+	sourceAddr, err := backendAddr("src", *f.src, *f.sourceAddr)
+	if err != nil {
+		return nil, err
+	}
+	destAddr, err := backendAddr("dst", *f.dst, *f.destAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.NewConfig(
+		*f.srcPath,
+		*f.dstPath,
+		recursive,
+		dryRun,
+		overwrite,
+		verbose,
+		parallel,
+		sourceAddr,
+		*f.sourceToken,
+		destAddr,
+		*f.destToken,
+		config.AuthConfig{
+			Method:         *f.sourceAuthMethod,
+			MountPath:      *f.sourceAuthMount,
+			RoleID:         *f.sourceRoleID,
+			SecretID:       *f.sourceSecretID,
+			SecretIDFile:   *f.sourceSecretIDFile,
+			KubernetesRole: *f.sourceK8sRole,
+			JWTPath:        *f.sourceJWTPath,
+		},
+		config.AuthConfig{
+			Method:         *f.destAuthMethod,
+			MountPath:      *f.destAuthMount,
+			RoleID:         *f.destRoleID,
+			SecretID:       *f.destSecretID,
+			SecretIDFile:   *f.destSecretIDFile,
+			KubernetesRole: *f.destK8sRole,
+			JWTPath:        *f.destJWTPath,
+		},
+		config.TLSConfig{
+			CACert:     *f.sourceCACert,
+			CAPath:     *f.sourceCAPath,
+			ClientCert: *f.sourceClientCert,
+			ClientKey:  *f.sourceClientKey,
+			ServerName: *f.sourceTLSServerName,
+			Insecure:   *f.sourceTLSSkipVerify,
+		},
+		config.TLSConfig{
+			CACert:     *f.destCACert,
+			CAPath:     *f.destCAPath,
+			ClientCert: *f.destClientCert,
+			ClientKey:  *f.destClientKey,
+			ServerName: *f.destTLSServerName,
+			Insecure:   *f.destTLSSkipVerify,
+		},
+		*f.sourceNamespace,
+		*f.destNamespace,
+		*f.configFile,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.applyClientSettings(cfg)
+}
+
+// newSourceConfig creates the configuration of a command that only reads from the source Vault
+func (f *connectionFlags) newSourceConfig(verbose bool) (*config.Config, error) {
+	sourceAddr, err := backendAddr("src", *f.src, *f.sourceAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.NewSourceConfig(
+		*f.srcPath,
+		verbose,
+		sourceAddr,
+		*f.sourceToken,
+		config.AuthConfig{
+			Method:         *f.sourceAuthMethod,
+			MountPath:      *f.sourceAuthMount,
+			RoleID:         *f.sourceRoleID,
+			SecretID:       *f.sourceSecretID,
+			SecretIDFile:   *f.sourceSecretIDFile,
+			KubernetesRole: *f.sourceK8sRole,
+			JWTPath:        *f.sourceJWTPath,
+		},
+		config.TLSConfig{
+			CACert:     *f.sourceCACert,
+			CAPath:     *f.sourceCAPath,
+			ClientCert: *f.sourceClientCert,
+			ClientKey:  *f.sourceClientKey,
+			ServerName: *f.sourceTLSServerName,
+			Insecure:   *f.sourceTLSSkipVerify,
+		},
+		*f.sourceNamespace,
+		*f.configFile,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.applyClientSettings(cfg)
+}
+
+// newDestinationConfig creates the configuration of a command that only writes to the destination Vault.
+// sourcePath is given by the caller, for example the root path of an archive.
+func (f *connectionFlags) newDestinationConfig(sourcePath string, dryRun, overwrite, verbose bool, parallel int) (*config.Config, error) {
+	destAddr, err := backendAddr("dst", *f.dst, *f.destAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.NewDestinationConfig(
+		sourcePath,
+		*f.dstPath,
+		dryRun,
+		overwrite,
+		verbose,
+		parallel,
+		destAddr,
+		*f.destToken,
+		config.AuthConfig{
+			Method:         *f.destAuthMethod,
+			MountPath:      *f.destAuthMount,
+			RoleID:         *f.destRoleID,
+			SecretID:       *f.destSecretID,
+			SecretIDFile:   *f.destSecretIDFile,
+			KubernetesRole: *f.destK8sRole,
+			JWTPath:        *f.destJWTPath,
+		},
+		config.TLSConfig{
+			CACert:     *f.destCACert,
+			CAPath:     *f.destCAPath,
+			ClientCert: *f.destClientCert,
+			ClientKey:  *f.destClientKey,
+			ServerName: *f.destTLSServerName,
+			Insecure:   *f.destTLSSkipVerify,
+		},
+		*f.destNamespace,
+		*f.configFile,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.applyClientSettings(cfg)
+}
+
+// backendAddr returns the address of one side: the --src or --dst backend URL, or else the --src-addr or --dst-addr Vault address
+func backendAddr(side, url, addr string) (string, error) {
+	if url != "" && addr != "" {
+		return "", fmt.Errorf("--%s and --%s-addr can't be used together", side, side)
+	}
+	if url != "" {
+		return url, nil
+	}
+	return addr, nil
+}
+
+// applyClientSettings sets the retry, rate and reader flags shared by both clients and validates cfg
+func (f *connectionFlags) applyClientSettings(cfg *config.Config) (*config.Config, error) {
+	cfg.Retry = config.RetryConfig{
+		MaxAttempts: *f.retryMaxAttempts,
+		BaseDelay:   *f.retryBaseDelay,
+		MaxDelay:    *f.retryMaxDelay,
+		Jitter:      *f.retryJitter,
+		RetryOn:     config.ParseRetryOn(*f.retryOn),
+	}
+	cfg.Rate = *f.rate
+	if *f.sourceRate != 0 {
+		cfg.SourceRate = *f.sourceRate
+	}
+	if *f.destRate != 0 {
+		cfg.DestRate = *f.destRate
+	}
+	cfg.ReadWorkers = *f.readWorkers
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// newClients creates the source and destination clients for cfg
+func newClients(cfg *config.Config) (vault.ClientInterface, vault.ClientInterface, error) {
+	sourceClient, err := newSourceClient(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	destClient, err := newDestinationClient(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sourceClient, destClient, nil
+}
+
+// newSourceClient creates the source client for cfg, a Vault client or a file backend
+func newSourceClient(cfg *config.Config) (vault.ClientInterface, error) {
+	if cfg.SourceBackend.Type == config.BackendFile {
+		client, err := filesystem.NewClient(cfg.SourceBackend.Dir, cfg.SourceBackend.Format)
+		if err != nil {
+			return nil, fmt.Errorf("error creating source file backend: %v", err)
+		}
+		return client, nil
+	}
+
+	client, err := vault.NewClientWithConfig(&vault.ClientConfig{
+		Addr:      cfg.SourceAddr,
+		Token:     cfg.SourceToken,
+		Auth:      cfg.SourceAuth,
+		TLS:       cfg.SourceTLS,
+		Namespace: cfg.SourceNamespace,
+		Retry:     cfg.Retry,
+		Rate:      cfg.SourceRequestRate(),
+		Readers:   cfg.ReadWorkers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating source Vault client: %v", err)
+	}
+	return client, nil
+}
+
+// newDestinationClient creates the destination client for cfg, a Vault client or a file backend
+func newDestinationClient(cfg *config.Config) (vault.ClientInterface, error) {
+	if cfg.DestBackend.Type == config.BackendFile {
+		client, err := filesystem.NewClient(cfg.DestBackend.Dir, cfg.DestBackend.Format)
+		if err != nil {
+			return nil, fmt.Errorf("error creating destination file backend: %v", err)
+		}
+		return client, nil
+	}
+
+	client, err := vault.NewClientWithConfig(&vault.ClientConfig{
+		Addr:      cfg.DestAddr,
+		Token:     cfg.DestToken,
+		Auth:      cfg.DestAuth,
+		TLS:       cfg.DestTLS,
+		Namespace: cfg.DestNamespace,
+		Retry:     cfg.Retry,
+		Rate:      cfg.DestRequestRate(),
+		Readers:   cfg.ReadWorkers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating destination Vault client: %v", err)
+	}
+	return client, nil
+}
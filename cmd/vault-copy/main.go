@@ -2,100 +2,272 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 
 	"vault-copy/internal/config"
 	"vault-copy/internal/sync"
-	"vault-copy/internal/vault"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "import":
+			runImport(os.Args[2:])
+			return
+		}
+	}
+
+	runCopy()
+}
+
+// runCopy copies secrets from the source to the destination, the default command
+func runCopy() {
 	// Parse command line arguments
-	configFile := flag.String("config", "config.yaml", "Path to config file")
-	srcPath := flag.String("src-path", "", "Source secret or directory path (required)")
-	dstPath := flag.String("dst-path", "", "Destination path in target Vault (required)")
+	conn := registerConnectionFlags(flag.CommandLine)
 	recursive := flag.Bool("recursive", false, "Recursively copy all secrets from folder (disabled by default)")
 	dryRun := flag.Bool("dry-run", false, "Show what would be copied without actually copying")
 	overwrite := flag.Bool("overwrite", false, "Overwrite existing secrets (disabled by default)")
 	parallel := flag.Int("parallel", 5, "Number of parallel operations")
+	adaptive := flag.Bool("adaptive-concurrency", false, "Lower the number of parallel operations while Vault answers 429 or slows down, and raise it again up to --parallel")
 	verbose := flag.Bool("v", false, "Enable verbose output")
-
-	// Source Vault flags
-	sourceAddr := flag.String("src-addr", "", "Source Vault URL (environment variable VAULT_SOURCE_ADDR will be used by default)")
-	sourceToken := flag.String("src-token", "", "Source Vault token (environment variable VAULT_SOURCE_TOKEN will be used by default)")
-
-	// Destination Vault flags
-	destAddr := flag.String("dst-addr", "", "Destination Vault URL (environment variable VAULT_DEST_ADDR will be used by default)")
-	destToken := flag.String("dst-token", "", "Destination Vault token (environment variable VAULT_DEST_TOKEN will be used by default)")
+	allVersions := flag.Bool("all-versions", false, "Copy the full KV v2 version history instead of only the latest version")
+	copyMetadata := flag.Bool("copy-metadata", false, "Copy KV v2 metadata: custom_metadata, max_versions, cas_required and delete_version_after")
+	deleteExtraneous := flag.Bool("delete-extraneous", false, "Delete destination secrets that don't exist in the source (mirror mode, requires --recursive)")
+	deleteMode := flag.String("delete-mode", config.DeleteModeSoft, "How extraneous KV v2 secrets are deleted: soft (delete latest version) or destroy (remove metadata and all versions)")
+	maxDeletes := flag.Int("max-deletes", config.DefaultMaxDeletes, "Abort mirror mode when more secrets would be deleted, 0 disables the limit")
+	stateFile := flag.String("state-file", "", "Record completed destination paths in this file so an interrupted copy can be resumed")
+	resume := flag.Bool("resume", false, "Skip the destination paths recorded in --state-file by a previous run of the same copy")
+	output := flag.String("output", outputText, "Summary format on stdout: text, json (one document at the end) or ndjson (one line per secret as it is processed)")
+	reportFile := flag.String("report", "", "Also write a machine-readable report with the statistics and every processed secret to this file")
+	reportFormat := flag.String("report-format", sync.ReportFormatJSON, "Format of the --report file: json or ndjson")
+	pathsFile := flag.String("paths-file", "", "Copy only the source paths listed in this file, one per line, for example the --failed-paths file of a previous run")
+	failedPaths := flag.String("failed-paths", "", "Write the source paths of the secrets that failed to this file, to be retried with --paths-file")
+	failOnSkip := flag.Bool("fail-on-skip", false, "Exit with the partial failure code when secrets were skipped because they already exist")
 
 	flag.Parse()
 
 	// Validate arguments
-	if *srcPath == "" || *dstPath == "" {
+	if *conn.srcPath == "" || *conn.dstPath == "" {
 		message := `
 example usage:
 
 export VAULT_SOURCE_TOKEN="source_token"
 export VAULT_SOURCE_ADDR="https://vault1:8200"
 
-./vault-sync --src-path="secret/data/apps/production" --dst-path="secret/data/backup/production" --recursive --parallel=10`
+./vault-sync --src-path="secret/data/apps/production" --dst-path="secret/data/backup/production" --recursive --parallel=10
+
+compare two subtrees without copying:
+
+./vault-sync diff --src-path="secret/data/apps/production" --dst-path="secret/data/backup/production"
+
+export a subtree to an encrypted archive:
+
+./vault-sync export --src-path="secret/data/apps/production" --file=production.vcx --passphrase-file=passphrase.txt
+
+export a subtree as environment variables:
+
+./vault-sync export --src-path="secret/data/apps/production" --format=dotenv --key-prefix-path --key-upper
+
+render Kubernetes Secret manifests:
+
+./vault-sync export --src-path="secret/data/apps/production" --format=k8s --k8s-namespace=production --out-dir=manifests
+
+restore an archive:
+
+./vault-sync import --file=production.vcx --passphrase-file=passphrase.txt --dst-path="secret/data/apps/production"`
 		fmt.Println("At least 2 parameters are required: --src-path and --dst-path, in this case secrets will be copied within VAULT_SOURCE_ADDR")
 		fmt.Println(message)
 		fmt.Println("enter --help for help")
-		os.Exit(1)
+		os.Exit(exitConfigError)
 	}
 
 	// Create configuration
-	cfg, err := config.NewConfig(
-		*srcPath,
-		*dstPath,
-		*recursive,
-		*dryRun,
-		*overwrite,
-		*verbose,
-		*parallel,
-		*sourceAddr,
-		*sourceToken,
-		*destAddr,
-		*destToken,
-		*configFile,
-	)
+	cfg, err := conn.newConfig(*recursive, *dryRun, *overwrite, *verbose, *parallel)
 	if err != nil {
-		log.Fatalf("Configuration error: %v", err)
+		fatal(exitConfigError, "Configuration error: %v", err)
+	}
+	cfg.AllVersions = *allVersions
+	cfg.CopyMetadata = *copyMetadata
+	cfg.DeleteExtraneous = *deleteExtraneous
+	cfg.DeleteMode = *deleteMode
+	cfg.MaxDeletes = *maxDeletes
+	cfg.StateFile = *stateFile
+	cfg.Resume = *resume
+	cfg.AdaptiveConcurrency = *adaptive
+	if *pathsFile != "" {
+		if cfg.Paths, err = config.ReadPathsFile(*pathsFile); err != nil {
+			fatal(exitConfigError, "Configuration error: %v", err)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		fatal(exitConfigError, "Configuration error: %v", err)
 	}
 
-	// Initialize Vault clients
-	sourceClient, err := vault.NewClient(cfg.SourceAddr, cfg.SourceToken)
+	// Open the machine-readable reports
+	reports, err := openReports(*output, *reportFile, *reportFormat)
 	if err != nil {
-		log.Fatalf("Error creating source Vault client: %v", err)
+		fatal(exitConfigError, "Configuration error: %v", err)
 	}
 
-	destClient, err := vault.NewClient(cfg.DestAddr, cfg.DestToken)
+	// Initialize Vault clients
+	sourceClient, destClient, err := newClients(cfg)
 	if err != nil {
-		log.Fatalf("Error creating destination Vault client: %v", err)
+		reports.finish(nil, err, cfg.DryRun)
+		reports.close()
+		fatal(exitConfigError, "%v", err)
 	}
 
 	// Create synchronization manager
 	syncManager := sync.NewManager(sourceClient, destClient, cfg)
+	syncManager.OnResult(reports.record)
 
-	// Perform synchronization
-	ctx := context.Background()
+	// Perform synchronization; the first SIGINT/SIGTERM lets in-flight secrets finish, a second one exits at once
+	ctx, stop := signalContext(func() {
+		stats := syncManager.Progress()
+		reports.finish(stats, sync.ErrInterrupted, cfg.DryRun)
+		writeFailedPaths(*failedPaths, stats)
+		if reports.text && stats != nil {
+			fmt.Printf("\nSynchronization aborted:\n")
+			printStats(cfg, stats)
+		}
+		printResumeState(reports.summaryOutput(), cfg)
+	})
 	stats, err := syncManager.Sync(ctx)
+	stop()
+
+	if stats == nil {
+		stats = syncManager.Progress()
+	}
+	reports.finish(stats, err, cfg.DryRun)
+	reports.close()
+	writeFailedPaths(*failedPaths, stats)
+
+	code := copyExitCode(stats, err, *failOnSkip)
+
+	if errors.Is(err, sync.ErrInterrupted) {
+		if reports.text {
+			fmt.Printf("\nSynchronization interrupted:\n")
+			printStats(cfg, stats)
+		}
+		printResumeState(reports.summaryOutput(), cfg)
+		os.Exit(code)
+	}
 	if err != nil {
-		log.Fatalf("Synchronization error: %v", err)
+		fatal(code, "Synchronization error: %v", err)
+	}
+
+	if reports.text {
+		// Output statistics
+		fmt.Printf("\nSynchronization completed:\n")
+		printStats(cfg, stats)
+
+		if *dryRun {
+			fmt.Println("\nDry-run mode - nothing was written")
+		}
 	}
 
-	// Output statistics
-	fmt.Printf("\nSynchronization completed:\n")
+	switch code {
+	case exitFailure:
+		log.Printf("ERROR: no secret could be copied, %d errors", stats.Errors)
+	case exitPartialFailure:
+		if stats.Errors > 0 {
+			log.Printf("ERROR: %d secrets failed", stats.Errors)
+		} else {
+			log.Printf("ERROR: %d secrets were skipped because they already exist (--fail-on-skip)", stats.SecretsSkipped)
+		}
+	}
+	os.Exit(code)
+}
+
+// printStats prints the synchronization statistics
+func printStats(cfg *config.Config, stats *sync.SyncStats) {
 	fmt.Printf("  Secrets read: %d\n", stats.SecretsRead)
 	fmt.Printf("  Secrets written: %d\n", stats.SecretsWritten)
 	fmt.Printf("  Skipped (already exist): %d\n", stats.SecretsSkipped)
+	fmt.Printf("  Unchanged: %d\n", stats.SecretsUnchanged)
+	if stats.SecretsSourceDeleted > 0 {
+		fmt.Printf("  Skipped (deleted in source): %d\n", stats.SecretsSourceDeleted)
+	}
+	if cfg.Resume {
+		fmt.Printf("  Completed by a previous run: %d\n", stats.SecretsResumed)
+	}
 	fmt.Printf("  Errors: %d\n", stats.Errors)
+	fmt.Printf("  Retries: %d\n", stats.Retries)
+	if cfg.AllVersions {
+		fmt.Printf("  Versions written: %d\n", stats.VersionsWritten)
+	}
+	if cfg.DeleteExtraneous {
+		fmt.Printf("  Deleted (extraneous): %d\n", stats.SecretsDeleted)
+	}
+
+	if failed := stats.Failed(); len(failed) > 0 {
+		fmt.Printf("\nFailed paths:\n")
+		for _, result := range failed {
+			path := result.SourcePath
+			if path == "" {
+				path = result.DestinationPath
+			}
+			fmt.Printf("  %s: %s\n", path, result.Error)
+		}
+	}
+}
+
+// printResumeState tells how to continue an interrupted copy when a state file is used
+func printResumeState(w io.Writer, cfg *config.Config) {
+	if cfg.StateFile == "" || cfg.DryRun {
+		return
+	}
+	fmt.Fprintf(w, "\nCompleted secrets are recorded in %s, rerun the same command with --resume to continue\n", cfg.StateFile)
+}
+
+// runDiff compares the source and destination subtrees and exits with exitDifferences when they differ.
+// Configuration and login errors exit with exitConfigError, failed comparisons with exitFailure.
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	conn := registerConnectionFlags(fs)
+	verbose := fs.Bool("v", false, "Enable verbose output")
+	showValues := fs.Bool("show-values", false, "Print secret values in the report instead of masking them")
+
+	fs.Parse(args)
+
+	if *conn.srcPath == "" || *conn.dstPath == "" {
+		fmt.Fprintln(os.Stderr, "diff requires --src-path and --dst-path")
+		fs.Usage()
+		os.Exit(exitConfigError)
+	}
+
+	cfg, err := conn.newConfig(true, true, false, *verbose, 1)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		os.Exit(exitConfigError)
+	}
+
+	sourceClient, destClient, err := newClients(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitConfigError)
+	}
+
+	result, err := sync.NewManager(sourceClient, destClient, cfg).Diff(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Comparison error: %v\n", err)
+		os.Exit(exitFailure)
+	}
+
+	result.Print(os.Stdout, *showValues)
 
-	if *dryRun {
-		fmt.Println("\nDry-run mode - nothing was written")
+	if result.HasDifferences() {
+		os.Exit(exitDifferences)
 	}
 }
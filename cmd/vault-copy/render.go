@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+	"vault-copy/internal/render"
+	"vault-copy/internal/vault"
+)
+
+// exportDocument flattens the source subtree into one document in format, written to file or to standard output.
+// The document is rendered in memory first, so a read error or a key collision writes nothing.
+func exportDocument(conn *connectionFlags, format, file string, verbose bool, opts render.KeyOptions) {
+	if err := render.CheckFormat(format); err != nil {
+		fatal(exitConfigError, "Configuration error: %v", err)
+	}
+
+	cfg, _, secrets := readExportSubtree(conn, verbose)
+
+	variables, err := render.Flatten(secrets, cfg.SourcePath, format, opts)
+	if err != nil {
+		fatal(exitFailure, "Export error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := render.Write(&buf, format, variables); err != nil {
+		fatal(exitFailure, "Export error: %v", err)
+	}
+	if err := writeOutput(file, buf.Bytes()); err != nil {
+		fatal(exitFailure, "Export error: %v", err)
+	}
+
+	if file != "" && file != "-" {
+		fmt.Printf("\nExport completed:\n")
+		fmt.Printf("File: %s\n", file)
+		fmt.Printf("Secrets exported: %d\n", len(secrets))
+		fmt.Printf("Keys written: %d\n", len(variables))
+	}
+}
+
+// exportManifests renders every secret of the source subtree as a Kubernetes Secret manifest,
+// one file per Secret in outDir, or a multi-document stream written to file or to standard output.
+func exportManifests(conn *connectionFlags, file, outDir string, verbose bool, opts render.ManifestOptions) {
+	if file != "" && outDir != "" {
+		fatal(exitConfigError, "Configuration error: --file and --out-dir can't be used together")
+	}
+	if err := opts.Validate(); err != nil {
+		fatal(exitConfigError, "Configuration error: %v", err)
+	}
+
+	cfg, sourceClient, secrets := readExportSubtree(conn, verbose)
+	opts.KVVersion = kvVersion(sourceClient, cfg.SourcePath, logger.NewLogger(cfg))
+
+	manifests, err := render.Manifests(secrets, cfg.SourcePath, opts)
+	if err != nil {
+		fatal(exitFailure, "Export error: %v", err)
+	}
+
+	if outDir == "" {
+		var buf bytes.Buffer
+		if err := render.WriteManifests(&buf, manifests); err != nil {
+			fatal(exitFailure, "Export error: %v", err)
+		}
+		if err := writeOutput(file, buf.Bytes()); err != nil {
+			fatal(exitFailure, "Export error: %v", err)
+		}
+		if file == "" || file == "-" {
+			return
+		}
+	} else {
+		if err := os.MkdirAll(outDir, 0700); err != nil {
+			fatal(exitFailure, "Export error: %v", err)
+		}
+		for _, manifest := range manifests {
+			var buf bytes.Buffer
+			if err := render.WriteManifests(&buf, []*render.Manifest{manifest}); err != nil {
+				fatal(exitFailure, "Export error: %v", err)
+			}
+			if err := writeOutput(filepath.Join(outDir, manifest.Metadata.Name+".yaml"), buf.Bytes()); err != nil {
+				fatal(exitFailure, "Export error: %v", err)
+			}
+		}
+	}
+
+	fmt.Printf("\nExport completed:\n")
+	if outDir != "" {
+		fmt.Printf("Directory: %s\n", outDir)
+	} else {
+		fmt.Printf("File: %s\n", file)
+	}
+	fmt.Printf("Secrets exported: %d\n", len(secrets))
+	fmt.Printf("Manifests written: %d\n", len(manifests))
+}
+
+// readExportSubtree connects to the source and reads the whole subtree at --src-path
+func readExportSubtree(conn *connectionFlags, verbose bool) (*config.Config, vault.ClientInterface, []*vault.Secret) {
+	cfg, err := conn.newSourceConfig(verbose)
+	if err != nil {
+		fatal(exitConfigError, "Configuration error: %v", err)
+	}
+
+	sourceClient, err := newSourceClient(cfg)
+	if err != nil {
+		fatal(exitConfigError, "%v", err)
+	}
+
+	// Nothing is written before the subtree has been read, so a second signal just exits
+	ctx, stop := signalContext(func() {})
+	secrets, err := render.ReadSubtree(ctx, sourceClient, cfg.SourcePath, logger.NewLogger(cfg))
+	interrupted := ctx.Err() != nil
+	stop()
+	if err != nil {
+		if interrupted {
+			fatal(exitInterrupted, "Export interrupted, nothing was written")
+		}
+		fatal(exitFailure, "Export error: %v", err)
+	}
+
+	return cfg, sourceClient, secrets
+}
+
+// kvVersion returns the KV engine version of path, 0 when it is unknown, as for a directory source
+func kvVersion(client vault.ClientInterface, path string, logger *logger.Logger) int {
+	version, err := client.GetKVEngineVersion(path, logger)
+	if err != nil {
+		logger.Verbose("KV version of %s unknown: %v", path, err)
+		return 0
+	}
+	return version
+}
+
+// parseLabels parses comma-separated key=value pairs
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// writeOutput writes data to standard output when file is empty or "-", otherwise to file with mode 0600.
+// The file is written to a temporary file renamed on success, so a failed write leaves no partial file behind.
+func writeOutput(file string, data []byte) error {
+	if file == "" || file == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	tmpFile := file + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("error writing %s: %v", file, err)
+	}
+	if err := os.Rename(tmpFile, file); err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("error writing %s: %v", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"vault-copy/internal/sync"
+)
+
+// outputText is the default --output, the human-readable summary
+const outputText = "text"
+
+// reports holds the machine-readable reports of a run:
+// stdout for --output json or ndjson, and the --report file.
+type reports struct {
+	writers []*sync.ReportWriter
+	file    *os.File
+	// text is set when the human-readable summary is printed on stdout
+	text bool
+}
+
+// openReports creates the reports requested by the --output, --report and --report-format flags
+func openReports(output, reportFile, reportFormat string) (*reports, error) {
+	r := &reports{text: output == outputText}
+
+	if !r.text {
+		writer, err := sync.NewReportWriter(os.Stdout, output)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --output: %v", err)
+		}
+		r.writers = append(r.writers, writer)
+	}
+
+	if reportFile != "" {
+		// Check the format before creating the file
+		if _, err := sync.NewReportWriter(io.Discard, reportFormat); err != nil {
+			return nil, fmt.Errorf("invalid --report-format: %v", err)
+		}
+
+		file, err := os.Create(reportFile)
+		if err != nil {
+			return nil, fmt.Errorf("error creating report file: %v", err)
+		}
+		writer, _ := sync.NewReportWriter(file, reportFormat)
+		r.writers = append(r.writers, writer)
+		r.file = file
+	}
+
+	return r, nil
+}
+
+// record adds the result of a secret to all reports
+func (r *reports) record(result sync.SecretResult) {
+	for _, writer := range r.writers {
+		writer.Record(result)
+	}
+}
+
+// finish writes the outcome of the run to all reports.
+// Only the first call writes, so it is safe to call from the signal handler as well.
+func (r *reports) finish(stats *sync.SyncStats, runErr error, dryRun bool) {
+	for _, writer := range r.writers {
+		if err := writer.Finish(stats, runErr, dryRun); err != nil {
+			log.Printf("ERROR: error writing report: %v", err)
+		}
+	}
+}
+
+// close closes the report file
+func (r *reports) close() {
+	if r.file == nil {
+		return
+	}
+	if err := r.file.Close(); err != nil {
+		log.Printf("ERROR: error writing report: %v", err)
+	}
+}
+
+// summaryOutput returns where the human-readable messages go: stdout, unless it carries a report
+func (r *reports) summaryOutput() io.Writer {
+	if r.text {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
+// writeFailedPaths writes the source paths of the failed secrets to filename, one per line,
+// in the format read by --paths-file. Nothing is written when filename is empty.
+func writeFailedPaths(filename string, stats *sync.SyncStats) {
+	if filename == "" || stats == nil {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("# Source paths that failed, retry them with --paths-file\n")
+	for _, path := range stats.FailedPaths() {
+		b.WriteString(path + "\n")
+	}
+
+	if err := os.WriteFile(filename, []byte(b.String()), 0600); err != nil {
+		log.Printf("ERROR: error writing failed paths: %v", err)
+	}
+}
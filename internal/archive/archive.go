@@ -0,0 +1,137 @@
+// Package archive reads and writes encrypted vault-copy archives holding an exported Vault subtree.
+//
+// An archive starts with the magic "VCX" and the format version byte, followed by the
+// length-prefixed JSON header with the key derivation and cipher parameters. The rest is a
+// sequence of AES-256-GCM sealed chunks, each prefixed with a final flag byte and the ciphertext
+// length. The key is derived from a passphrase with scrypt. Chunk nonces are built from a random
+// prefix, the chunk counter and the final flag, and the header is authenticated with every chunk,
+// so a modified header, a reordered, modified or missing chunk and a truncated archive are detected.
+//
+// The decrypted stream holds one JSON record per line: the archive info first, then the secrets,
+// and finally the manifest with the number of secrets and the SHA-256 of every secret record.
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"vault-copy/internal/vault"
+)
+
+// FormatVersion is the version of the archive format written by this package
+const FormatVersion = 1
+
+const (
+	// magic identifies a vault-copy archive, it is followed by the format version byte
+	magic = "VCX"
+	// kdfScrypt is the only supported key derivation function
+	kdfScrypt = "scrypt"
+	// cipherAESGCM is the only supported cipher
+	cipherAESGCM = "aes-256-gcm"
+	// chunkSize is the size of the plaintext sealed in one chunk
+	chunkSize = 64 * 1024
+	// keySize is the size of the AES-256 key
+	keySize = 32
+	// saltSize is the size of the random scrypt salt
+	saltSize = 16
+	// noncePrefixSize is the size of the random part of the chunk nonces,
+	// followed by the 4-byte chunk counter and the final flag byte
+	noncePrefixSize = 7
+	// maxHeaderSize bounds the header read before the passphrase can be checked
+	maxHeaderSize = 4096
+)
+
+// Record types of the decrypted stream
+const (
+	recordInfo     = "info"
+	recordSecret   = "secret"
+	recordManifest = "manifest"
+)
+
+// Default scrypt cost parameters, as recommended for interactive use: about 32 MiB and 100 ms per key
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// ErrNotArchive is returned when the input doesn't start with the archive magic
+var ErrNotArchive = errors.New("not a vault-copy archive")
+
+// ErrDecrypt is returned when the first chunk can't be decrypted
+var ErrDecrypt = errors.New("wrong passphrase or corrupted archive")
+
+// Info describes the exported subtree, it is the first record of an archive
+type Info struct {
+	// SourceAddr is the address of the Vault the secrets were exported from
+	SourceAddr string `json:"source_addr"`
+	// SourceNamespace is the namespace the secrets were exported from
+	SourceNamespace string `json:"source_namespace,omitempty"`
+	// RootPath is the exported path, the secret paths are under it
+	RootPath string `json:"root_path"`
+	// KVVersion is the version of the KV engine the secrets were read from, 0 when unknown
+	KVVersion int `json:"kv_version"`
+	// CreatedAt is when the export started
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Entry is one exported secret
+type Entry struct {
+	// Path is the full path of the secret in the source Vault
+	Path string `json:"path"`
+	// Data contains the secret's key-value pairs
+	Data map[string]interface{} `json:"data"`
+	// Metadata holds the KV v2 metadata settings, nil for KV v1 secrets
+	Metadata *vault.SecretMetadata `json:"metadata,omitempty"`
+}
+
+// Manifest lists the exported secrets, it is the last record of an archive
+type Manifest struct {
+	// Count is the number of secrets in the archive
+	Count int `json:"count"`
+	// Secrets maps the path of every secret to the hex SHA-256 of its record line
+	Secrets map[string]string `json:"secrets"`
+}
+
+// record is one line of the decrypted stream
+type record struct {
+	Type     string    `json:"type"`
+	Info     *Info     `json:"info,omitempty"`
+	Secret   *Entry    `json:"secret,omitempty"`
+	Manifest *Manifest `json:"manifest,omitempty"`
+}
+
+// header holds the key derivation and cipher parameters, stored in clear before the chunks
+type header struct {
+	KDF         string `json:"kdf"`
+	Salt        []byte `json:"salt"`
+	N           int    `json:"n"`
+	R           int    `json:"r"`
+	P           int    `json:"p"`
+	Cipher      string `json:"cipher"`
+	NoncePrefix []byte `json:"nonce_prefix"`
+}
+
+// validate checks that the header uses supported parameters with bounded costs
+func (h *header) validate() error {
+	if h.KDF != kdfScrypt {
+		return fmt.Errorf("unsupported key derivation %q", h.KDF)
+	}
+	if h.Cipher != cipherAESGCM {
+		return fmt.Errorf("unsupported cipher %q", h.Cipher)
+	}
+	if h.N < 1<<10 || h.N > 1<<20 || h.N&(h.N-1) != 0 {
+		return fmt.Errorf("invalid scrypt parameter N=%d", h.N)
+	}
+	if h.R < 1 || h.R > 32 || h.P < 1 || h.P > 16 {
+		return fmt.Errorf("invalid scrypt parameters r=%d p=%d", h.R, h.P)
+	}
+	if len(h.Salt) < saltSize {
+		return errors.New("scrypt salt is too short")
+	}
+	if len(h.NoncePrefix) != noncePrefixSize {
+		return errors.New("invalid nonce prefix")
+	}
+	return nil
+}
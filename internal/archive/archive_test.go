@@ -0,0 +1,213 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"vault-copy/internal/vault"
+)
+
+const testPassphrase = "correct horse battery staple"
+
+// writeArchive writes an archive with the given entries and returns its bytes
+func writeArchive(t *testing.T, entries ...*Entry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, testPassphrase, Info{
+		SourceAddr: "https://vault:8200",
+		RootPath:   "secret/data/apps",
+		KVVersion:  2,
+		CreatedAt:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	for _, entry := range entries {
+		if err := w.WriteSecret(entry); err != nil {
+			t.Fatalf("WriteSecret(%s) error = %v", entry.Path, err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	entries := []*Entry{
+		{
+			Path: "secret/data/apps/db",
+			Data: map[string]interface{}{"password": "s3cr3t", "port": json.Number("5432")},
+			Metadata: &vault.SecretMetadata{
+				CustomMetadata: map[string]string{"owner": "team-a"},
+				MaxVersions:    5,
+			},
+		},
+		{Path: "secret/data/apps/cache", Data: map[string]interface{}{"url": "redis://cache"}},
+	}
+
+	data := writeArchive(t, entries...)
+
+	if bytes.Contains(data, []byte("s3cr3t")) || bytes.Contains(data, []byte("secret/data/apps")) {
+		t.Error("archive contains plaintext secrets or paths")
+	}
+
+	r, err := NewReader(bytes.NewReader(data), testPassphrase)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+
+	info := r.Info()
+	if info.RootPath != "secret/data/apps" || info.KVVersion != 2 || info.SourceAddr != "https://vault:8200" {
+		t.Errorf("Info() = %+v", info)
+	}
+
+	got, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("got %d secrets, want %d", len(got), len(entries))
+	}
+
+	if got[0].Path != "secret/data/apps/db" || got[0].Data["password"] != "s3cr3t" || got[0].Data["port"] != json.Number("5432") {
+		t.Errorf("first secret = %+v", got[0])
+	}
+	if got[0].Metadata == nil || got[0].Metadata.MaxVersions != 5 || got[0].Metadata.CustomMetadata["owner"] != "team-a" {
+		t.Errorf("first secret metadata = %+v", got[0].Metadata)
+	}
+	if got[1].Metadata != nil {
+		t.Errorf("second secret metadata = %+v, want nil", got[1].Metadata)
+	}
+
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next() after the end error = %v, want io.EOF", err)
+	}
+}
+
+func TestArchiveLargeSecrets(t *testing.T) {
+	// Secrets spanning several chunks
+	var entries []*Entry
+	for i := 0; i < 20; i++ {
+		entries = append(entries, &Entry{
+			Path: fmt.Sprintf("secret/data/apps/app%d", i),
+			Data: map[string]interface{}{"blob": strings.Repeat("x", 10000*i)},
+		})
+	}
+
+	r, err := NewReader(bytes.NewReader(writeArchive(t, entries...)), testPassphrase)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+
+	got, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("got %d secrets, want %d", len(got), len(entries))
+	}
+	for i, entry := range got {
+		if len(entry.Data["blob"].(string)) != 10000*i {
+			t.Errorf("secret %s has %d bytes, want %d", entry.Path, len(entry.Data["blob"].(string)), 10000*i)
+		}
+	}
+}
+
+func TestArchiveWrongPassphrase(t *testing.T) {
+	data := writeArchive(t, &Entry{Path: "secret/data/apps/db", Data: map[string]interface{}{"key": "value"}})
+
+	if _, err := NewReader(bytes.NewReader(data), "wrong"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("NewReader() error = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestArchiveNotArchive(t *testing.T) {
+	if _, err := NewReader(strings.NewReader(`{"secret": "plain"}`), testPassphrase); !errors.Is(err, ErrNotArchive) {
+		t.Errorf("NewReader() error = %v, want ErrNotArchive", err)
+	}
+}
+
+func TestArchiveTampering(t *testing.T) {
+	var entries []*Entry
+	for i := 0; i < 10; i++ {
+		entries = append(entries, &Entry{
+			Path: fmt.Sprintf("secret/data/apps/app%d", i),
+			Data: map[string]interface{}{"blob": strings.Repeat("y", 20000)},
+		})
+	}
+	data := writeArchive(t, entries...)
+	headerEnd := bytes.IndexByte(data, '}') + 1
+
+	tests := []struct {
+		name   string
+		modify func([]byte) []byte
+	}{
+		{"truncated", func(b []byte) []byte { return b[:len(b)-100] }},
+		{"final chunk removed", func(b []byte) []byte { return b[:len(b)-40] }},
+		{"flipped ciphertext bit", func(b []byte) []byte { b[len(b)/2] ^= 1; return b }},
+		{"modified header", func(b []byte) []byte { b[headerEnd-2] ^= 1; return b }},
+		{"trailing data", func(b []byte) []byte { return append(b, 0) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := tt.modify(append([]byte(nil), data...))
+
+			r, err := NewReader(bytes.NewReader(modified), testPassphrase)
+			if err != nil {
+				return
+			}
+			if _, err := r.ReadAll(); err == nil {
+				t.Error("ReadAll() expected error for a damaged archive, got nil")
+			}
+		})
+	}
+}
+
+func TestArchiveUnclosed(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, testPassphrase, Info{RootPath: "secret/data/apps"})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.WriteSecret(&Entry{Path: "secret/data/apps/db", Data: map[string]interface{}{"key": "value"}}); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	// An aborted export has no final chunk and no manifest
+	if _, err := NewReader(bytes.NewReader(buf.Bytes()), testPassphrase); err == nil {
+		t.Error("NewReader() expected error for an unclosed archive, got nil")
+	}
+}
+
+func TestWriterDuplicateSecret(t *testing.T) {
+	w, err := NewWriter(io.Discard, testPassphrase, Info{RootPath: "secret/data/apps"})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	entry := &Entry{Path: "secret/data/apps/db", Data: map[string]interface{}{"key": "value"}}
+	if err := w.WriteSecret(entry); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if err := w.WriteSecret(entry); err == nil {
+		t.Error("WriteSecret() expected error for a duplicate path, got nil")
+	}
+}
+
+func TestNewWriterEmptyPassphrase(t *testing.T) {
+	if _, err := NewWriter(io.Discard, "", Info{}); err == nil {
+		t.Error("NewWriter() expected error for an empty passphrase, got nil")
+	}
+}
@@ -0,0 +1,44 @@
+package archive
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Chunk flags, authenticated as the last byte of the chunk nonce
+const (
+	chunkMore  byte = 0
+	chunkFinal byte = 1
+)
+
+// newAEAD derives the key from passphrase with the scrypt parameters of h and returns the AES-GCM cipher
+func newAEAD(passphrase string, h *header) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), h.Salt, h.N, h.R, h.P, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving key: %v", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of chunk counter: the random prefix, the counter and the flag
+func chunkNonce(prefix []byte, counter uint32, flag byte) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	return append(nonce, flag)
+}
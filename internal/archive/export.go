@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"vault-copy/internal/logger"
+	"vault-copy/internal/vault"
+)
+
+// ExportStats holds statistics about an export
+type ExportStats struct {
+	// SecretsExported is the number of secrets written to the archive
+	SecretsExported int64
+	// MetadataExported is the number of secrets exported with their KV v2 metadata
+	MetadataExported int64
+}
+
+// Export writes every secret under info.RootPath, read from client, to an archive on out
+// encrypted with passphrase. The KV engine version is detected and recorded in the archive info,
+// and the KV v2 metadata of every secret is included when the client can read it.
+// Any read error aborts the export; the archive is then left without manifest and fails verification.
+func Export(ctx context.Context, client vault.ClientInterface, info Info, out io.Writer, passphrase string, logger *logger.Logger) (*ExportStats, error) {
+	if strings.Contains(info.RootPath, "*") {
+		return nil, fmt.Errorf("wildcard paths can't be exported: %s", info.RootPath)
+	}
+
+	version, err := client.GetKVEngineVersion(info.RootPath, logger)
+	if err != nil {
+		logger.Info("KV version of %s is unknown, metadata is not exported: %v", info.RootPath, err)
+		version = 0
+	}
+	info.KVVersion = version
+	if info.CreatedAt.IsZero() {
+		info.CreatedAt = time.Now().UTC()
+	}
+
+	metadataReader, _ := client.(vault.MetadataReader)
+	if version != 2 {
+		metadataReader = nil
+	}
+
+	w, err := NewWriter(out, passphrase, info)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Exporting %s (KV v%d)", info.RootPath, version)
+
+	stats := &ExportStats{}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	secrets, errs := client.GetAllSecrets(ctx, info.RootPath, logger)
+	for secrets != nil || errs != nil {
+		select {
+		case secret, ok := <-secrets:
+			if !ok {
+				secrets = nil
+				continue
+			}
+
+			entry := &Entry{Path: secret.Path, Data: secret.Data}
+			if metadataReader != nil {
+				metadata, err := metadataReader.ReadSecretMetadata(secret.Path, logger)
+				if err != nil {
+					return stats, fmt.Errorf("error reading metadata of %s: %v", secret.Path, err)
+				}
+				entry.Metadata = metadata
+			}
+
+			if err := w.WriteSecret(entry); err != nil {
+				return stats, err
+			}
+			stats.SecretsExported++
+			if entry.Metadata != nil {
+				stats.MetadataExported++
+			}
+			logger.Verbose("Exported secret: %s", secret.Path)
+
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if err != nil {
+				return stats, fmt.Errorf("error reading secrets: %v", err)
+			}
+
+		case <-ctx.Done():
+			return stats, ctx.Err()
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return stats, err
+	}
+
+	logger.Info("Exported %d secrets", stats.SecretsExported)
+	return stats, nil
+}
@@ -0,0 +1,86 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+	"vault-copy/internal/vault"
+	"vault-copy/mocks"
+)
+
+func TestExport(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	sourceMock.AddDirectory("secret/data/apps", []string{"db", "team/"})
+	sourceMock.AddDirectory("secret/data/apps/team/", []string{"cache"})
+	sourceMock.AddSecret("secret/data/apps/db", map[string]interface{}{"password": "s3cr3t"})
+	sourceMock.AddSecret("secret/data/apps/team/cache", map[string]interface{}{"url": "redis://cache"})
+	sourceMock.SetSecretMetadata("secret/data/apps/db", &vault.SecretMetadata{MaxVersions: 3})
+	sourceMock.SetSecretMetadata("secret/data/apps/team/cache", &vault.SecretMetadata{CASRequired: true})
+
+	var buf bytes.Buffer
+	stats, err := Export(context.Background(), mocks.NewAdapter(sourceMock), Info{RootPath: "secret/data/apps"},
+		&buf, testPassphrase, logger.NewLogger(&config.Config{}))
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if stats.SecretsExported != 2 || stats.MetadataExported != 2 {
+		t.Errorf("stats = %+v, want 2 secrets with metadata", stats)
+	}
+
+	r, err := NewReader(&buf, testPassphrase)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if info := r.Info(); info.KVVersion != 2 || info.CreatedAt.IsZero() {
+		t.Errorf("Info() = %+v, want KV v2 and a creation time", info)
+	}
+
+	entries, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	secrets := make(map[string]*Entry)
+	for _, entry := range entries {
+		secrets[entry.Path] = entry
+	}
+	if db := secrets["secret/data/apps/db"]; db == nil || db.Data["password"] != "s3cr3t" || db.Metadata == nil || db.Metadata.MaxVersions != 3 {
+		t.Errorf("db = %+v", db)
+	}
+	if cache := secrets["secret/data/apps/team/cache"]; cache == nil || cache.Data["url"] != "redis://cache" || cache.Metadata == nil || !cache.Metadata.CASRequired {
+		t.Errorf("cache = %+v", cache)
+	}
+}
+
+func TestExportReadError(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	sourceMock.AddDirectory("secret/data/apps", []string{"db"})
+	sourceMock.SetReadError("secret/data/apps/db", errors.New("permission denied"))
+
+	var buf bytes.Buffer
+	_, err := Export(context.Background(), mocks.NewAdapter(sourceMock), Info{RootPath: "secret/data/apps"},
+		&buf, testPassphrase, logger.NewLogger(&config.Config{}))
+	if err == nil {
+		t.Fatal("Export() expected error, got nil")
+	}
+
+	// The incomplete archive is rejected
+	if r, err := NewReader(&buf, testPassphrase); err == nil {
+		if _, err := r.ReadAll(); err == nil {
+			t.Error("ReadAll() expected error for an aborted export, got nil")
+		}
+	}
+}
+
+func TestExportWildcard(t *testing.T) {
+	_, err := Export(context.Background(), mocks.NewAdapter(mocks.NewMockClient()), Info{RootPath: "secret/data/apps/*"},
+		&bytes.Buffer{}, testPassphrase, logger.NewLogger(&config.Config{}))
+	if err == nil {
+		t.Error("Export() expected error for a wildcard path, got nil")
+	}
+}
@@ -0,0 +1,233 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Reader reads an encrypted archive written by Writer.
+// Secrets are returned as they are decrypted; the manifest is only verified when Next
+// reaches the end of the archive, so a caller that must not act on a damaged archive
+// reads it completely first, see ReadAll. It is not safe for concurrent use.
+type Reader struct {
+	in          *bufio.Reader
+	aead        cipher.AEAD
+	aad         []byte
+	noncePrefix []byte
+	counter     uint32
+	// plain holds the decrypted data not returned yet
+	plain bytes.Buffer
+	// final is set once the final chunk was decrypted
+	final  bool
+	info   Info
+	hashes map[string]string
+	done   bool
+}
+
+// NewReader opens an archive, derives the key from passphrase and reads the archive info
+func NewReader(in io.Reader, passphrase string) (*Reader, error) {
+	r := &Reader{in: bufio.NewReader(in), hashes: make(map[string]string)}
+
+	prefix := make([]byte, len(magic)+5)
+	if _, err := io.ReadFull(r.in, prefix); err != nil {
+		return nil, ErrNotArchive
+	}
+	if string(prefix[:len(magic)]) != magic {
+		return nil, ErrNotArchive
+	}
+	if version := prefix[len(magic)]; version != FormatVersion {
+		return nil, fmt.Errorf("unsupported archive format version %d", version)
+	}
+
+	size := binary.BigEndian.Uint32(prefix[len(magic)+1:])
+	if size > maxHeaderSize {
+		return nil, errors.New("archive header is too large")
+	}
+	headerJSON := make([]byte, size)
+	if _, err := io.ReadFull(r.in, headerJSON); err != nil {
+		return nil, fmt.Errorf("error reading archive header: %v", err)
+	}
+
+	var h header
+	if err := json.Unmarshal(headerJSON, &h); err != nil {
+		return nil, fmt.Errorf("invalid archive header: %v", err)
+	}
+	if err := h.validate(); err != nil {
+		return nil, fmt.Errorf("invalid archive header: %v", err)
+	}
+
+	aead, err := newAEAD(passphrase, &h)
+	if err != nil {
+		return nil, err
+	}
+	r.aead = aead
+	r.aad = append(prefix, headerJSON...)
+	r.noncePrefix = h.NoncePrefix
+
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	rec, err := decodeRecord(line)
+	if err != nil || rec.Type != recordInfo || rec.Info == nil {
+		return nil, errors.New("corrupted archive: missing archive info")
+	}
+	r.info = *rec.Info
+
+	return r, nil
+}
+
+// Info returns the description of the exported subtree
+func (r *Reader) Info() Info {
+	return r.info
+}
+
+// Next returns the next secret. At the end of the archive it verifies the manifest
+// and returns io.EOF, or the error describing why the archive is incomplete or damaged.
+func (r *Reader) Next() (*Entry, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := decodeRecord(line)
+	if err != nil {
+		return nil, fmt.Errorf("corrupted archive: %v", err)
+	}
+
+	switch {
+	case rec.Type == recordSecret && rec.Secret != nil:
+		if _, ok := r.hashes[rec.Secret.Path]; ok {
+			return nil, fmt.Errorf("corrupted archive: duplicate secret %s", rec.Secret.Path)
+		}
+		sum := sha256.Sum256(line)
+		r.hashes[rec.Secret.Path] = hex.EncodeToString(sum[:])
+		return rec.Secret, nil
+
+	case rec.Type == recordManifest && rec.Manifest != nil:
+		if err := r.verify(rec.Manifest); err != nil {
+			return nil, err
+		}
+		// The manifest may end a chunk, followed by an empty final chunk
+		for !r.final {
+			if err := r.readChunk(); err != nil {
+				return nil, err
+			}
+		}
+		if r.plain.Len() > 0 {
+			return nil, errors.New("corrupted archive: data after the manifest")
+		}
+		r.done = true
+		return nil, io.EOF
+	}
+
+	return nil, fmt.Errorf("corrupted archive: unexpected %q record", rec.Type)
+}
+
+// ReadAll reads the remaining secrets and verifies the manifest.
+// Nothing is returned unless the whole archive is intact.
+func (r *Reader) ReadAll() ([]*Entry, error) {
+	var entries []*Entry
+	for {
+		entry, err := r.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// verify checks the secrets read against the manifest
+func (r *Reader) verify(manifest *Manifest) error {
+	if manifest.Count != len(r.hashes) || len(manifest.Secrets) != len(r.hashes) {
+		return fmt.Errorf("integrity check failed: manifest lists %d secrets, archive holds %d", manifest.Count, len(r.hashes))
+	}
+
+	for path, hash := range r.hashes {
+		if manifest.Secrets[path] != hash {
+			return fmt.Errorf("integrity check failed: checksum mismatch for %s", path)
+		}
+	}
+
+	return nil
+}
+
+// readLine returns the next line of the decrypted stream, decrypting chunks as needed
+func (r *Reader) readLine() ([]byte, error) {
+	for {
+		if i := bytes.IndexByte(r.plain.Bytes(), '\n'); i >= 0 {
+			return r.plain.Next(i + 1), nil
+		}
+		if r.final {
+			return nil, errors.New("corrupted archive: truncated record")
+		}
+		if err := r.readChunk(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readChunk decrypts the next chunk into the plaintext buffer
+func (r *Reader) readChunk() error {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r.in, prefix[:]); err != nil {
+		return errors.New("corrupted archive: truncated, the final chunk is missing")
+	}
+
+	flag := prefix[0]
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if (flag != chunkMore && flag != chunkFinal) || size > chunkSize+uint32(r.aead.Overhead()) {
+		return fmt.Errorf("corrupted archive: invalid chunk %d", r.counter)
+	}
+
+	ciphertext := make([]byte, size)
+	if _, err := io.ReadFull(r.in, ciphertext); err != nil {
+		return errors.New("corrupted archive: truncated chunk")
+	}
+
+	plaintext, err := r.aead.Open(nil, chunkNonce(r.noncePrefix, r.counter, flag), ciphertext, r.aad)
+	if err != nil {
+		if r.counter == 0 {
+			return ErrDecrypt
+		}
+		return fmt.Errorf("corrupted archive: chunk %d failed authentication", r.counter)
+	}
+	r.counter++
+	r.plain.Write(plaintext)
+
+	if flag == chunkFinal {
+		r.final = true
+		if _, err := r.in.ReadByte(); err != io.EOF {
+			return errors.New("corrupted archive: data after the final chunk")
+		}
+	}
+
+	return nil
+}
+
+// decodeRecord decodes a record line, keeping numbers as json.Number so values are not rounded
+func decodeRecord(line []byte) (*record, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	var rec record
+	if err := decoder.Decode(&rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
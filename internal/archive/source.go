@@ -0,0 +1,199 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"vault-copy/internal/logger"
+	"vault-copy/internal/vault"
+)
+
+// errReadOnly is returned by the write methods of Source
+var errReadOnly = errors.New("archive source is read-only")
+
+// Ensure that Source implements the interfaces used by the sync manager for a source
+var (
+	_ vault.ClientInterface = (*Source)(nil)
+	_ vault.MetadataReader  = (*Source)(nil)
+)
+
+// Source serves the secrets of a verified archive through vault.ClientInterface,
+// so an import is a copy whose source is the archive. It is read-only and safe for concurrent use.
+type Source struct {
+	info    Info
+	entries map[string]*Entry
+	// paths holds the sorted paths of all secrets
+	paths []string
+}
+
+// NewSource reads the whole archive from r and verifies it.
+// An incomplete or damaged archive is rejected before any secret is served.
+func NewSource(r *Reader) (*Source, error) {
+	entries, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Source{info: r.Info(), entries: make(map[string]*Entry, len(entries))}
+	for _, entry := range entries {
+		s.entries[entry.Path] = entry
+		s.paths = append(s.paths, entry.Path)
+	}
+	sort.Strings(s.paths)
+
+	return s, nil
+}
+
+// Info returns the description of the exported subtree
+func (s *Source) Info() Info {
+	return s.info
+}
+
+// Count returns the number of secrets in the archive
+func (s *Source) Count() int {
+	return len(s.paths)
+}
+
+// ReadSecret returns the secret stored at path
+func (s *Source) ReadSecret(path string, logger *logger.Logger) (*vault.Secret, error) {
+	entry, ok := s.entries[path]
+	if !ok {
+		return nil, fmt.Errorf("secret not found in archive: %s", path)
+	}
+
+	logger.Verbose("Read secret from archive: %s", path)
+	return &vault.Secret{Path: entry.Path, Data: entry.Data}, nil
+}
+
+// IsDirectory reports whether secrets are stored under path
+func (s *Source) IsDirectory(path string, logger *logger.Logger) (bool, error) {
+	prefix := dirPrefix(path)
+	i := sort.SearchStrings(s.paths, prefix)
+	return i < len(s.paths) && strings.HasPrefix(s.paths[i], prefix), nil
+}
+
+// ListSecrets lists the secrets and folders directly under path, folders with a trailing slash as Vault does
+func (s *Source) ListSecrets(path string, logger *logger.Logger) ([]string, error) {
+	prefix := dirPrefix(path)
+
+	var items []string
+	seen := make(map[string]bool)
+	for _, p := range s.under(prefix) {
+		item := strings.TrimPrefix(p, prefix)
+		if i := strings.Index(item, "/"); i >= 0 {
+			item = item[:i+1]
+		}
+		if !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+// GetAllSecrets returns the secrets under rootPath, or the secret at rootPath itself
+func (s *Source) GetAllSecrets(ctx context.Context, rootPath string, logger *logger.Logger) (<-chan *vault.Secret, <-chan error) {
+	secretsChan := make(chan *vault.Secret, 100)
+	errChan := make(chan error, 1)
+
+	paths := s.under(dirPrefix(rootPath))
+	if _, ok := s.entries[rootPath]; ok && len(paths) == 0 {
+		paths = []string{rootPath}
+	}
+
+	go func() {
+		defer close(secretsChan)
+		defer close(errChan)
+
+		for _, p := range paths {
+			entry := s.entries[p]
+			select {
+			case secretsChan <- &vault.Secret{Path: entry.Path, Data: entry.Data}:
+			case <-ctx.Done():
+				errChan <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return secretsChan, errChan
+}
+
+// ExpandWildcardPath returns the secret and folder paths matching pattern, segment by segment
+func (s *Source) ExpandWildcardPath(pattern string, logger *logger.Logger) ([]string, error) {
+	if !strings.Contains(pattern, "*") {
+		return []string{pattern}, nil
+	}
+
+	matches, err := vault.MatchWildcard(pattern, s.paths)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Verbose("Wildcard %s matched %d paths in archive", pattern, len(matches))
+	return matches, nil
+}
+
+// ReadSecretMetadata returns the KV v2 metadata stored with the secret, nil when there is none
+func (s *Source) ReadSecretMetadata(path string, logger *logger.Logger) (*vault.SecretMetadata, error) {
+	entry, ok := s.entries[path]
+	if !ok {
+		return nil, fmt.Errorf("secret not found in archive: %s", path)
+	}
+	return entry.Metadata, nil
+}
+
+// GetKVEngine returns the first segment of path, as the Vault client does
+func (s *Source) GetKVEngine(path string) (string, error) {
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) < 2 {
+		return "secret", nil
+	}
+	return parts[0], nil
+}
+
+// GetKVEngineVersion returns the KV version the secrets were exported from
+func (s *Source) GetKVEngineVersion(engine string, logger *logger.Logger) (int, error) {
+	if s.info.KVVersion == 0 {
+		return 0, fmt.Errorf("KV version of the exported engine is unknown")
+	}
+	return s.info.KVVersion, nil
+}
+
+// WriteSecret always fails, an archive is only a source
+func (s *Source) WriteSecret(path string, data map[string]interface{}, logger *logger.Logger) error {
+	return errReadOnly
+}
+
+// SecretExists reports whether a secret is stored at path
+func (s *Source) SecretExists(path string, logger *logger.Logger) (bool, error) {
+	_, ok := s.entries[path]
+	return ok, nil
+}
+
+// BatchWriteSecrets always fails, an archive is only a source
+func (s *Source) BatchWriteSecrets(ctx context.Context, secrets <-chan *vault.Secret, basePath string, logger *logger.Logger) <-chan error {
+	errChan := make(chan error, 1)
+	errChan <- errReadOnly
+	close(errChan)
+	return errChan
+}
+
+// under returns the sorted paths starting with prefix
+func (s *Source) under(prefix string) []string {
+	start := sort.SearchStrings(s.paths, prefix)
+	end := start
+	for end < len(s.paths) && strings.HasPrefix(s.paths[end], prefix) {
+		end++
+	}
+	return s.paths[start:end]
+}
+
+// dirPrefix returns path with a single trailing slash, the prefix of the paths under it
+func dirPrefix(path string) string {
+	return strings.TrimSuffix(path, "/") + "/"
+}
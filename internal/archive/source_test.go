@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+	"vault-copy/internal/sync"
+	"vault-copy/internal/vault"
+	"vault-copy/mocks"
+)
+
+// newTestSource returns a source serving an archive of the given entries
+func newTestSource(t *testing.T, entries ...*Entry) *Source {
+	t.Helper()
+
+	r, err := NewReader(bytes.NewReader(writeArchive(t, entries...)), testPassphrase)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+
+	source, err := NewSource(r)
+	if err != nil {
+		t.Fatalf("NewSource() error = %v", err)
+	}
+	return source
+}
+
+func testEntries() []*Entry {
+	return []*Entry{
+		{
+			Path:     "secret/data/apps/db",
+			Data:     map[string]interface{}{"password": "s3cr3t"},
+			Metadata: &vault.SecretMetadata{MaxVersions: 3},
+		},
+		{Path: "secret/data/apps/team/cache", Data: map[string]interface{}{"url": "redis://cache"}},
+		{Path: "secret/data/apps/team/queue", Data: map[string]interface{}{"url": "amqp://queue"}},
+	}
+}
+
+func TestSourceReader(t *testing.T) {
+	source := newTestSource(t, testEntries()...)
+	log := logger.NewLogger(&config.Config{})
+
+	if isDir, _ := source.IsDirectory("secret/data/apps", log); !isDir {
+		t.Error("IsDirectory(secret/data/apps) = false, want true")
+	}
+	if isDir, _ := source.IsDirectory("secret/data/apps/db", log); isDir {
+		t.Error("IsDirectory(secret/data/apps/db) = true, want false")
+	}
+
+	items, _ := source.ListSecrets("secret/data/apps/", log)
+	if want := []string{"db", "team/"}; !reflect.DeepEqual(items, want) {
+		t.Errorf("ListSecrets() = %v, want %v", items, want)
+	}
+
+	secret, err := source.ReadSecret("secret/data/apps/team/cache", log)
+	if err != nil || secret.Data["url"] != "redis://cache" {
+		t.Errorf("ReadSecret() = %v, %v", secret, err)
+	}
+	if _, err := source.ReadSecret("secret/data/apps/missing", log); err == nil {
+		t.Error("ReadSecret() expected error for a missing secret, got nil")
+	}
+
+	paths, err := source.ExpandWildcardPath("secret/data/apps/team/c*", log)
+	if want := []string{"secret/data/apps/team/cache"}; err != nil || !reflect.DeepEqual(paths, want) {
+		t.Errorf("ExpandWildcardPath() = %v, %v, want %v", paths, err, want)
+	}
+
+	secrets, errs := source.GetAllSecrets(context.Background(), "secret/data/apps/team", log)
+	var got []string
+	for secret := range secrets {
+		got = append(got, secret.Path)
+	}
+	if err := <-errs; err != nil {
+		t.Errorf("GetAllSecrets() error = %v", err)
+	}
+	if want := []string{"secret/data/apps/team/cache", "secret/data/apps/team/queue"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAllSecrets() = %v, want %v", got, want)
+	}
+
+	if version, err := source.GetKVEngineVersion("secret", log); err != nil || version != 2 {
+		t.Errorf("GetKVEngineVersion() = %d, %v, want 2", version, err)
+	}
+	if err := source.WriteSecret("secret/data/apps/db", nil, log); err == nil {
+		t.Error("WriteSecret() expected error, got nil")
+	}
+}
+
+func TestImport(t *testing.T) {
+	source := newTestSource(t, testEntries()...)
+
+	destMock := mocks.NewMockClient()
+	destMock.AddSecret("secret/data/restored/db", map[string]interface{}{"password": "old"})
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/apps",
+		DestinationPath: "secret/data/restored",
+		Recursive:       true,
+		ParallelWorkers: 2,
+		CopyMetadata:    true,
+	}
+
+	stats, err := sync.NewManager(source, mocks.NewAdapter(destMock), cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	// The existing secret is kept without --overwrite
+	if stats.SecretsWritten != 2 || stats.SecretsSkipped != 1 || stats.Errors != 0 {
+		t.Errorf("stats = %+v, want 2 written and 1 skipped", stats)
+	}
+	if destMock.Secrets["secret/data/restored/db"].Data["password"] != "old" {
+		t.Error("existing secret was overwritten")
+	}
+	if cache := destMock.Secrets["secret/data/restored/team/cache"]; cache == nil || cache.Data["url"] != "redis://cache" {
+		t.Errorf("remapped secret = %+v", cache)
+	}
+
+	cfg.Overwrite = true
+	stats, err = sync.NewManager(source, mocks.NewAdapter(destMock), cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() with overwrite error = %v", err)
+	}
+	if stats.SecretsWritten != 1 || stats.SecretsUnchanged != 2 {
+		t.Errorf("stats with overwrite = %+v, want 1 written and 2 unchanged", stats)
+	}
+	if destMock.Secrets["secret/data/restored/db"].Data["password"] != "s3cr3t" {
+		t.Error("existing secret was not overwritten")
+	}
+	if metadata := destMock.Metadata["secret/data/restored/db"]; metadata == nil || metadata.MaxVersions != 3 {
+		t.Errorf("metadata = %+v, want max_versions 3", metadata)
+	}
+}
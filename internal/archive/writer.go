@@ -0,0 +1,159 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Writer writes an encrypted archive. Secrets are sealed in chunks as they are written,
+// so an export never holds the whole subtree in memory. It is not safe for concurrent use.
+type Writer struct {
+	out         io.Writer
+	aead        cipher.AEAD
+	aad         []byte
+	noncePrefix []byte
+	counter     uint32
+	// pending holds the plaintext not sealed yet
+	pending  bytes.Buffer
+	manifest Manifest
+	closed   bool
+}
+
+// NewWriter starts an archive on out, encrypted with a key derived from passphrase,
+// and writes info as its first record
+func NewWriter(out io.Writer, passphrase string, info Info) (*Writer, error) {
+	h := &header{
+		KDF:         kdfScrypt,
+		Salt:        make([]byte, saltSize),
+		N:           scryptN,
+		R:           scryptR,
+		P:           scryptP,
+		Cipher:      cipherAESGCM,
+		NoncePrefix: make([]byte, noncePrefixSize),
+	}
+	if _, err := rand.Read(h.Salt); err != nil {
+		return nil, fmt.Errorf("error generating salt: %v", err)
+	}
+	if _, err := rand.Read(h.NoncePrefix); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %v", err)
+	}
+
+	aead, err := newAEAD(passphrase, h)
+	if err != nil {
+		return nil, err
+	}
+
+	headerJSON, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+
+	// The magic, version and header are authenticated with every chunk
+	prefix := append([]byte(magic), FormatVersion)
+	prefix = binary.BigEndian.AppendUint32(prefix, uint32(len(headerJSON)))
+	prefix = append(prefix, headerJSON...)
+	if _, err := out.Write(prefix); err != nil {
+		return nil, fmt.Errorf("error writing archive header: %v", err)
+	}
+
+	w := &Writer{
+		out:         out,
+		aead:        aead,
+		aad:         prefix,
+		noncePrefix: h.NoncePrefix,
+		manifest:    Manifest{Secrets: make(map[string]string)},
+	}
+
+	if _, err := w.writeRecord(record{Type: recordInfo, Info: &info}); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// WriteSecret adds a secret to the archive
+func (w *Writer) WriteSecret(entry *Entry) error {
+	if w.closed {
+		return errors.New("archive is closed")
+	}
+	if _, ok := w.manifest.Secrets[entry.Path]; ok {
+		return fmt.Errorf("duplicate secret %s", entry.Path)
+	}
+
+	line, err := w.writeRecord(record{Type: recordSecret, Secret: entry})
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(line)
+	w.manifest.Secrets[entry.Path] = hex.EncodeToString(sum[:])
+	w.manifest.Count++
+	return nil
+}
+
+// Count returns the number of secrets written so far
+func (w *Writer) Count() int {
+	return w.manifest.Count
+}
+
+// Close writes the manifest and seals the final chunk. It doesn't close the underlying writer.
+// An archive that was not closed is rejected as truncated when it is read.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	if _, err := w.writeRecord(record{Type: recordManifest, Manifest: &w.manifest}); err != nil {
+		return err
+	}
+	w.closed = true
+
+	return w.seal(w.pending.Next(w.pending.Len()), chunkFinal)
+}
+
+// writeRecord appends a record line to the stream, sealing every full chunk, and returns the line
+func (w *Writer) writeRecord(rec record) ([]byte, error) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding %s record: %v", rec.Type, err)
+	}
+	line = append(line, '\n')
+
+	w.pending.Write(line)
+	for w.pending.Len() >= chunkSize {
+		if err := w.seal(w.pending.Next(chunkSize), chunkMore); err != nil {
+			return nil, err
+		}
+	}
+
+	return line, nil
+}
+
+// seal encrypts one chunk and writes it with its flag and length
+func (w *Writer) seal(plaintext []byte, flag byte) error {
+	if w.counter == math.MaxUint32 {
+		return errors.New("archive is too large")
+	}
+
+	ciphertext := w.aead.Seal(nil, chunkNonce(w.noncePrefix, w.counter, flag), plaintext, w.aad)
+	w.counter++
+
+	chunk := make([]byte, 0, 5+len(ciphertext))
+	chunk = append(chunk, flag)
+	chunk = binary.BigEndian.AppendUint32(chunk, uint32(len(ciphertext)))
+	chunk = append(chunk, ciphertext...)
+	if _, err := w.out.Write(chunk); err != nil {
+		return fmt.Errorf("error writing archive: %v", err)
+	}
+
+	return nil
+}
@@ -2,9 +2,13 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -23,8 +27,34 @@ type Config struct {
 	Overwrite bool
 	// ParallelWorkers is the number of parallel workers for copying secrets
 	ParallelWorkers int
+	// ReadWorkers is the number of concurrent readers walking the source, 0 selects DefaultReadWorkers
+	ReadWorkers int
 	// Verbose indicates whether to enable verbose logging
 	Verbose bool
+	// AllVersions indicates whether to replay the full KV v2 version history instead of the latest version
+	AllVersions bool
+	// CopyMetadata indicates whether to copy KV v2 metadata settings (custom_metadata, max_versions, ...)
+	CopyMetadata bool
+	// DeleteExtraneous indicates whether to delete destination secrets that have no source counterpart
+	DeleteExtraneous bool
+	// DeleteMode selects how extraneous KV v2 secrets are deleted: DeleteModeSoft or DeleteModeDestroy
+	DeleteMode string
+	// MaxDeletes aborts the deletion of extraneous secrets when more are planned, 0 disables the limit
+	MaxDeletes int
+	// StateFile is the file recording completed destination paths for resuming an interrupted run
+	StateFile string
+	// Resume indicates whether to skip the destination paths already recorded in StateFile
+	Resume bool
+	// Rate limits the requests per second sent to each Vault, 0 disables the limit
+	Rate float64
+	// SourceRate limits the requests per second sent to the source Vault, 0 uses Rate
+	SourceRate float64
+	// DestRate limits the requests per second sent to the destination Vault, 0 uses Rate
+	DestRate float64
+	// AdaptiveConcurrency indicates whether to lower the number of active workers while Vault throttles or slows down
+	AdaptiveConcurrency bool
+	// Paths restricts the run to these source paths under SourcePath, for example the failed paths of a previous run
+	Paths []string
 
 	// SourceAddr is the address of the source Vault server
 	SourceAddr string
@@ -35,19 +65,325 @@ type Config struct {
 	DestAddr string
 	// DestToken is the authentication token for the destination Vault
 	DestToken string
+
+	// SourceAuth holds the auth method settings for the source Vault
+	SourceAuth AuthConfig
+	// DestAuth holds the auth method settings for the destination Vault
+	DestAuth AuthConfig
+
+	// SourceTLS holds the TLS settings for connecting to the source Vault
+	SourceTLS TLSConfig
+	// DestTLS holds the TLS settings for connecting to the destination Vault
+	DestTLS TLSConfig
+
+	// SourceNamespace is the Vault Enterprise namespace used on the source Vault
+	SourceNamespace string
+	// DestNamespace is the Vault Enterprise namespace used on the destination Vault
+	DestNamespace string
+
+	// SourceBackend selects where secrets are read from, a Vault server unless the source address is a file:// URL
+	SourceBackend BackendConfig
+	// DestBackend selects where secrets are written to, a Vault server unless the destination address is a file:// URL
+	DestBackend BackendConfig
+
+	// Retry holds the retry policy for transient errors of both Vault clients
+	Retry RetryConfig
+}
+
+// Supported Vault auth methods
+const (
+	// AuthMethodToken uses a static token as-is
+	AuthMethodToken = "token"
+	// AuthMethodAppRole logs in via auth/<mount>/login with a role_id and secret_id
+	AuthMethodAppRole = "approle"
+	// AuthMethodKubernetes logs in via auth/<mount>/login with a service account JWT
+	AuthMethodKubernetes = "kubernetes"
+)
+
+// Deletion modes for extraneous KV v2 secrets
+const (
+	// DeleteModeSoft soft-deletes the latest version, the history can still be undeleted
+	DeleteModeSoft = "soft"
+	// DeleteModeDestroy deletes the metadata and permanently destroys all versions
+	DeleteModeDestroy = "destroy"
+)
+
+// Secret backends, selected by the scheme of the source or destination address
+const (
+	// BackendVault is a Vault server, addressed by an http://, https:// or vault:// URL
+	BackendVault = "vault"
+	// BackendFile is a local directory tree of JSON or YAML files, addressed by a file:// URL
+	BackendFile = "file"
+)
+
+// Formats of the secret files written by the file backend
+const (
+	// FileFormatJSON writes secrets as .json files, the default
+	FileFormatJSON = "json"
+	// FileFormatYAML writes secrets as .yaml files
+	FileFormatYAML = "yaml"
+)
+
+// DefaultReadWorkers is the default number of concurrent readers walking a folder
+const DefaultReadWorkers = 5
+
+// DefaultMaxDeletes is the default limit of extraneous secrets deleted in one run
+const DefaultMaxDeletes = 100
+
+// Retry classes accepted in RetryConfig.RetryOn besides exact status codes such as "503"
+const (
+	// RetryOnServerErrors matches every 5xx status except 501 Not Implemented
+	RetryOnServerErrors = "5xx"
+	// RetryOnNetwork matches connection errors such as a refused or reset connection
+	RetryOnNetwork = "network"
+)
+
+// DefaultKubernetesJWTPath is where Kubernetes mounts the service account token in a pod
+const DefaultKubernetesJWTPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// AuthConfig holds the authentication settings for one side of the copy.
+// When Method is AuthMethodToken the Config token is used directly,
+// otherwise the Vault client logs in and obtains its own token.
+type AuthConfig struct {
+	// Method is the auth method to use (token, approle or kubernetes)
+	Method string
+	// MountPath is the path the auth method is mounted at, defaults to the method name
+	MountPath string
+	// RoleID is the AppRole role_id
+	RoleID string
+	// SecretID is the AppRole secret_id
+	SecretID string
+	// SecretIDFile is the path to a file containing the AppRole secret_id
+	SecretIDFile string
+	// KubernetesRole is the Vault role to log in with via the Kubernetes auth method
+	KubernetesRole string
+	// JWTPath is the path to the service account JWT used by the Kubernetes auth method
+	JWTPath string
+}
+
+// BackendConfig selects the secret backend of one side of the copy
+type BackendConfig struct {
+	// Type is BackendVault or BackendFile
+	Type string
+	// Dir is the root directory of a file backend, secret paths are relative to it
+	Dir string
+	// Format is the format of the files written by a file backend, FileFormatJSON or FileFormatYAML
+	Format string
+}
+
+// ParseBackendURL parses a source or destination address.
+// file://dir selects the file backend rooted at dir, relative to the working directory unless dir is absolute,
+// and the format query parameter sets the format of the written files, e.g. file://./secrets?format=yaml.
+// vault://host:port is a Vault server over HTTPS and vault+http://host:port one over plain HTTP;
+// they are returned as https:// and http:// addresses. Any other address is a Vault address returned as-is.
+func ParseBackendURL(raw string) (BackendConfig, string, error) {
+	scheme, rest, ok := strings.Cut(raw, "://")
+	if !ok {
+		return BackendConfig{Type: BackendVault}, raw, nil
+	}
+
+	switch strings.ToLower(scheme) {
+	case "file":
+		dir, query, _ := strings.Cut(rest, "?")
+		if dir == "" {
+			return BackendConfig{}, "", fmt.Errorf("file backend URL %s has no directory", raw)
+		}
+
+		backend := BackendConfig{Type: BackendFile, Dir: dir, Format: FileFormatJSON}
+		if query != "" {
+			values, err := url.ParseQuery(query)
+			if err != nil {
+				return BackendConfig{}, "", fmt.Errorf("invalid file backend URL %s: %v", raw, err)
+			}
+			for key := range values {
+				if key != "format" {
+					return BackendConfig{}, "", fmt.Errorf("unsupported file backend option %q", key)
+				}
+			}
+			if format := values.Get("format"); format != "" {
+				backend.Format = strings.ToLower(format)
+			}
+		}
+		if backend.Format == "yml" {
+			backend.Format = FileFormatYAML
+		}
+		if backend.Format != FileFormatJSON && backend.Format != FileFormatYAML {
+			return BackendConfig{}, "", fmt.Errorf("unsupported file format %q, use %s or %s", backend.Format, FileFormatJSON, FileFormatYAML)
+		}
+		return backend, raw, nil
+
+	case "vault":
+		if rest == "" {
+			return BackendConfig{}, "", fmt.Errorf("vault URL %s has no host", raw)
+		}
+		return BackendConfig{Type: BackendVault}, "https://" + rest, nil
+
+	case "vault+http":
+		if rest == "" {
+			return BackendConfig{}, "", fmt.Errorf("vault URL %s has no host", raw)
+		}
+		return BackendConfig{Type: BackendVault}, "http://" + rest, nil
+	}
+
+	return BackendConfig{Type: BackendVault}, raw, nil
+}
+
+// TLSConfig holds the TLS settings for connecting to one Vault server.
+// Empty fields keep the defaults, including the standard VAULT_CACERT-style environment variables.
+type TLSConfig struct {
+	// CACert is the path to a PEM-encoded CA bundle used to verify the server certificate
+	CACert string
+	// CAPath is the path to a directory of PEM-encoded CA certificates
+	CAPath string
+	// ClientCert is the path to the client certificate for mutual TLS
+	ClientCert string
+	// ClientKey is the path to the private key of the client certificate
+	ClientKey string
+	// ServerName is the SNI host name used when connecting
+	ServerName string
+	// Insecure disables verification of the server certificate
+	Insecure bool
+}
+
+// IsEmpty reports whether no TLS setting was given
+func (t TLSConfig) IsEmpty() bool {
+	return t == TLSConfig{}
+}
+
+// RetryConfig holds the retry policy for transient Vault errors.
+// The delay before retry n is BaseDelay*2^(n-1), capped at MaxDelay and randomised by Jitter.
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts per request, 1 disables retries.
+	// A zero RetryConfig selects DefaultRetryConfig.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts, including delays requested by Retry-After
+	MaxDelay time.Duration
+	// Jitter is the fraction (0 to 1) of each delay that is randomised
+	Jitter float64
+	// RetryOn lists the retryable status codes ("429", "503"), classes ("5xx") and "network"
+	RetryOn []string
+}
+
+// DefaultRetryConfig returns the retry policy used unless configured otherwise
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+		RetryOn:     []string{"429", "500", "502", "503", "504", RetryOnNetwork},
+	}
+}
+
+// ParseRetryOn splits a comma-separated list of retryable status codes and classes
+func ParseRetryOn(value string) []string {
+	var retryOn []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			retryOn = append(retryOn, item)
+		}
+	}
+	return retryOn
+}
+
+// RetriesStatus reports whether a response with the given HTTP status code is retried
+func (r RetryConfig) RetriesStatus(code int) bool {
+	for _, item := range r.RetryOn {
+		if item == RetryOnServerErrors && code >= 500 && code <= 599 && code != 501 {
+			return true
+		}
+		if item == strconv.Itoa(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetriesNetworkErrors reports whether connection errors are retried
+func (r RetryConfig) RetriesNetworkErrors() bool {
+	for _, item := range r.RetryOn {
+		if item == RetryOnNetwork {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the retry policy is usable
+func (r RetryConfig) Validate() error {
+	if r.MaxAttempts < 0 {
+		return errors.New("retry attempts cannot be negative")
+	}
+
+	if r.BaseDelay < 0 || r.MaxDelay < 0 {
+		return errors.New("retry delays cannot be negative")
+	}
+
+	if r.MaxDelay < r.BaseDelay {
+		return errors.New("retry max delay cannot be less than the base delay")
+	}
+
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return errors.New("retry jitter must be between 0 and 1")
+	}
+
+	for _, item := range r.RetryOn {
+		if item == RetryOnServerErrors || item == RetryOnNetwork {
+			continue
+		}
+		if code, err := strconv.Atoi(item); err != nil || code < 100 || code > 599 {
+			return fmt.Errorf("unsupported retry class %q, use a status code, %s or %s", item, RetryOnServerErrors, RetryOnNetwork)
+		}
+	}
+
+	return nil
+}
+
+// TLSFileConfig represents the tls block of a source or destination section
+type TLSFileConfig struct {
+	CACert     string `yaml:"ca_cert"`
+	CAPath     string `yaml:"ca_path"`
+	ClientCert string `yaml:"client_cert"`
+	ClientKey  string `yaml:"client_key"`
+	ServerName string `yaml:"server_name"`
+	SkipVerify bool   `yaml:"skip_verify"`
+}
+
+// AppRoleFileConfig represents the approle block of a source or destination section
+type AppRoleFileConfig struct {
+	MountPath    string `yaml:"mount_path"`
+	RoleID       string `yaml:"role_id"`
+	SecretID     string `yaml:"secret_id"`
+	SecretIDFile string `yaml:"secret_id_file"`
+}
+
+// KubernetesFileConfig represents the kubernetes block of a source or destination section
+type KubernetesFileConfig struct {
+	MountPath string `yaml:"mount_path"`
+	Role      string `yaml:"role"`
+	JWTPath   string `yaml:"jwt_path"`
+}
+
+// VaultFileConfig represents the source or destination section of the YAML config file
+type VaultFileConfig struct {
+	Address    string               `yaml:"address"`
+	Token      string               `yaml:"token"`
+	Namespace  string               `yaml:"namespace"`
+	Rate       float64              `yaml:"rate"`
+	AuthMethod string               `yaml:"auth_method"`
+	AppRole    AppRoleFileConfig    `yaml:"approle"`
+	Kubernetes KubernetesFileConfig `yaml:"kubernetes"`
+	TLS        TLSFileConfig        `yaml:"tls"`
 }
 
 // FileConfig represents the structure of the YAML config file
 type FileConfig struct {
-	Source struct {
-		Address string `yaml:"address"`
-		Token   string `yaml:"token"`
-	} `yaml:"source"`
-	Destination struct {
-		Address string `yaml:"address"`
-		Token   string `yaml:"token"`
-	} `yaml:"destination"`
-	Settings struct {
+	Source      VaultFileConfig `yaml:"source"`
+	Destination VaultFileConfig `yaml:"destination"`
+	Settings    struct {
 		Recursive bool `yaml:"recursive"`
 		DryRun    bool `yaml:"dry_run"`
 		Overwrite bool `yaml:"overwrite"`
@@ -77,7 +413,7 @@ func LoadConfigFromFile(filename string) (*FileConfig, error) {
 }
 
 // NewConfig creates a new Config instance with the provided parameters.
-// It handles environment variable fallbacks for Vault addresses and tokens.
+// It handles environment variable fallbacks for Vault addresses, tokens, namespaces, auth and TLS settings.
 // Priority order: function parameters > environment variables > config file > defaults
 func NewConfig(
 	sourcePath, destinationPath string,
@@ -85,6 +421,9 @@ func NewConfig(
 	parallelWorkers int,
 	sourceAddr, sourceToken,
 	destAddr, destToken string,
+	sourceAuth, destAuth AuthConfig,
+	sourceTLS, destTLS TLSConfig,
+	sourceNamespace, destNamespace string,
 	configFile string,
 ) (*Config, error) {
 	// Load config file
@@ -102,6 +441,7 @@ func NewConfig(
 		Overwrite:       overwrite,
 		ParallelWorkers: parallelWorkers,
 		Verbose:         verbose,
+		Retry:           DefaultRetryConfig(),
 	}
 
 	// Validate configuration early to catch path errors before token validation
@@ -109,8 +449,103 @@ func NewConfig(
 		return nil, err
 	}
 
-	// Get source Vault configuration
-	// Priority: function parameter > environment variable > config file > default
+	if err := cfg.resolveSource(fileConfig, sourceAddr, sourceToken, sourceAuth, sourceTLS, sourceNamespace); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.resolveDestination(fileConfig, destAddr, destToken, destAuth, destTLS, destNamespace); err != nil {
+		return nil, err
+	}
+
+	cfg.applyFileSettings(fileConfig, recursive, dryRun, overwrite, verbose, parallelWorkers)
+
+	return cfg, nil
+}
+
+// NewSourceConfig creates the configuration of a command that only reads from the source Vault, such as export.
+// Only the source settings are resolved, so no destination token is needed; the destination path is the source path.
+func NewSourceConfig(
+	sourcePath string,
+	verbose bool,
+	sourceAddr, sourceToken string,
+	sourceAuth AuthConfig,
+	sourceTLS TLSConfig,
+	sourceNamespace string,
+	configFile string,
+) (*Config, error) {
+	// Load config file
+	fileConfig, err := LoadConfigFromFile(configFile)
+	if err != nil {
+		log.Printf("Warning: could not load config file: %v", err)
+		fileConfig = &FileConfig{}
+	}
+
+	cfg := &Config{
+		SourcePath:      sourcePath,
+		DestinationPath: sourcePath,
+		Recursive:       true,
+		ParallelWorkers: 1,
+		Verbose:         verbose || fileConfig.Settings.Verbose,
+		Retry:           DefaultRetryConfig(),
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.resolveSource(fileConfig, sourceAddr, sourceToken, sourceAuth, sourceTLS, sourceNamespace); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// NewDestinationConfig creates the configuration of a command that only writes to the destination Vault, such as import.
+// Only the destination settings are resolved, so no source token is needed; the copy is always recursive.
+func NewDestinationConfig(
+	sourcePath, destinationPath string,
+	dryRun, overwrite, verbose bool,
+	parallelWorkers int,
+	destAddr, destToken string,
+	destAuth AuthConfig,
+	destTLS TLSConfig,
+	destNamespace string,
+	configFile string,
+) (*Config, error) {
+	// Load config file
+	fileConfig, err := LoadConfigFromFile(configFile)
+	if err != nil {
+		log.Printf("Warning: could not load config file: %v", err)
+		fileConfig = &FileConfig{}
+	}
+
+	cfg := &Config{
+		SourcePath:      sourcePath,
+		DestinationPath: destinationPath,
+		Recursive:       true,
+		DryRun:          dryRun,
+		Overwrite:       overwrite,
+		ParallelWorkers: parallelWorkers,
+		Verbose:         verbose,
+		Retry:           DefaultRetryConfig(),
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.resolveDestination(fileConfig, destAddr, destToken, destAuth, destTLS, destNamespace); err != nil {
+		return nil, err
+	}
+
+	cfg.applyFileSettings(fileConfig, true, dryRun, overwrite, verbose, parallelWorkers)
+
+	return cfg, nil
+}
+
+// resolveSource fills in the address, token, namespace, TLS and auth settings of the source Vault.
+// Priority: function parameter > environment variable > config file > default
+func (c *Config) resolveSource(fileConfig *FileConfig, sourceAddr, sourceToken string, sourceAuth AuthConfig, sourceTLS TLSConfig, sourceNamespace string) error {
 	if sourceAddr == "" {
 		sourceAddr = os.Getenv("VAULT_SOURCE_ADDR")
 	}
@@ -123,7 +558,17 @@ func NewConfig(
 	if sourceAddr == "" {
 		sourceAddr = "http://localhost:8200"
 	}
-	cfg.SourceAddr = sourceAddr
+
+	// A file:// source needs no Vault settings
+	backend, sourceAddr, err := ParseBackendURL(sourceAddr)
+	if err != nil {
+		return fmt.Errorf("source address: %v", err)
+	}
+	c.SourceBackend = backend
+	c.SourceAddr = sourceAddr
+	if backend.Type == BackendFile {
+		return nil
+	}
 
 	if sourceToken == "" {
 		sourceToken = os.Getenv("VAULT_SOURCE_TOKEN")
@@ -134,13 +579,43 @@ func NewConfig(
 	if sourceToken == "" {
 		sourceToken = os.Getenv("VAULT_TOKEN")
 	}
-	if sourceToken == "" {
-		return nil, errors.New("source Vault token not found. Set VAULT_SOURCE_TOKEN or VAULT_TOKEN")
+	c.SourceToken = sourceToken
+
+	if sourceNamespace == "" {
+		sourceNamespace = os.Getenv("VAULT_SOURCE_NAMESPACE")
+	}
+	if sourceNamespace == "" {
+		sourceNamespace = fileConfig.Source.Namespace
+	}
+	if sourceNamespace == "" {
+		sourceNamespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	c.SourceNamespace = strings.Trim(sourceNamespace, "/")
+
+	c.SourceRate, err = resolveRate("VAULT_SOURCE_", fileConfig.Source.Rate)
+	if err != nil {
+		return fmt.Errorf("source rate: %v", err)
+	}
+
+	c.SourceTLS, err = resolveTLS(sourceTLS, "VAULT_SOURCE_", fileConfig.Source.TLS)
+	if err != nil {
+		return fmt.Errorf("source TLS configuration: %v", err)
 	}
-	cfg.SourceToken = sourceToken
 
-	// Get destination Vault configuration
-	// Priority: function parameter > environment variable > config file > default
+	c.SourceAuth = resolveAuth(sourceAuth, "VAULT_SOURCE_", fileConfig.Source)
+	if err := validateAuth("source", "src", "VAULT_SOURCE_", c.SourceAuth); err != nil {
+		return err
+	}
+	if c.SourceAuth.Method == AuthMethodToken && sourceToken == "" {
+		return errors.New("source Vault token not found. Set VAULT_SOURCE_TOKEN or VAULT_TOKEN")
+	}
+
+	return nil
+}
+
+// resolveDestination fills in the address, token, namespace, TLS and auth settings of the destination Vault.
+// Priority: function parameter > environment variable > config file > default
+func (c *Config) resolveDestination(fileConfig *FileConfig, destAddr, destToken string, destAuth AuthConfig, destTLS TLSConfig, destNamespace string) error {
 	if destAddr == "" {
 		destAddr = os.Getenv("VAULT_DEST_ADDR")
 	}
@@ -151,7 +626,17 @@ func NewConfig(
 		destAddr = os.Getenv("VAULT_ADDR")
 		log.Println("VAULT_DEST_ADDR not found, using VAULT_ADDR, copying within the same Vault")
 	}
-	cfg.DestAddr = destAddr
+
+	// A file:// destination needs no Vault settings
+	backend, destAddr, err := ParseBackendURL(destAddr)
+	if err != nil {
+		return fmt.Errorf("destination address: %v", err)
+	}
+	c.DestBackend = backend
+	c.DestAddr = destAddr
+	if backend.Type == BackendFile {
+		return nil
+	}
 
 	if destToken == "" {
 		destToken = os.Getenv("VAULT_DEST_TOKEN")
@@ -162,46 +647,198 @@ func NewConfig(
 	if destToken == "" {
 		destToken = os.Getenv("VAULT_TOKEN")
 	}
-	if destToken == "" {
-		return nil, errors.New("destination Vault token not found. Set VAULT_DEST_TOKEN or VAULT_TOKEN")
+	c.DestToken = destToken
+
+	if destNamespace == "" {
+		destNamespace = os.Getenv("VAULT_DEST_NAMESPACE")
+	}
+	if destNamespace == "" {
+		destNamespace = fileConfig.Destination.Namespace
+	}
+	if destNamespace == "" {
+		destNamespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	c.DestNamespace = strings.Trim(destNamespace, "/")
+
+	c.DestRate, err = resolveRate("VAULT_DEST_", fileConfig.Destination.Rate)
+	if err != nil {
+		return fmt.Errorf("destination rate: %v", err)
+	}
+
+	c.DestTLS, err = resolveTLS(destTLS, "VAULT_DEST_", fileConfig.Destination.TLS)
+	if err != nil {
+		return fmt.Errorf("destination TLS configuration: %v", err)
 	}
-	cfg.DestToken = destToken
 
+	c.DestAuth = resolveAuth(destAuth, "VAULT_DEST_", fileConfig.Destination)
+	if err := validateAuth("destination", "dst", "VAULT_DEST_", c.DestAuth); err != nil {
+		return err
+	}
+	if c.DestAuth.Method == AuthMethodToken && destToken == "" {
+		return errors.New("destination Vault token not found. Set VAULT_DEST_TOKEN or VAULT_TOKEN")
+	}
+
+	return nil
+}
+
+// applyFileSettings applies the settings of the config file that were not given on the command line
+func (c *Config) applyFileSettings(fileConfig *FileConfig, recursive, dryRun, overwrite, verbose bool, parallelWorkers int) {
 	// Apply default settings from config file if not set by command line
 	// Command line has explicit values when flags are provided
 	// We need to check if the values are at their default state
 	if !recursive {
-		cfg.Recursive = fileConfig.Settings.Recursive
+		c.Recursive = fileConfig.Settings.Recursive
 	} else {
-		cfg.Recursive = recursive
+		c.Recursive = recursive
 	}
 
 	if !dryRun {
-		cfg.DryRun = fileConfig.Settings.DryRun
+		c.DryRun = fileConfig.Settings.DryRun
 	} else {
-		cfg.DryRun = dryRun
+		c.DryRun = dryRun
 	}
 
 	if !overwrite {
-		cfg.Overwrite = fileConfig.Settings.Overwrite
+		c.Overwrite = fileConfig.Settings.Overwrite
 	} else {
-		cfg.Overwrite = overwrite
+		c.Overwrite = overwrite
 	}
 
 	if parallelWorkers == 5 && fileConfig.Settings.Parallel != 0 {
 		// Only use config file value if command line wasn't explicitly set to default
-		cfg.ParallelWorkers = fileConfig.Settings.Parallel
+		c.ParallelWorkers = fileConfig.Settings.Parallel
 	} else {
-		cfg.ParallelWorkers = parallelWorkers
+		c.ParallelWorkers = parallelWorkers
 	}
 
 	if !verbose {
-		cfg.Verbose = fileConfig.Settings.Verbose
+		c.Verbose = fileConfig.Settings.Verbose
 	} else {
-		cfg.Verbose = verbose
+		c.Verbose = verbose
 	}
+}
 
-	return cfg, nil
+// resolveAuth fills in the auth settings of one side that were not given as parameters.
+// Priority: function parameter > environment variable (envPrefix + name) > config file > default
+func resolveAuth(auth AuthConfig, envPrefix string, fileSide VaultFileConfig) AuthConfig {
+	auth.Method = firstNonEmpty(auth.Method, os.Getenv(envPrefix+"AUTH_METHOD"), fileSide.AuthMethod, AuthMethodToken)
+	auth.Method = strings.ToLower(auth.Method)
+
+	var fileMount string
+	switch auth.Method {
+	case AuthMethodAppRole:
+		fileMount = fileSide.AppRole.MountPath
+	case AuthMethodKubernetes:
+		fileMount = fileSide.Kubernetes.MountPath
+	}
+	auth.MountPath = firstNonEmpty(auth.MountPath, os.Getenv(envPrefix+"AUTH_MOUNT"), fileMount, auth.Method)
+	auth.MountPath = strings.Trim(auth.MountPath, "/")
+
+	auth.RoleID = firstNonEmpty(auth.RoleID, os.Getenv(envPrefix+"ROLE_ID"), fileSide.AppRole.RoleID)
+	auth.SecretID = firstNonEmpty(auth.SecretID, os.Getenv(envPrefix+"SECRET_ID"), fileSide.AppRole.SecretID)
+	auth.SecretIDFile = firstNonEmpty(auth.SecretIDFile, os.Getenv(envPrefix+"SECRET_ID_FILE"), fileSide.AppRole.SecretIDFile)
+
+	auth.KubernetesRole = firstNonEmpty(auth.KubernetesRole, os.Getenv(envPrefix+"KUBERNETES_ROLE"), fileSide.Kubernetes.Role)
+	auth.JWTPath = firstNonEmpty(auth.JWTPath, os.Getenv(envPrefix+"KUBERNETES_JWT_PATH"), fileSide.Kubernetes.JWTPath)
+	if auth.Method == AuthMethodKubernetes && auth.JWTPath == "" {
+		auth.JWTPath = DefaultKubernetesJWTPath
+	}
+
+	return auth
+}
+
+// validateAuth checks that the settings required by the selected auth method are present.
+// side is used in error messages, flagPrefix and envPrefix point the user at the right option.
+func validateAuth(side, flagPrefix, envPrefix string, auth AuthConfig) error {
+	switch auth.Method {
+	case AuthMethodToken:
+		return nil
+	case AuthMethodAppRole:
+		if auth.RoleID == "" {
+			return fmt.Errorf("%s AppRole role_id not found. Set --%s-role-id or %sROLE_ID", side, flagPrefix, envPrefix)
+		}
+		if auth.SecretID == "" && auth.SecretIDFile == "" {
+			return fmt.Errorf("%s AppRole secret_id not found. Set --%s-secret-id, --%s-secret-id-file or %sSECRET_ID",
+				side, flagPrefix, flagPrefix, envPrefix)
+		}
+		return nil
+	case AuthMethodKubernetes:
+		if auth.KubernetesRole == "" {
+			return fmt.Errorf("%s Kubernetes role not found. Set --%s-k8s-role or %sKUBERNETES_ROLE", side, flagPrefix, envPrefix)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported %s auth method: %s", side, auth.Method)
+	}
+}
+
+// resolveTLS fills in the TLS settings of one side that were not given as parameters.
+// Priority: function parameter > environment variable (envPrefix + name) > config file
+func resolveTLS(tls TLSConfig, envPrefix string, fileTLS TLSFileConfig) (TLSConfig, error) {
+	tls.CACert = firstNonEmpty(tls.CACert, os.Getenv(envPrefix+"CACERT"), fileTLS.CACert)
+	tls.CAPath = firstNonEmpty(tls.CAPath, os.Getenv(envPrefix+"CAPATH"), fileTLS.CAPath)
+	tls.ClientCert = firstNonEmpty(tls.ClientCert, os.Getenv(envPrefix+"CLIENT_CERT"), fileTLS.ClientCert)
+	tls.ClientKey = firstNonEmpty(tls.ClientKey, os.Getenv(envPrefix+"CLIENT_KEY"), fileTLS.ClientKey)
+	tls.ServerName = firstNonEmpty(tls.ServerName, os.Getenv(envPrefix+"TLS_SERVER_NAME"), fileTLS.ServerName)
+
+	if !tls.Insecure {
+		if env := os.Getenv(envPrefix + "SKIP_VERIFY"); env != "" {
+			insecure, err := strconv.ParseBool(env)
+			if err != nil {
+				return tls, fmt.Errorf("invalid %sSKIP_VERIFY value %q", envPrefix, env)
+			}
+			tls.Insecure = insecure
+		} else {
+			tls.Insecure = fileTLS.SkipVerify
+		}
+	}
+
+	if (tls.ClientCert == "") != (tls.ClientKey == "") {
+		return tls, errors.New("client certificate and client key must be set together")
+	}
+
+	return tls, nil
+}
+
+// resolveRate returns the request rate of one side, 0 when it is not set.
+// Priority: environment variable (envPrefix + "RATE") > config file
+func resolveRate(envPrefix string, fileRate float64) (float64, error) {
+	env := os.Getenv(envPrefix + "RATE")
+	if env == "" {
+		return fileRate, nil
+	}
+
+	rate, err := strconv.ParseFloat(env, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %sRATE value %q", envPrefix, env)
+	}
+	return rate, nil
+}
+
+// SourceRequestRate returns the requests per second allowed to the source Vault, 0 when unlimited
+func (c *Config) SourceRequestRate() float64 {
+	if c.SourceRate > 0 {
+		return c.SourceRate
+	}
+	return c.Rate
+}
+
+// DestRequestRate returns the requests per second allowed to the destination Vault, 0 when unlimited
+func (c *Config) DestRequestRate() float64 {
+	if c.DestRate > 0 {
+		return c.DestRate
+	}
+	return c.Rate
+}
+
+// firstNonEmpty returns the first non-empty string from values
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
 }
 
 // normalizePath normalizes Vault secret paths by ensuring they don't get incorrectly modified.
@@ -235,6 +872,43 @@ func normalizePath(path string) string {
 	return path
 }
 
+// ReadPathsFile reads the source paths of a paths file, one per line.
+// Blank lines and lines starting with # are ignored.
+func ReadPathsFile(filename string) ([]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading paths file: %v", err)
+	}
+
+	var paths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("paths file %s has no paths", filename)
+	}
+
+	return paths, nil
+}
+
+// isWithinSourcePath reports whether path is sourcePath itself or lies under it.
+// For a wildcard source path only the folders before the first wildcard are compared.
+func isWithinSourcePath(path, sourcePath string) bool {
+	base := sourcePath
+	if i := strings.Index(base, "*"); i >= 0 {
+		base = base[:strings.LastIndex(base[:i], "/")+1]
+		return strings.HasPrefix(path, base)
+	}
+
+	base = strings.TrimSuffix(base, "/")
+	return path == base || strings.HasPrefix(path, base+"/")
+}
+
 // Validate checks that the configuration is valid.
 func (c *Config) Validate() error {
 	if c.SourcePath == "" {
@@ -267,5 +941,48 @@ func (c *Config) Validate() error {
 		return errors.New("parallel workers must be >= 1")
 	}
 
+	if c.ReadWorkers < 0 {
+		return errors.New("read workers must be >= 0")
+	}
+
+	if c.DeleteMode != "" && c.DeleteMode != DeleteModeSoft && c.DeleteMode != DeleteModeDestroy {
+		return fmt.Errorf("unsupported delete mode %q, use %s or %s", c.DeleteMode, DeleteModeSoft, DeleteModeDestroy)
+	}
+
+	if c.MaxDeletes < 0 {
+		return errors.New("max deletes must be >= 0")
+	}
+
+	if c.Rate < 0 || c.SourceRate < 0 || c.DestRate < 0 {
+		return errors.New("rate must be >= 0")
+	}
+
+	if c.Resume && c.StateFile == "" {
+		return errors.New("resume requires a state file")
+	}
+
+	if len(c.Paths) > 0 && c.DeleteExtraneous {
+		return errors.New("delete extraneous can't be used with a paths file")
+	}
+
+	for _, path := range c.Paths {
+		if !isWithinSourcePath(path, c.SourcePath) {
+			return fmt.Errorf("path %s is not under the source path %s", path, c.SourcePath)
+		}
+	}
+
+	if c.SourceBackend.Type == BackendFile || c.DestBackend.Type == BackendFile {
+		if c.AllVersions {
+			return errors.New("all versions can't be copied from or to a file backend")
+		}
+		if c.CopyMetadata {
+			return errors.New("metadata can't be copied from or to a file backend")
+		}
+	}
+
+	if err := c.Retry.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
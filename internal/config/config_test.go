@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestNewConfig(t *testing.T) {
@@ -145,6 +146,12 @@ func TestNewConfig(t *testing.T) {
 				sourceToken,
 				destAddr,
 				destToken,
+				AuthConfig{},  // sourceAuth
+				AuthConfig{},  // destAuth
+				TLSConfig{},   // sourceTLS
+				TLSConfig{},   // destTLS
+				"",            // sourceNamespace
+				"",            // destNamespace
 				"config.yaml", // configFile
 			)
 
@@ -255,6 +262,12 @@ settings:
 		"",                 // sourceToken (will be overridden by config file)
 		"",                 // destAddr (will be overridden by config file)
 		"",                 // destToken (will be overridden by config file)
+		AuthConfig{},       // sourceAuth
+		AuthConfig{},       // destAuth
+		TLSConfig{},        // sourceTLS
+		TLSConfig{},        // destTLS
+		"",                 // sourceNamespace
+		"",                 // destNamespace
 		"test-config.yaml", // configFile
 	)
 
@@ -367,6 +380,12 @@ destination:
 		"",                          // sourceToken (will be taken from environment)
 		"",                          // destAddr (will be taken from environment)
 		"",                          // destToken (will be taken from environment)
+		AuthConfig{},                // sourceAuth
+		AuthConfig{},                // destAuth
+		TLSConfig{},                 // sourceTLS
+		TLSConfig{},                 // destTLS
+		"",                          // sourceNamespace
+		"",                          // destNamespace
 		"priority-test-config.yaml", // configFile
 	)
 
@@ -398,6 +417,729 @@ destination:
 	}
 }
 
+func TestNewConfigAppRole(t *testing.T) {
+	// Save original environment variables
+	originalEnv := map[string]string{
+		"VAULT_SOURCE_TOKEN":       os.Getenv("VAULT_SOURCE_TOKEN"),
+		"VAULT_DEST_TOKEN":         os.Getenv("VAULT_DEST_TOKEN"),
+		"VAULT_TOKEN":              os.Getenv("VAULT_TOKEN"),
+		"VAULT_SOURCE_AUTH_METHOD": os.Getenv("VAULT_SOURCE_AUTH_METHOD"),
+		"VAULT_SOURCE_ROLE_ID":     os.Getenv("VAULT_SOURCE_ROLE_ID"),
+		"VAULT_SOURCE_SECRET_ID":   os.Getenv("VAULT_SOURCE_SECRET_ID"),
+		"VAULT_DEST_AUTH_METHOD":   os.Getenv("VAULT_DEST_AUTH_METHOD"),
+	}
+	defer func() {
+		for k, v := range originalEnv {
+			if v != "" {
+				os.Setenv(k, v)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}()
+
+	// Clear environment variables
+	for k := range originalEnv {
+		os.Unsetenv(k)
+	}
+
+	configContent := `
+source:
+  auth_method: approle
+  approle:
+    mount_path: ci-approle
+    role_id: "file-role"
+    secret_id_file: "/run/secrets/secret-id"
+destination:
+  token: "dest-file-token"
+`
+
+	err := os.WriteFile("approle-test-config.yaml", []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove("approle-test-config.yaml")
+
+	t.Run("source approle from config file, destination token", func(t *testing.T) {
+		os.Setenv("VAULT_SOURCE_ROLE_ID", "env-role")
+
+		cfg, err := NewConfig(
+			"secret/data/app",
+			"secret/data/backup",
+			false, false, false, false, 5,
+			"", "", "", "",
+			AuthConfig{},
+			AuthConfig{},
+			TLSConfig{},
+			TLSConfig{},
+			"", "",
+			"approle-test-config.yaml",
+		)
+		os.Unsetenv("VAULT_SOURCE_ROLE_ID")
+		if err != nil {
+			t.Fatalf("NewConfig() unexpected error = %v", err)
+		}
+
+		if cfg.SourceAuth.Method != AuthMethodAppRole {
+			t.Errorf("SourceAuth.Method = %v, want %v", cfg.SourceAuth.Method, AuthMethodAppRole)
+		}
+		if cfg.SourceAuth.MountPath != "ci-approle" {
+			t.Errorf("SourceAuth.MountPath = %v, want %v", cfg.SourceAuth.MountPath, "ci-approle")
+		}
+		// Environment variable has higher priority than config file
+		if cfg.SourceAuth.RoleID != "env-role" {
+			t.Errorf("SourceAuth.RoleID = %v, want %v", cfg.SourceAuth.RoleID, "env-role")
+		}
+		if cfg.SourceAuth.SecretIDFile != "/run/secrets/secret-id" {
+			t.Errorf("SourceAuth.SecretIDFile = %v, want %v", cfg.SourceAuth.SecretIDFile, "/run/secrets/secret-id")
+		}
+		if cfg.DestAuth.Method != AuthMethodToken {
+			t.Errorf("DestAuth.Method = %v, want %v", cfg.DestAuth.Method, AuthMethodToken)
+		}
+	})
+
+	t.Run("destination approle from parameters with default mount", func(t *testing.T) {
+		cfg, err := NewConfig(
+			"secret/data/app",
+			"secret/data/backup",
+			false, false, false, false, 5,
+			"", "", "", "",
+			AuthConfig{},
+			AuthConfig{Method: AuthMethodAppRole, RoleID: "role", SecretID: "secret"},
+			TLSConfig{},
+			TLSConfig{},
+			"", "",
+			"approle-test-config.yaml",
+		)
+		if err != nil {
+			t.Fatalf("NewConfig() unexpected error = %v", err)
+		}
+
+		if cfg.DestAuth.MountPath != "approle" {
+			t.Errorf("DestAuth.MountPath = %v, want %v", cfg.DestAuth.MountPath, "approle")
+		}
+		if cfg.DestAuth.SecretID != "secret" {
+			t.Errorf("DestAuth.SecretID = %v, want %v", cfg.DestAuth.SecretID, "secret")
+		}
+	})
+
+	t.Run("missing secret_id", func(t *testing.T) {
+		_, err := NewConfig(
+			"secret/data/app",
+			"secret/data/backup",
+			false, false, false, false, 5,
+			"", "", "", "dest-token",
+			AuthConfig{Method: AuthMethodAppRole, RoleID: "role"},
+			AuthConfig{},
+			TLSConfig{},
+			TLSConfig{},
+			"", "",
+			"missing-config.yaml",
+		)
+		if err == nil || !contains(err.Error(), "source AppRole secret_id not found") {
+			t.Errorf("NewConfig() error = %v, want containing %v", err, "source AppRole secret_id not found")
+		}
+	})
+
+	t.Run("kubernetes with default mount and JWT path", func(t *testing.T) {
+		cfg, err := NewConfig(
+			"secret/data/app",
+			"secret/data/backup",
+			false, false, false, false, 5,
+			"", "source-token", "", "",
+			AuthConfig{Method: AuthMethodToken},
+			AuthConfig{Method: AuthMethodKubernetes, KubernetesRole: "vault-copy"},
+			TLSConfig{},
+			TLSConfig{},
+			"", "",
+			"missing-config.yaml",
+		)
+		if err != nil {
+			t.Fatalf("NewConfig() unexpected error = %v", err)
+		}
+		if cfg.DestAuth.MountPath != "kubernetes" {
+			t.Errorf("DestAuth.MountPath = %v, want %v", cfg.DestAuth.MountPath, "kubernetes")
+		}
+		if cfg.DestAuth.JWTPath != DefaultKubernetesJWTPath {
+			t.Errorf("DestAuth.JWTPath = %v, want %v", cfg.DestAuth.JWTPath, DefaultKubernetesJWTPath)
+		}
+	})
+
+	t.Run("kubernetes without role", func(t *testing.T) {
+		_, err := NewConfig(
+			"secret/data/app",
+			"secret/data/backup",
+			false, false, false, false, 5,
+			"", "source-token", "", "",
+			AuthConfig{},
+			AuthConfig{Method: AuthMethodKubernetes},
+			TLSConfig{},
+			TLSConfig{},
+			"", "",
+			"missing-config.yaml",
+		)
+		if err == nil || !contains(err.Error(), "destination Kubernetes role not found") {
+			t.Errorf("NewConfig() error = %v, want containing %v", err, "destination Kubernetes role not found")
+		}
+	})
+
+	t.Run("unsupported method", func(t *testing.T) {
+		_, err := NewConfig(
+			"secret/data/app",
+			"secret/data/backup",
+			false, false, false, false, 5,
+			"", "source-token", "", "dest-token",
+			AuthConfig{},
+			AuthConfig{Method: "ldap"},
+			TLSConfig{},
+			TLSConfig{},
+			"", "",
+			"missing-config.yaml",
+		)
+		if err == nil || !contains(err.Error(), "unsupported destination auth method") {
+			t.Errorf("NewConfig() error = %v, want containing %v", err, "unsupported destination auth method")
+		}
+	})
+}
+
+func TestNewConfigTLS(t *testing.T) {
+	// Save original environment variables
+	originalEnv := map[string]string{
+		"VAULT_SOURCE_TOKEN":       os.Getenv("VAULT_SOURCE_TOKEN"),
+		"VAULT_DEST_TOKEN":         os.Getenv("VAULT_DEST_TOKEN"),
+		"VAULT_SOURCE_CACERT":      os.Getenv("VAULT_SOURCE_CACERT"),
+		"VAULT_DEST_SKIP_VERIFY":   os.Getenv("VAULT_DEST_SKIP_VERIFY"),
+		"VAULT_DEST_CLIENT_CERT":   os.Getenv("VAULT_DEST_CLIENT_CERT"),
+		"VAULT_SOURCE_AUTH_METHOD": os.Getenv("VAULT_SOURCE_AUTH_METHOD"),
+		"VAULT_DEST_AUTH_METHOD":   os.Getenv("VAULT_DEST_AUTH_METHOD"),
+	}
+	defer func() {
+		for k, v := range originalEnv {
+			if v != "" {
+				os.Setenv(k, v)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}()
+
+	// Clear environment variables
+	for k := range originalEnv {
+		os.Unsetenv(k)
+	}
+	os.Setenv("VAULT_SOURCE_TOKEN", "source-token")
+	os.Setenv("VAULT_DEST_TOKEN", "dest-token")
+
+	configContent := `
+source:
+  tls:
+    ca_cert: "/etc/ssl/source-ca.pem"
+    server_name: "vault.internal"
+destination:
+  tls:
+    ca_path: "/etc/ssl/dest-cas"
+    client_cert: "/etc/ssl/client.pem"
+    client_key: "/etc/ssl/client-key.pem"
+`
+
+	err := os.WriteFile("tls-test-config.yaml", []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove("tls-test-config.yaml")
+
+	t.Run("per side settings from config file and environment", func(t *testing.T) {
+		os.Setenv("VAULT_SOURCE_CACERT", "/etc/ssl/env-ca.pem")
+		os.Setenv("VAULT_DEST_SKIP_VERIFY", "true")
+		defer os.Unsetenv("VAULT_SOURCE_CACERT")
+		defer os.Unsetenv("VAULT_DEST_SKIP_VERIFY")
+
+		cfg, err := NewConfig(
+			"secret/data/app",
+			"secret/data/backup",
+			false, false, false, false, 5,
+			"", "", "", "",
+			AuthConfig{},
+			AuthConfig{},
+			TLSConfig{},
+			TLSConfig{},
+			"", "",
+			"tls-test-config.yaml",
+		)
+		if err != nil {
+			t.Fatalf("NewConfig() unexpected error = %v", err)
+		}
+
+		if cfg.SourceTLS.CACert != "/etc/ssl/env-ca.pem" {
+			t.Errorf("SourceTLS.CACert = %v, want %v", cfg.SourceTLS.CACert, "/etc/ssl/env-ca.pem")
+		}
+		if cfg.SourceTLS.ServerName != "vault.internal" {
+			t.Errorf("SourceTLS.ServerName = %v, want %v", cfg.SourceTLS.ServerName, "vault.internal")
+		}
+		if cfg.SourceTLS.Insecure {
+			t.Errorf("SourceTLS.Insecure = %v, want %v", cfg.SourceTLS.Insecure, false)
+		}
+		if cfg.DestTLS.CAPath != "/etc/ssl/dest-cas" {
+			t.Errorf("DestTLS.CAPath = %v, want %v", cfg.DestTLS.CAPath, "/etc/ssl/dest-cas")
+		}
+		if cfg.DestTLS.ClientCert != "/etc/ssl/client.pem" || cfg.DestTLS.ClientKey != "/etc/ssl/client-key.pem" {
+			t.Errorf("DestTLS client cert = %v/%v, want file values", cfg.DestTLS.ClientCert, cfg.DestTLS.ClientKey)
+		}
+		if !cfg.DestTLS.Insecure {
+			t.Errorf("DestTLS.Insecure = %v, want %v", cfg.DestTLS.Insecure, true)
+		}
+	})
+
+	t.Run("parameters override config file", func(t *testing.T) {
+		cfg, err := NewConfig(
+			"secret/data/app",
+			"secret/data/backup",
+			false, false, false, false, 5,
+			"", "", "", "",
+			AuthConfig{},
+			AuthConfig{},
+			TLSConfig{CACert: "/tmp/flag-ca.pem", Insecure: true},
+			TLSConfig{},
+			"", "",
+			"tls-test-config.yaml",
+		)
+		if err != nil {
+			t.Fatalf("NewConfig() unexpected error = %v", err)
+		}
+
+		if cfg.SourceTLS.CACert != "/tmp/flag-ca.pem" {
+			t.Errorf("SourceTLS.CACert = %v, want %v", cfg.SourceTLS.CACert, "/tmp/flag-ca.pem")
+		}
+		if !cfg.SourceTLS.Insecure {
+			t.Errorf("SourceTLS.Insecure = %v, want %v", cfg.SourceTLS.Insecure, true)
+		}
+	})
+
+	t.Run("client certificate without key", func(t *testing.T) {
+		_, err := NewConfig(
+			"secret/data/app",
+			"secret/data/backup",
+			false, false, false, false, 5,
+			"", "", "", "",
+			AuthConfig{},
+			AuthConfig{},
+			TLSConfig{},
+			TLSConfig{ClientCert: "/tmp/client.pem"},
+			"", "",
+			"missing-config.yaml",
+		)
+		if err == nil || !contains(err.Error(), "destination TLS configuration") {
+			t.Errorf("NewConfig() error = %v, want containing %v", err, "destination TLS configuration")
+		}
+	})
+
+	t.Run("invalid skip verify value", func(t *testing.T) {
+		os.Setenv("VAULT_DEST_SKIP_VERIFY", "maybe")
+		defer os.Unsetenv("VAULT_DEST_SKIP_VERIFY")
+
+		_, err := NewConfig(
+			"secret/data/app",
+			"secret/data/backup",
+			false, false, false, false, 5,
+			"", "", "", "",
+			AuthConfig{},
+			AuthConfig{},
+			TLSConfig{},
+			TLSConfig{},
+			"", "",
+			"missing-config.yaml",
+		)
+		if err == nil || !contains(err.Error(), "VAULT_DEST_SKIP_VERIFY") {
+			t.Errorf("NewConfig() error = %v, want containing %v", err, "VAULT_DEST_SKIP_VERIFY")
+		}
+	})
+}
+
+func TestNewConfigNamespace(t *testing.T) {
+	// Save original environment variables
+	originalEnv := map[string]string{
+		"VAULT_SOURCE_TOKEN":     os.Getenv("VAULT_SOURCE_TOKEN"),
+		"VAULT_DEST_TOKEN":       os.Getenv("VAULT_DEST_TOKEN"),
+		"VAULT_SOURCE_NAMESPACE": os.Getenv("VAULT_SOURCE_NAMESPACE"),
+		"VAULT_DEST_NAMESPACE":   os.Getenv("VAULT_DEST_NAMESPACE"),
+		"VAULT_NAMESPACE":        os.Getenv("VAULT_NAMESPACE"),
+	}
+	defer func() {
+		for k, v := range originalEnv {
+			if v != "" {
+				os.Setenv(k, v)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}()
+
+	// Clear environment variables
+	for k := range originalEnv {
+		os.Unsetenv(k)
+	}
+	os.Setenv("VAULT_SOURCE_TOKEN", "source-token")
+	os.Setenv("VAULT_DEST_TOKEN", "dest-token")
+
+	configContent := `
+source:
+  namespace: "team-a"
+destination:
+  namespace: "team-b/prod/"
+`
+
+	err := os.WriteFile("namespace-test-config.yaml", []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove("namespace-test-config.yaml")
+
+	tests := []struct {
+		name         string
+		envVars      map[string]string
+		srcNamespace string
+		configFile   string
+		wantSourceNS string
+		wantDestNS   string
+	}{
+		{
+			name:         "namespaces from config file",
+			configFile:   "namespace-test-config.yaml",
+			wantSourceNS: "team-a",
+			wantDestNS:   "team-b/prod",
+		},
+		{
+			name:         "parameter and environment override config file",
+			envVars:      map[string]string{"VAULT_DEST_NAMESPACE": "team-c"},
+			srcNamespace: "team-x",
+			configFile:   "namespace-test-config.yaml",
+			wantSourceNS: "team-x",
+			wantDestNS:   "team-c",
+		},
+		{
+			name:         "VAULT_NAMESPACE fallback for both sides",
+			envVars:      map[string]string{"VAULT_NAMESPACE": "shared"},
+			configFile:   "missing-config.yaml",
+			wantSourceNS: "shared",
+			wantDestNS:   "shared",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+
+			cfg, err := NewConfig(
+				"secret/data/app",
+				"secret/data/backup",
+				false, false, false, false, 5,
+				"", "", "", "",
+				AuthConfig{},
+				AuthConfig{},
+				TLSConfig{},
+				TLSConfig{},
+				tt.srcNamespace, "",
+				tt.configFile,
+			)
+			if err != nil {
+				t.Fatalf("NewConfig() unexpected error = %v", err)
+			}
+
+			if cfg.SourceNamespace != tt.wantSourceNS {
+				t.Errorf("SourceNamespace = %v, want %v", cfg.SourceNamespace, tt.wantSourceNS)
+			}
+			if cfg.DestNamespace != tt.wantDestNS {
+				t.Errorf("DestNamespace = %v, want %v", cfg.DestNamespace, tt.wantDestNS)
+			}
+		})
+	}
+}
+
+func TestNewConfigRate(t *testing.T) {
+	// Save original environment variables
+	originalEnv := map[string]string{
+		"VAULT_SOURCE_TOKEN": os.Getenv("VAULT_SOURCE_TOKEN"),
+		"VAULT_DEST_TOKEN":   os.Getenv("VAULT_DEST_TOKEN"),
+		"VAULT_SOURCE_RATE":  os.Getenv("VAULT_SOURCE_RATE"),
+		"VAULT_DEST_RATE":    os.Getenv("VAULT_DEST_RATE"),
+	}
+	defer func() {
+		for k, v := range originalEnv {
+			if v != "" {
+				os.Setenv(k, v)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}()
+
+	// Clear environment variables
+	for k := range originalEnv {
+		os.Unsetenv(k)
+	}
+	os.Setenv("VAULT_SOURCE_TOKEN", "source-token")
+	os.Setenv("VAULT_DEST_TOKEN", "dest-token")
+
+	configContent := `
+source:
+  rate: 10
+destination:
+  rate: 200
+`
+
+	err := os.WriteFile("rate-test-config.yaml", []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove("rate-test-config.yaml")
+
+	tests := []struct {
+		name           string
+		envVars        map[string]string
+		configFile     string
+		rate           float64
+		wantSourceRate float64
+		wantDestRate   float64
+		wantErr        bool
+	}{
+		{
+			name:           "rates from config file",
+			configFile:     "rate-test-config.yaml",
+			wantSourceRate: 10,
+			wantDestRate:   200,
+		},
+		{
+			name:           "environment overrides config file",
+			envVars:        map[string]string{"VAULT_SOURCE_RATE": "2.5"},
+			configFile:     "rate-test-config.yaml",
+			wantSourceRate: 2.5,
+			wantDestRate:   200,
+		},
+		{
+			name:           "shared rate fallback for an unset side",
+			envVars:        map[string]string{"VAULT_SOURCE_RATE": "5"},
+			configFile:     "missing-config.yaml",
+			rate:           50,
+			wantSourceRate: 5,
+			wantDestRate:   50,
+		},
+		{
+			name:       "invalid environment value",
+			envVars:    map[string]string{"VAULT_DEST_RATE": "fast"},
+			configFile: "missing-config.yaml",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.envVars {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+
+			cfg, err := NewConfig(
+				"secret/data/app",
+				"secret/data/backup",
+				false, false, false, false, 5,
+				"", "", "", "",
+				AuthConfig{},
+				AuthConfig{},
+				TLSConfig{},
+				TLSConfig{},
+				"", "",
+				tt.configFile,
+			)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			cfg.Rate = tt.rate
+
+			if got := cfg.SourceRequestRate(); got != tt.wantSourceRate {
+				t.Errorf("SourceRequestRate() = %v, want %v", got, tt.wantSourceRate)
+			}
+			if got := cfg.DestRequestRate(); got != tt.wantDestRate {
+				t.Errorf("DestRequestRate() = %v, want %v", got, tt.wantDestRate)
+			}
+		})
+	}
+}
+
+func TestNewSourceConfig(t *testing.T) {
+	// Save original environment variables
+	originalEnv := map[string]string{
+		"VAULT_SOURCE_TOKEN": os.Getenv("VAULT_SOURCE_TOKEN"),
+		"VAULT_DEST_TOKEN":   os.Getenv("VAULT_DEST_TOKEN"),
+		"VAULT_TOKEN":        os.Getenv("VAULT_TOKEN"),
+	}
+	defer func() {
+		for k, v := range originalEnv {
+			if v != "" {
+				os.Setenv(k, v)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}()
+
+	// Clear environment variables
+	for k := range originalEnv {
+		os.Unsetenv(k)
+	}
+
+	// No destination token is needed
+	os.Setenv("VAULT_SOURCE_TOKEN", "source-token")
+	cfg, err := NewSourceConfig("secret/data/app", false, "https://vault:8200", "", AuthConfig{}, TLSConfig{}, "", "missing-config.yaml")
+	if err != nil {
+		t.Fatalf("NewSourceConfig() unexpected error = %v", err)
+	}
+	if cfg.SourceToken != "source-token" || cfg.SourceAddr != "https://vault:8200" {
+		t.Errorf("source = %s %s, want https://vault:8200 source-token", cfg.SourceAddr, cfg.SourceToken)
+	}
+	if cfg.SourcePath != "secret/data/app" || !cfg.Recursive {
+		t.Errorf("SourcePath = %s, Recursive = %v", cfg.SourcePath, cfg.Recursive)
+	}
+
+	os.Unsetenv("VAULT_SOURCE_TOKEN")
+	if _, err := NewSourceConfig("secret/data/app", false, "", "", AuthConfig{}, TLSConfig{}, "", "missing-config.yaml"); err == nil {
+		t.Error("NewSourceConfig() expected error without a source token, got nil")
+	}
+}
+
+func TestNewDestinationConfig(t *testing.T) {
+	// Save original environment variables
+	originalEnv := map[string]string{
+		"VAULT_SOURCE_TOKEN": os.Getenv("VAULT_SOURCE_TOKEN"),
+		"VAULT_DEST_TOKEN":   os.Getenv("VAULT_DEST_TOKEN"),
+		"VAULT_TOKEN":        os.Getenv("VAULT_TOKEN"),
+	}
+	defer func() {
+		for k, v := range originalEnv {
+			if v != "" {
+				os.Setenv(k, v)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}()
+
+	// Clear environment variables
+	for k := range originalEnv {
+		os.Unsetenv(k)
+	}
+
+	// No source token is needed
+	os.Setenv("VAULT_DEST_TOKEN", "dest-token")
+	cfg, err := NewDestinationConfig("secret/data/app", "secret/data/restored", true, true, false, 3,
+		"https://vault:8200", "", AuthConfig{}, TLSConfig{}, "", "missing-config.yaml")
+	if err != nil {
+		t.Fatalf("NewDestinationConfig() unexpected error = %v", err)
+	}
+	if cfg.DestToken != "dest-token" || cfg.DestAddr != "https://vault:8200" {
+		t.Errorf("destination = %s %s, want https://vault:8200 dest-token", cfg.DestAddr, cfg.DestToken)
+	}
+	if !cfg.Recursive || !cfg.DryRun || !cfg.Overwrite || cfg.ParallelWorkers != 3 {
+		t.Errorf("settings = recursive %v, dry-run %v, overwrite %v, parallel %d", cfg.Recursive, cfg.DryRun, cfg.Overwrite, cfg.ParallelWorkers)
+	}
+
+	os.Unsetenv("VAULT_DEST_TOKEN")
+	if _, err := NewDestinationConfig("secret/data/app", "secret/data/restored", false, false, false, 5,
+		"", "", AuthConfig{}, TLSConfig{}, "", "missing-config.yaml"); err == nil {
+		t.Error("NewDestinationConfig() expected error without a destination token, got nil")
+	}
+}
+
+func TestParseBackendURL(t *testing.T) {
+	tests := []struct {
+		raw         string
+		wantBackend BackendConfig
+		wantAddr    string
+		wantErr     bool
+	}{
+		{raw: "https://vault:8200", wantBackend: BackendConfig{Type: BackendVault}, wantAddr: "https://vault:8200"},
+		{raw: "vault://vault:8200", wantBackend: BackendConfig{Type: BackendVault}, wantAddr: "https://vault:8200"},
+		{raw: "vault+http://localhost:8200", wantBackend: BackendConfig{Type: BackendVault}, wantAddr: "http://localhost:8200"},
+		{raw: "file://./secrets", wantBackend: BackendConfig{Type: BackendFile, Dir: "./secrets", Format: FileFormatJSON}, wantAddr: "file://./secrets"},
+		{raw: "file:///srv/secrets?format=yaml", wantBackend: BackendConfig{Type: BackendFile, Dir: "/srv/secrets", Format: FileFormatYAML}, wantAddr: "file:///srv/secrets?format=yaml"},
+		{raw: "file://secrets?format=yml", wantBackend: BackendConfig{Type: BackendFile, Dir: "secrets", Format: FileFormatYAML}, wantAddr: "file://secrets?format=yml"},
+		{raw: "file://", wantErr: true},
+		{raw: "file://secrets?format=xml", wantErr: true},
+		{raw: "file://secrets?mode=0600", wantErr: true},
+		{raw: "vault://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			backend, addr, err := ParseBackendURL(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBackendURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if backend != tt.wantBackend || addr != tt.wantAddr {
+				t.Errorf("ParseBackendURL() = %+v, %s, want %+v, %s", backend, addr, tt.wantBackend, tt.wantAddr)
+			}
+		})
+	}
+}
+
+func TestNewConfigFileBackend(t *testing.T) {
+	// Save original environment variables
+	originalEnv := map[string]string{
+		"VAULT_SOURCE_TOKEN": os.Getenv("VAULT_SOURCE_TOKEN"),
+		"VAULT_DEST_TOKEN":   os.Getenv("VAULT_DEST_TOKEN"),
+		"VAULT_TOKEN":        os.Getenv("VAULT_TOKEN"),
+	}
+	defer func() {
+		for k, v := range originalEnv {
+			if v != "" {
+				os.Setenv(k, v)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}()
+
+	// Clear environment variables
+	for k := range originalEnv {
+		os.Unsetenv(k)
+	}
+
+	// Only the Vault side needs a token
+	os.Setenv("VAULT_DEST_TOKEN", "dest-token")
+	cfg, err := NewConfig("apps", "secret/data/apps", true, false, false, false, 5,
+		"file://./seed?format=yaml", "", "https://vault:8200", "",
+		AuthConfig{}, AuthConfig{}, TLSConfig{}, TLSConfig{}, "", "", "missing-config.yaml")
+	if err != nil {
+		t.Fatalf("NewConfig() unexpected error = %v", err)
+	}
+	if cfg.SourceBackend.Type != BackendFile || cfg.SourceBackend.Dir != "./seed" || cfg.SourceBackend.Format != FileFormatYAML {
+		t.Errorf("SourceBackend = %+v, want the yaml file backend in ./seed", cfg.SourceBackend)
+	}
+	if cfg.DestBackend.Type != BackendVault || cfg.DestToken != "dest-token" {
+		t.Errorf("DestBackend = %+v, DestToken = %s", cfg.DestBackend, cfg.DestToken)
+	}
+
+	cfg.CopyMetadata = true
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() expected error for metadata with a file backend, got nil")
+	}
+
+	os.Unsetenv("VAULT_DEST_TOKEN")
+	if _, err := NewConfig("secret/data/apps", "apps", true, false, false, false, 5,
+		"https://vault:8200", "", "file://./backup", "",
+		AuthConfig{}, AuthConfig{}, TLSConfig{}, TLSConfig{}, "", "", "missing-config.yaml"); err == nil {
+		t.Error("NewConfig() expected error without a source token, got nil")
+	}
+}
+
 func TestNormalizePath(t *testing.T) {
 	tests := []struct {
 		name string
@@ -537,6 +1279,137 @@ func TestConfigValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "destroy delete mode",
+			config: &Config{
+				SourcePath:      "secret/data/app",
+				DestinationPath: "secret/data/backup",
+				ParallelWorkers: 5,
+				DeleteMode:      DeleteModeDestroy,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unsupported delete mode",
+			config: &Config{
+				SourcePath:      "secret/data/app",
+				DestinationPath: "secret/data/backup",
+				ParallelWorkers: 5,
+				DeleteMode:      "purge",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported retry class",
+			config: &Config{
+				SourcePath:      "secret/data/app",
+				DestinationPath: "secret/data/backup",
+				ParallelWorkers: 5,
+				Retry:           RetryConfig{MaxAttempts: 3, RetryOn: []string{"4xx"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "retry max delay below base delay",
+			config: &Config{
+				SourcePath:      "secret/data/app",
+				DestinationPath: "secret/data/backup",
+				ParallelWorkers: 5,
+				Retry:           RetryConfig{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Millisecond},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative read workers",
+			config: &Config{
+				SourcePath:      "secret/data/app",
+				DestinationPath: "secret/data/backup",
+				ParallelWorkers: 5,
+				ReadWorkers:     -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative rate",
+			config: &Config{
+				SourcePath:      "secret/data/app",
+				DestinationPath: "secret/data/backup",
+				ParallelWorkers: 5,
+				Rate:            -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "default retry policy",
+			config: &Config{
+				SourcePath:      "secret/data/app",
+				DestinationPath: "secret/data/backup",
+				ParallelWorkers: 5,
+				Retry:           DefaultRetryConfig(),
+			},
+			wantErr: false,
+		},
+		{
+			name: "resume without state file",
+			config: &Config{
+				SourcePath:      "secret/data/app",
+				DestinationPath: "secret/data/backup",
+				ParallelWorkers: 5,
+				Resume:          true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative max deletes",
+			config: &Config{
+				SourcePath:      "secret/data/app",
+				DestinationPath: "secret/data/backup",
+				ParallelWorkers: 5,
+				MaxDeletes:      -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "paths under source path",
+			config: &Config{
+				SourcePath:      "secret/data/app",
+				DestinationPath: "secret/data/backup",
+				ParallelWorkers: 5,
+				Paths:           []string{"secret/data/app", "secret/data/app/db"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "path outside source path",
+			config: &Config{
+				SourcePath:      "secret/data/app",
+				DestinationPath: "secret/data/backup",
+				ParallelWorkers: 5,
+				Paths:           []string{"secret/data/application/db"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "paths under wildcard source path",
+			config: &Config{
+				SourcePath:      "secret/data/app/psql*",
+				DestinationPath: "secret/data/backup",
+				ParallelWorkers: 5,
+				Paths:           []string{"secret/data/app/psql1/password"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "paths with delete extraneous",
+			config: &Config{
+				SourcePath:       "secret/data/app",
+				DestinationPath:  "secret/data/backup",
+				ParallelWorkers:  5,
+				DeleteExtraneous: true,
+				Paths:            []string{"secret/data/app/db"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -555,3 +1428,69 @@ func TestConfigValidate(t *testing.T) {
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && (s[:len(substr)] == substr || contains(s[1:], substr)))
 }
+
+func TestRetryConfigRetriesStatus(t *testing.T) {
+	retry := RetryConfig{RetryOn: ParseRetryOn(" 429, 5XX ,,")}
+
+	tests := []struct {
+		code int
+		want bool
+	}{
+		{429, true},
+		{500, true},
+		{503, true},
+		{501, false},
+		{404, false},
+		{412, false},
+	}
+
+	for _, tt := range tests {
+		if got := retry.RetriesStatus(tt.code); got != tt.want {
+			t.Errorf("RetriesStatus(%d) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+
+	if retry.RetriesNetworkErrors() {
+		t.Error("RetriesNetworkErrors() = true without the network class, want false")
+	}
+	if !DefaultRetryConfig().RetriesNetworkErrors() {
+		t.Error("RetriesNetworkErrors() = false for the default policy, want true")
+	}
+}
+
+func TestReadPathsFile(t *testing.T) {
+	dir := t.TempDir()
+
+	filename := dir + "/failed.txt"
+	content := "# Source paths that failed\nsecret/data/app/db\n\n  secret/data/app/cache  \n"
+	if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write paths file: %v", err)
+	}
+
+	paths, err := ReadPathsFile(filename)
+	if err != nil {
+		t.Fatalf("ReadPathsFile() error = %v", err)
+	}
+
+	want := []string{"secret/data/app/db", "secret/data/app/cache"}
+	if len(paths) != len(want) {
+		t.Fatalf("ReadPathsFile() = %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("path %d = %q, want %q", i, paths[i], want[i])
+		}
+	}
+
+	empty := dir + "/empty.txt"
+	if err := os.WriteFile(empty, []byte("# nothing failed\n"), 0600); err != nil {
+		t.Fatalf("failed to write paths file: %v", err)
+	}
+	if _, err := ReadPathsFile(empty); err == nil {
+		t.Error("ReadPathsFile() expected error for a file without paths, got nil")
+	}
+
+	if _, err := ReadPathsFile(dir + "/missing.txt"); err == nil {
+		t.Error("ReadPathsFile() expected error for a missing file, got nil")
+	}
+}
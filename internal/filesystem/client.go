@@ -0,0 +1,481 @@
+// Package filesystem implements a secret backend on a local directory tree.
+//
+// Every secret is a JSON or YAML file holding the secret's key-value pairs, and its path is the
+// file path relative to the root directory without the extension: apps/db.yaml is the secret apps/db.
+// Directories are folders. Files are written in the configured format, keeping the format of an
+// existing file; hidden files and directories and files with other extensions are ignored.
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+	"vault-copy/internal/vault"
+	"vault-copy/pkg/utils"
+)
+
+// extensions are the secret file extensions, in the order they are looked up
+var extensions = []string{".json", ".yaml", ".yml"}
+
+// Ensure that Client implements the interfaces used by the sync manager
+var (
+	_ vault.ClientInterface = (*Client)(nil)
+	_ vault.Deleter         = (*Client)(nil)
+)
+
+// Client reads and writes secrets as files under a root directory
+type Client struct {
+	dir    string
+	format string
+}
+
+// NewClient creates a client for the directory tree rooted at dir, writing new secrets in format.
+// The directory is created by the first write when it doesn't exist.
+func NewClient(dir, format string) (*Client, error) {
+	if dir == "" {
+		return nil, errors.New("file backend directory cannot be empty")
+	}
+	if format != config.FileFormatJSON && format != config.FileFormatYAML {
+		return nil, fmt.Errorf("unsupported file format %q, use %s or %s", format, config.FileFormatJSON, config.FileFormatYAML)
+	}
+
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("file backend path %s is not a directory", dir)
+	}
+
+	return &Client{dir: filepath.Clean(dir), format: format}, nil
+}
+
+// ReadSecret reads the secret file of path
+func (c *Client) ReadSecret(path string, logger *logger.Logger) (*vault.Secret, error) {
+	file, err := c.secretFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if file == "" {
+		return nil, fmt.Errorf("secret not found: %s", path)
+	}
+
+	logger.Verbose("Reading secret file: %s", file)
+	data, err := readFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading secret %s: %v", path, err)
+	}
+
+	return &vault.Secret{Path: path, Data: data}, nil
+}
+
+// IsDirectory reports whether path is a directory
+func (c *Client) IsDirectory(path string, logger *logger.Logger) (bool, error) {
+	dir, err := c.resolve(path)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+// ListSecrets lists the secrets and folders directly under path, folders with a trailing slash as Vault does
+func (c *Client) ListSecrets(path string, logger *logger.Logger) ([]string, error) {
+	dir, err := c.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		logger.Verbose("No secrets in: %s", path)
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		item := ""
+		if entry.IsDir() {
+			item = name + "/"
+		} else if ext := filepath.Ext(name); isSecretExtension(ext) {
+			item = strings.TrimSuffix(name, ext)
+		}
+
+		if item != "" && !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+	sort.Strings(items)
+
+	logger.Verbose("Found %d secrets in: %s", len(items), path)
+	return items, nil
+}
+
+// GetAllSecrets reads the secrets under rootPath recursively, or the secret at rootPath itself.
+// Folders and files below rootPath that can't be read are sent as *vault.PathError on the error channel
+// while the walk goes on, as for a Vault walk. The caller must read from both channels until they are closed,
+// or cancel ctx when it stops reading.
+func (c *Client) GetAllSecrets(ctx context.Context, rootPath string, logger *logger.Logger) (<-chan *vault.Secret, <-chan error) {
+	secretsChan := make(chan *vault.Secret, 100)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(secretsChan)
+		defer close(errChan)
+
+		isDir, err := c.IsDirectory(rootPath, logger)
+		if err != nil {
+			errChan <- &vault.PathError{Path: rootPath, Err: err}
+			return
+		}
+		if !isDir {
+			if file, _ := c.secretFile(rootPath); file != "" {
+				c.sendSecret(ctx, rootPath, secretsChan, errChan, logger)
+			}
+			return
+		}
+
+		c.walk(ctx, strings.TrimSuffix(rootPath, "/"), secretsChan, errChan, logger)
+	}()
+
+	return secretsChan, errChan
+}
+
+// walk sends the secrets under the folder path, reporting the folders and files that can't be read.
+// It returns false once ctx is cancelled.
+func (c *Client) walk(ctx context.Context, path string, secretsChan chan<- *vault.Secret, errChan chan<- error, logger *logger.Logger) bool {
+	items, err := c.ListSecrets(path, logger)
+	if err != nil {
+		logger.Verbose("Walk goes on after error: %v", err)
+		return sendError(ctx, errChan, &vault.PathError{Path: path, Err: err})
+	}
+
+	for _, item := range items {
+		itemPath := strings.TrimSuffix(item, "/")
+		if path != "" {
+			itemPath = vault.BuildPath(path, itemPath)
+		}
+
+		if strings.HasSuffix(item, "/") {
+			if !c.walk(ctx, itemPath, secretsChan, errChan, logger) {
+				return false
+			}
+			continue
+		}
+
+		if !c.sendSecret(ctx, itemPath, secretsChan, errChan, logger) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sendSecret reads the secret at path and sends it, or reports it when it can't be read.
+// It returns false once ctx is cancelled.
+func (c *Client) sendSecret(ctx context.Context, path string, secretsChan chan<- *vault.Secret, errChan chan<- error, logger *logger.Logger) bool {
+	secret, err := c.ReadSecret(path, logger)
+	if err != nil {
+		logger.Verbose("Walk goes on after error: %v", err)
+		return sendError(ctx, errChan, &vault.PathError{Path: path, Err: err})
+	}
+
+	select {
+	case secretsChan <- secret:
+		return true
+	case <-ctx.Done():
+		sendError(ctx, errChan, ctx.Err())
+		return false
+	}
+}
+
+// sendError sends err on the error channel, returning false once ctx is cancelled.
+// Nobody may be reading anymore when the caller cancelled the walk.
+func sendError(ctx context.Context, errChan chan<- error, err error) bool {
+	select {
+	case errChan <- err:
+		return ctx.Err() == nil
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// ExpandWildcardPath returns the secret and folder paths matching pattern, segment by segment
+func (c *Client) ExpandWildcardPath(pattern string, logger *logger.Logger) ([]string, error) {
+	if !strings.Contains(pattern, "*") {
+		return []string{pattern}, nil
+	}
+
+	// Only the tree above the first wildcard segment has to be walked
+	base := pattern[:strings.Index(pattern, "*")]
+	if i := strings.LastIndex(base, "/"); i >= 0 {
+		base = base[:i]
+	} else {
+		base = ""
+	}
+
+	// The walk is stopped when returning early on an error
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var paths []string
+	secrets, errs := c.GetAllSecrets(ctx, base, logger)
+	for secrets != nil || errs != nil {
+		select {
+		case secret, ok := <-secrets:
+			if !ok {
+				secrets = nil
+				continue
+			}
+			paths = append(paths, secret.Path)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	matches, err := vault.MatchWildcard(pattern, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Verbose("Wildcard %s matched %d paths in %s", pattern, len(matches), c.dir)
+	return matches, nil
+}
+
+// WriteSecret writes the secret file of path, replacing it atomically
+func (c *Client) WriteSecret(path string, data map[string]interface{}, logger *logger.Logger) error {
+	file, err := c.secretFile(path)
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		base, err := c.resolve(path)
+		if err != nil {
+			return err
+		}
+		if base == c.dir {
+			return fmt.Errorf("invalid secret path: %q", path)
+		}
+		file = base + "." + c.format
+	}
+
+	content, err := encode(filepath.Ext(file), data)
+	if err != nil {
+		return fmt.Errorf("error encoding secret %s: %v", path, err)
+	}
+
+	logger.Verbose("Writing secret file: %s", file)
+	if err := writeFile(file, content); err != nil {
+		return fmt.Errorf("error writing secret %s: %v", path, err)
+	}
+	return nil
+}
+
+// SecretExists reports whether a secret file exists for path
+func (c *Client) SecretExists(path string, logger *logger.Logger) (bool, error) {
+	file, err := c.secretFile(path)
+	if err != nil {
+		return false, err
+	}
+	return file != "", nil
+}
+
+// BatchWriteSecrets writes the secrets read from the channel under basePath
+func (c *Client) BatchWriteSecrets(ctx context.Context, secrets <-chan *vault.Secret, basePath string, logger *logger.Logger) <-chan error {
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(errChan)
+
+		for secret := range secrets {
+			select {
+			case <-ctx.Done():
+				errChan <- ctx.Err()
+				return
+			default:
+			}
+
+			destPath := vault.TransformPath(secret.Path, basePath, logger)
+			if err := c.WriteSecret(destPath, secret.Data, logger); err != nil {
+				errChan <- err
+			}
+		}
+	}()
+
+	return errChan
+}
+
+// DeleteSecret removes the secret file of path. Files have no version history, so destroy makes no difference.
+func (c *Client) DeleteSecret(path string, destroy bool, logger *logger.Logger) error {
+	file, err := c.secretFile(path)
+	if err != nil || file == "" {
+		return err
+	}
+
+	logger.Verbose("Removing secret file: %s", file)
+	return os.Remove(file)
+}
+
+// GetKVEngine returns the first segment of path, as the Vault client does
+func (c *Client) GetKVEngine(path string) (string, error) {
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) < 2 {
+		return "secret", nil
+	}
+	return parts[0], nil
+}
+
+// GetKVEngineVersion always fails, a directory is not a KV engine
+func (c *Client) GetKVEngineVersion(engine string, logger *logger.Logger) (int, error) {
+	return 0, fmt.Errorf("%s is a directory, not a KV engine", c.dir)
+}
+
+// resolve returns the file system path of a secret path, without extension.
+// Paths can't leave the root directory.
+func (c *Client) resolve(path string) (string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return c.dir, nil
+	}
+
+	for _, part := range strings.Split(path, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("invalid secret path: %q", path)
+		}
+	}
+
+	return filepath.Join(c.dir, filepath.FromSlash(path)), nil
+}
+
+// secretFile returns the existing file of the secret at path, or "" when there is none
+func (c *Client) secretFile(path string) (string, error) {
+	base, err := c.resolve(path)
+	if err != nil {
+		return "", err
+	}
+	if base == c.dir {
+		return "", nil
+	}
+
+	for _, ext := range extensions {
+		info, err := os.Stat(base + ext)
+		if err == nil && info.Mode().IsRegular() {
+			return base + ext, nil
+		}
+	}
+	return "", nil
+}
+
+// isSecretExtension reports whether ext is the extension of a secret file
+func isSecretExtension(ext string) bool {
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// readFile decodes a secret file according to its extension.
+// JSON numbers are kept as json.Number, as the Vault client returns them.
+func readFile(file string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]interface{})
+	if len(bytes.TrimSpace(content)) == 0 {
+		return data, nil
+	}
+
+	if filepath.Ext(file) == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err := decoder.Decode(&data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// encode encodes secret data for a file with extension ext
+func encode(ext string, data map[string]interface{}) ([]byte, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	if ext == ".json" {
+		content, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(content, '\n'), nil
+	}
+
+	// json.Number is a string type and would be written quoted, so convert the data to plain values first
+	value, err := utils.PlainValue(data)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(value)
+}
+
+// writeFile writes content to file through a temporary file in the same directory, with mode 0600
+func writeFile(file string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".vault-copy-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
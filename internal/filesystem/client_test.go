@@ -0,0 +1,249 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+	"vault-copy/internal/sync"
+	"vault-copy/internal/vault"
+	"vault-copy/mocks"
+)
+
+// writeTestFile writes a file under dir, creating its directories
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	file := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestTree returns a client for a directory holding JSON and YAML secrets
+func newTestTree(t *testing.T) (*Client, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "apps/db.json", `{"password": "s3cr3t", "port": 5432}`)
+	writeTestFile(t, dir, "apps/team/cache.yaml", "url: redis://cache\nttl: 60\n")
+	writeTestFile(t, dir, "apps/team/queue.yml", "url: amqp://queue\n")
+	writeTestFile(t, dir, "apps/README.md", "not a secret")
+	writeTestFile(t, dir, "apps/.git/config", "ignored")
+
+	client, err := NewClient(dir, config.FileFormatJSON)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client, dir
+}
+
+func TestClientRead(t *testing.T) {
+	client, _ := newTestTree(t)
+	log := logger.NewLogger(&config.Config{})
+
+	secret, err := client.ReadSecret("apps/db", log)
+	if err != nil {
+		t.Fatalf("ReadSecret() error = %v", err)
+	}
+	if secret.Data["password"] != "s3cr3t" || secret.Data["port"] != json.Number("5432") {
+		t.Errorf("ReadSecret() = %v", secret.Data)
+	}
+
+	secret, err = client.ReadSecret("apps/team/cache", log)
+	if err != nil || secret.Data["url"] != "redis://cache" || secret.Data["ttl"] != 60 {
+		t.Errorf("ReadSecret(yaml) = %v, %v", secret, err)
+	}
+
+	if _, err := client.ReadSecret("apps/missing", log); err == nil {
+		t.Error("ReadSecret() expected error for a missing secret, got nil")
+	}
+	if _, err := client.ReadSecret("apps/../../etc/passwd", log); err == nil {
+		t.Error("ReadSecret() expected error for a path leaving the root, got nil")
+	}
+
+	items, err := client.ListSecrets("apps", log)
+	if want := []string{"db", "team/"}; err != nil || !reflect.DeepEqual(items, want) {
+		t.Errorf("ListSecrets() = %v, %v, want %v", items, err, want)
+	}
+
+	if isDir, _ := client.IsDirectory("apps/team", log); !isDir {
+		t.Error("IsDirectory(apps/team) = false, want true")
+	}
+	if isDir, _ := client.IsDirectory("apps/db", log); isDir {
+		t.Error("IsDirectory(apps/db) = true, want false")
+	}
+
+	secrets, errs := client.GetAllSecrets(context.Background(), "apps", log)
+	var paths []string
+	for secret := range secrets {
+		paths = append(paths, secret.Path)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("GetAllSecrets() error = %v", err)
+	}
+	if want := []string{"apps/db", "apps/team/cache", "apps/team/queue"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("GetAllSecrets() = %v, want %v", paths, want)
+	}
+
+	matches, err := client.ExpandWildcardPath("apps/team/c*", log)
+	if want := []string{"apps/team/cache"}; err != nil || !reflect.DeepEqual(matches, want) {
+		t.Errorf("ExpandWildcardPath() = %v, %v, want %v", matches, err, want)
+	}
+}
+
+func TestClientReadInvalidFile(t *testing.T) {
+	client, dir := newTestTree(t)
+	writeTestFile(t, dir, "apps/broken.json", `{"password": `)
+	writeTestFile(t, dir, "apps/team/bad.yaml", "url: [unclosed\n")
+
+	secrets, errs := client.GetAllSecrets(context.Background(), "apps", logger.NewLogger(&config.Config{}))
+
+	// Every bad file is reported and the walk goes on with the others
+	var paths, failed []string
+	for secrets != nil || errs != nil {
+		select {
+		case secret, ok := <-secrets:
+			if !ok {
+				secrets = nil
+				continue
+			}
+			paths = append(paths, secret.Path)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			pathErr, isPathErr := err.(*vault.PathError)
+			if !isPathErr {
+				t.Fatalf("GetAllSecrets() error = %v, want a path error", err)
+			}
+			failed = append(failed, pathErr.Path)
+		}
+	}
+	sort.Strings(paths)
+	sort.Strings(failed)
+
+	if want := []string{"apps/db", "apps/team/cache", "apps/team/queue"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("GetAllSecrets() paths = %v, want %v", paths, want)
+	}
+	if want := []string{"apps/broken", "apps/team/bad"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("GetAllSecrets() failed paths = %v, want %v", failed, want)
+	}
+
+	// A wildcard can't be expanded over a tree that can't be read completely
+	if _, err := client.ExpandWildcardPath("apps/*", logger.NewLogger(&config.Config{})); err == nil {
+		t.Error("ExpandWildcardPath() error = nil, want the read error")
+	}
+}
+
+func TestClientWrite(t *testing.T) {
+	dir := t.TempDir()
+	log := logger.NewLogger(&config.Config{})
+
+	client, err := NewClient(filepath.Join(dir, "out"), config.FileFormatYAML)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	data := map[string]interface{}{"password": "s3cr3t", "port": json.Number("5432")}
+	if err := client.WriteSecret("apps/db", data, log); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "out", "apps", "db.yaml"))
+	if err != nil {
+		t.Fatalf("secret file not written: %v", err)
+	}
+	if want := "password: s3cr3t\nport: 5432\n"; string(content) != want {
+		t.Errorf("secret file = %q, want %q", content, want)
+	}
+
+	info, _ := os.Stat(filepath.Join(dir, "out", "apps", "db.yaml"))
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("secret file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// An existing file keeps its format
+	writeTestFile(t, dir, "out/apps/cache.json", `{"url": "old"}`)
+	if err := client.WriteSecret("apps/cache", map[string]interface{}{"url": "new"}, log); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if secret, err := client.ReadSecret("apps/cache", log); err != nil || secret.Data["url"] != "new" {
+		t.Errorf("rewritten secret = %v, %v", secret, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "apps", "cache.yaml")); err == nil {
+		t.Error("a second file was written for an existing secret")
+	}
+
+	if exists, _ := client.SecretExists("apps/db", log); !exists {
+		t.Error("SecretExists() = false after WriteSecret")
+	}
+	if err := client.DeleteSecret("apps/db", false, log); err != nil {
+		t.Fatalf("DeleteSecret() error = %v", err)
+	}
+	if exists, _ := client.SecretExists("apps/db", log); exists {
+		t.Error("SecretExists() = true after DeleteSecret")
+	}
+
+	if err := client.WriteSecret("../escape", data, log); err == nil {
+		t.Error("WriteSecret() expected error for a path leaving the root, got nil")
+	}
+}
+
+func TestSyncBetweenFileAndVault(t *testing.T) {
+	source, _ := newTestTree(t)
+
+	destMock := mocks.NewMockClient()
+	cfg := &config.Config{
+		SourcePath:      "apps",
+		DestinationPath: "secret/data/apps",
+		Recursive:       true,
+		ParallelWorkers: 2,
+	}
+
+	stats, err := sync.NewManager(source, mocks.NewAdapter(destMock), cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if stats.SecretsWritten != 3 || stats.Errors != 0 {
+		t.Errorf("stats = %+v, want 3 written", stats)
+	}
+	if cache := destMock.Secrets["secret/data/apps/team/cache"]; cache == nil || cache.Data["url"] != "redis://cache" {
+		t.Errorf("copied secret = %+v", cache)
+	}
+
+	// And back from Vault to a new directory
+	dest, err := NewClient(filepath.Join(t.TempDir(), "backup"), config.FileFormatJSON)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	destMock.AddDirectory("secret/data/apps", []string{"db", "team/"})
+	destMock.AddDirectory("secret/data/apps/team/", []string{"cache", "queue"})
+
+	cfg = &config.Config{
+		SourcePath:      "secret/data/apps",
+		DestinationPath: "apps",
+		Recursive:       true,
+		ParallelWorkers: 2,
+	}
+	stats, err = sync.NewManager(mocks.NewAdapter(destMock), dest, cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() to files error = %v", err)
+	}
+	if stats.SecretsWritten != 3 || stats.Errors != 0 {
+		t.Errorf("stats = %+v, want 3 written", stats)
+	}
+	if secret, err := dest.ReadSecret("apps/db", logger.NewLogger(cfg)); err != nil || secret.Data["password"] != "s3cr3t" {
+		t.Errorf("written secret = %v, %v", secret, err)
+	}
+}
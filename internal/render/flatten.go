@@ -0,0 +1,295 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+
+	"vault-copy/internal/vault"
+	"vault-copy/pkg/utils"
+)
+
+// Formats of a flattened document
+const (
+	// FormatDotenv writes KEY=value lines, as read by docker compose and dotenv libraries
+	FormatDotenv = "dotenv"
+	// FormatJSON writes one JSON object
+	FormatJSON = "json"
+	// FormatYAML writes one YAML mapping
+	FormatYAML = "yaml"
+	// FormatProperties writes a Java properties file
+	FormatProperties = "properties"
+)
+
+// DefaultKeySeparator joins the path segments and the field of a key
+const DefaultKeySeparator = "_"
+
+// KeyOptions are the rules turning a secret path and field into a document key
+type KeyOptions struct {
+	// PrefixPath prefixes the field with the secret path relative to the root: team/db and password give team_db_password
+	PrefixPath bool
+	// Prefix is prepended to every key as-is, e.g. "APP_"
+	Prefix string
+	// Upper upper-cases the keys
+	Upper bool
+	// Separator joins the path segments and the field, DefaultKeySeparator when empty
+	Separator string
+}
+
+// Variable is one key of a flattened document
+type Variable struct {
+	// Key is the key in the document
+	Key string
+	// Value is the value of the secret field
+	Value interface{}
+	// Path is the path of the secret the value comes from
+	Path string
+	// Field is the secret field the value comes from
+	Field string
+}
+
+// KeyCollisionError is returned by Flatten when fields of different secrets flatten to the same key
+type KeyCollisionError struct {
+	// Collisions maps every colliding key to its sources, as path#field
+	Collisions map[string][]string
+}
+
+// Error lists the colliding keys and their sources
+func (e *KeyCollisionError) Error() string {
+	keys := make([]string, 0, len(e.Collisions))
+	for key := range e.Collisions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s (%s)", key, strings.Join(e.Collisions[key], ", ")))
+	}
+	return fmt.Sprintf("keys collide, use the path prefix or another separator: %s", strings.Join(parts, "; "))
+}
+
+// CheckFormat returns an error unless format is a flattened document format
+func CheckFormat(format string) error {
+	switch format {
+	case FormatDotenv, FormatJSON, FormatYAML, FormatProperties:
+		return nil
+	}
+	return fmt.Errorf("unsupported format %q, use %s, %s, %s or %s", format, FormatDotenv, FormatJSON, FormatYAML, FormatProperties)
+}
+
+// Flatten turns every field of the secrets under rootPath into one variable, sorted by key.
+// For dotenv, characters that are not letters, digits or underscores are replaced with underscores.
+// Fields of different secrets that end up with the same key are reported in a *KeyCollisionError.
+func Flatten(secrets []*vault.Secret, rootPath, format string, opts KeyOptions) ([]Variable, error) {
+	if err := CheckFormat(format); err != nil {
+		return nil, err
+	}
+	if opts.Separator == "" {
+		opts.Separator = DefaultKeySeparator
+	}
+
+	var variables []Variable
+	sources := make(map[string][]string)
+	for _, secret := range secrets {
+		for field, value := range secret.Data {
+			key := flattenKey(relativePath(secret.Path, rootPath), field, format, opts)
+			sources[key] = append(sources[key], secret.Path+"#"+field)
+			variables = append(variables, Variable{Key: key, Value: value, Path: secret.Path, Field: field})
+		}
+	}
+
+	collisions := make(map[string][]string)
+	for key, from := range sources {
+		if len(from) > 1 {
+			sort.Strings(from)
+			collisions[key] = from
+		}
+	}
+	if len(collisions) > 0 {
+		return nil, &KeyCollisionError{Collisions: collisions}
+	}
+
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Key < variables[j].Key
+	})
+	return variables, nil
+}
+
+// flattenKey builds the key of a field of the secret at the relative path
+func flattenKey(relative, field, format string, opts KeyOptions) string {
+	var parts []string
+	if opts.PrefixPath && relative != "" {
+		parts = strings.Split(relative, "/")
+	}
+	parts = append(parts, field)
+
+	key := opts.Prefix + strings.Join(parts, opts.Separator)
+	if opts.Upper {
+		key = strings.ToUpper(key)
+	}
+	if format == FormatDotenv {
+		key = envKey(key)
+	}
+	return key
+}
+
+// envKey replaces the characters that are not valid in an environment variable name
+func envKey(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case r == '_' || (r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))):
+			if i == 0 && unicode.IsDigit(r) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// Write writes the variables as one document in format
+func Write(w io.Writer, format string, variables []Variable) error {
+	switch format {
+	case FormatDotenv:
+		return writeLines(w, variables, func(key, value string) string {
+			return key + "=" + quoteEnv(value)
+		})
+
+	case FormatProperties:
+		return writeLines(w, variables, func(key, value string) string {
+			return escapeProperty(key, true) + "=" + escapeProperty(value, false)
+		})
+
+	case FormatJSON:
+		document := make(map[string]interface{}, len(variables))
+		for _, v := range variables {
+			document[v.Key] = v.Value
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(document)
+
+	case FormatYAML:
+		document := make(map[string]interface{}, len(variables))
+		for _, v := range variables {
+			document[v.Key] = v.Value
+		}
+		plain, err := utils.PlainValue(document)
+		if err != nil {
+			return err
+		}
+		encoder := yaml.NewEncoder(w)
+		if err := encoder.Encode(plain); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	return CheckFormat(format)
+}
+
+// writeLines writes one line per variable, values converted to strings
+func writeLines(w io.Writer, variables []Variable, line func(key, value string) string) error {
+	for _, v := range variables {
+		value, err := stringValue(v.Value)
+		if err != nil {
+			return fmt.Errorf("error encoding %s#%s: %v", v.Path, v.Field, err)
+		}
+		if _, err := io.WriteString(w, line(v.Key, value)+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringValue converts a secret value for a line-based format, nested values are written as JSON
+func stringValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int, int64:
+		return fmt.Sprint(v), nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// quoteEnv returns a dotenv value, double-quoted with escapes unless it only holds safe characters
+func quoteEnv(value string) string {
+	safe := value != ""
+	for _, r := range value {
+		if !(r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))) && !strings.ContainsRune("_-.,/:@%+=", r) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`, "`", "\\`")
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// escapeProperty escapes a properties key or value: backslashes, line breaks and non-ASCII characters,
+// leading spaces of values and all spaces and separators of keys
+func escapeProperty(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == ' ' && (key || i == 0):
+			b.WriteString(`\ `)
+		case key && strings.ContainsRune("=:#!", r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case !key && i == 0 && (r == '#' || r == '!'):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			writeUnicodeEscape(&b, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// writeUnicodeEscape writes r as \uXXXX, as a surrogate pair outside the basic multilingual plane
+func writeUnicodeEscape(b *strings.Builder, r rune) {
+	if r > 0xffff {
+		r -= 0x10000
+		fmt.Fprintf(b, `\u%04x\u%04x`, 0xd800+(r>>10), 0xdc00+(r&0x3ff))
+		return
+	}
+	fmt.Fprintf(b, `\u%04x`, r)
+}
@@ -0,0 +1,190 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"vault-copy/internal/vault"
+)
+
+func testSecrets() []*vault.Secret {
+	return []*vault.Secret{
+		{Path: "secret/data/apps/db", Data: map[string]interface{}{"password": "p@ss word", "port": json.Number("5432")}},
+		{Path: "secret/data/apps/team/cache", Data: map[string]interface{}{"url": "redis://cache:6379", "tls": true}},
+	}
+}
+
+func TestFlattenKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		opts   KeyOptions
+		want   []string
+	}{
+		{
+			name:   "field names",
+			format: FormatJSON,
+			want:   []string{"password", "port", "tls", "url"},
+		},
+		{
+			name:   "path prefix and upper case",
+			format: FormatDotenv,
+			opts:   KeyOptions{PrefixPath: true, Upper: true},
+			want:   []string{"DB_PASSWORD", "DB_PORT", "TEAM_CACHE_TLS", "TEAM_CACHE_URL"},
+		},
+		{
+			name:   "custom separator and prefix",
+			format: FormatProperties,
+			opts:   KeyOptions{PrefixPath: true, Prefix: "app.", Separator: "."},
+			want:   []string{"app.db.password", "app.db.port", "app.team.cache.tls", "app.team.cache.url"},
+		},
+		{
+			name:   "dotenv replaces invalid characters",
+			format: FormatDotenv,
+			opts:   KeyOptions{PrefixPath: true, Separator: "."},
+			want:   []string{"db_password", "db_port", "team_cache_tls", "team_cache_url"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variables, err := Flatten(testSecrets(), "secret/data/apps", tt.format, tt.opts)
+			if err != nil {
+				t.Fatalf("Flatten() error = %v", err)
+			}
+
+			var keys []string
+			for _, v := range variables {
+				keys = append(keys, v.Key)
+			}
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("keys = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlattenCollisions(t *testing.T) {
+	secrets := []*vault.Secret{
+		{Path: "secret/data/apps/db", Data: map[string]interface{}{"password": "a", "user": "admin"}},
+		{Path: "secret/data/apps/cache", Data: map[string]interface{}{"password": "b"}},
+		{Path: "secret/data/apps/queue", Data: map[string]interface{}{"PASSWORD": "c"}},
+	}
+
+	_, err := Flatten(secrets, "secret/data/apps", FormatDotenv, KeyOptions{Upper: true})
+
+	var collision *KeyCollisionError
+	if !errors.As(err, &collision) {
+		t.Fatalf("Flatten() error = %v, want a KeyCollisionError", err)
+	}
+	want := map[string][]string{
+		"PASSWORD": {"secret/data/apps/cache#password", "secret/data/apps/db#password", "secret/data/apps/queue#PASSWORD"},
+	}
+	if !reflect.DeepEqual(collision.Collisions, want) {
+		t.Errorf("Collisions = %v, want %v", collision.Collisions, want)
+	}
+
+	// The path prefix tells them apart
+	if _, err := Flatten(secrets, "secret/data/apps", FormatDotenv, KeyOptions{Upper: true, PrefixPath: true}); err != nil {
+		t.Errorf("Flatten() with path prefix error = %v", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	opts := KeyOptions{PrefixPath: true, Upper: true}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: FormatDotenv,
+			want: `DB_PASSWORD="p@ss word"
+DB_PORT=5432
+TEAM_CACHE_TLS=true
+TEAM_CACHE_URL=redis://cache:6379
+`,
+		},
+		{
+			format: FormatProperties,
+			want: `DB_PASSWORD=p@ss word
+DB_PORT=5432
+TEAM_CACHE_TLS=true
+TEAM_CACHE_URL=redis://cache:6379
+`,
+		},
+		{
+			format: FormatJSON,
+			want: `{
+  "DB_PASSWORD": "p@ss word",
+  "DB_PORT": 5432,
+  "TEAM_CACHE_TLS": true,
+  "TEAM_CACHE_URL": "redis://cache:6379"
+}
+`,
+		},
+		{
+			format: FormatYAML,
+			want: `DB_PASSWORD: p@ss word
+DB_PORT: 5432
+TEAM_CACHE_TLS: true
+TEAM_CACHE_URL: redis://cache:6379
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			variables, err := Flatten(testSecrets(), "secret/data/apps", tt.format, opts)
+			if err != nil {
+				t.Fatalf("Flatten() error = %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.format, variables); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Write() =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteEscaping(t *testing.T) {
+	variables := []Variable{
+		{Key: "MULTILINE", Value: "line1\nline2 \"quoted\" $HOME"},
+		{Key: "NESTED", Value: map[string]interface{}{"a": json.Number("1")}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatDotenv, variables); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := `MULTILINE="line1\nline2 \"quoted\" \$HOME"
+NESTED="{\"a\":1}"
+`
+	if buf.String() != want {
+		t.Errorf("dotenv =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	variables = []Variable{{Key: "my key=1", Value: " café\n"}}
+	if err := Write(&buf, FormatProperties, variables); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if want := `my\ key\=1=\ caf\u00e9\n` + "\n"; buf.String() != want {
+		t.Errorf("properties = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	if err := CheckFormat(FormatDotenv); err != nil {
+		t.Errorf("CheckFormat(dotenv) error = %v", err)
+	}
+	if err := CheckFormat("toml"); err == nil {
+		t.Error("CheckFormat(toml) expected error, got nil")
+	}
+}
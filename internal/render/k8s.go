@@ -0,0 +1,197 @@
+package render
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"vault-copy/internal/vault"
+)
+
+// FormatKubernetes renders every secret as a Kubernetes v1/Secret manifest
+const FormatKubernetes = "k8s"
+
+// Labels and annotations recording where a manifest comes from
+const (
+	// LabelManagedBy marks the manifests rendered by vault-copy
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	// LabelKVVersion and AnnotationKVVersion hold the KV engine version of the source
+	LabelKVVersion      = "vault-copy/kv-version"
+	AnnotationKVVersion = "vault-copy/kv-version"
+	// AnnotationSourcePath holds the Vault path of the secret, which is not a valid label value
+	AnnotationSourcePath = "vault-copy/source-path"
+)
+
+var (
+	// dnsLabel is a Kubernetes namespace name, RFC 1123 label
+	dnsLabel = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	// secretKey is a valid key of the data of a Secret
+	secretKey = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+	// labelValue is a valid label value, the empty value included
+	labelValue = regexp.MustCompile(`^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?$`)
+	// labelName is the name part of a label key
+	labelName = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	// invalidName matches the runs of characters replaced with a dash in a Secret name
+	invalidName = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// ManifestOptions are the settings of the rendered Secret manifests
+type ManifestOptions struct {
+	// Namespace of the Secrets, left out of the manifests when empty
+	Namespace string
+	// NamePrefix is prepended to the name derived from the secret path
+	NamePrefix string
+	// Labels are added to every manifest
+	Labels map[string]string
+	// KVVersion is the KV engine version of the source, left out of the manifests when 0
+	KVVersion int
+}
+
+// Manifest is a Kubernetes v1/Secret
+type Manifest struct {
+	APIVersion string           `yaml:"apiVersion"`
+	Kind       string           `yaml:"kind"`
+	Metadata   ManifestMetadata `yaml:"metadata"`
+	Type       string           `yaml:"type"`
+	// Data holds the base64-encoded field values
+	Data map[string]string `yaml:"data"`
+}
+
+// ManifestMetadata is the object metadata of a Secret manifest
+type ManifestMetadata struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// Manifests renders one Opaque Secret per secret under rootPath, in the order of the secrets.
+// The name is the secret path relative to rootPath, lower-cased, with other characters than letters and digits replaced by dashes,
+// or the last path segment for the secret at rootPath itself.
+// Secrets ending up with the same name and fields that are not valid Secret keys are reported as errors.
+func Manifests(secrets []*vault.Secret, rootPath string, opts ManifestOptions) ([]*Manifest, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	manifests := make([]*Manifest, 0, len(secrets))
+	sources := make(map[string][]string)
+	for _, secret := range secrets {
+		name, err := manifestName(secret.Path, rootPath, opts.NamePrefix)
+		if err != nil {
+			return nil, err
+		}
+		sources[name] = append(sources[name], secret.Path)
+
+		manifest, err := newManifest(secret, name, opts)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	var collisions []string
+	for name, paths := range sources {
+		if len(paths) > 1 {
+			collisions = append(collisions, fmt.Sprintf("%s (%s)", name, strings.Join(paths, ", ")))
+		}
+	}
+	if len(collisions) > 0 {
+		sort.Strings(collisions)
+		return nil, fmt.Errorf("secrets map to the same Secret name, use a more specific path: %s", strings.Join(collisions, "; "))
+	}
+
+	return manifests, nil
+}
+
+// WriteManifests writes the manifests as a multi-document YAML stream
+func WriteManifests(w io.Writer, manifests []*Manifest) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	for _, manifest := range manifests {
+		if err := encoder.Encode(manifest); err != nil {
+			return err
+		}
+	}
+	return encoder.Close()
+}
+
+// newManifest renders the Secret named name holding the fields of secret
+func newManifest(secret *vault.Secret, name string, opts ManifestOptions) (*Manifest, error) {
+	manifest := &Manifest{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata: ManifestMetadata{
+			Name:        name,
+			Namespace:   opts.Namespace,
+			Labels:      map[string]string{LabelManagedBy: "vault-copy"},
+			Annotations: map[string]string{AnnotationSourcePath: secret.Path},
+		},
+		Type: "Opaque",
+		Data: make(map[string]string, len(secret.Data)),
+	}
+	for key, value := range opts.Labels {
+		manifest.Metadata.Labels[key] = value
+	}
+	if opts.KVVersion > 0 {
+		version := strconv.Itoa(opts.KVVersion)
+		manifest.Metadata.Labels[LabelKVVersion] = version
+		manifest.Metadata.Annotations[AnnotationKVVersion] = version
+	}
+
+	for field, value := range secret.Data {
+		if !secretKey.MatchString(field) {
+			return nil, fmt.Errorf("field %q of %s is not a valid Secret key, only letters, digits, '-', '_' and '.' are allowed", field, secret.Path)
+		}
+		s, err := stringValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding %s#%s: %v", secret.Path, field, err)
+		}
+		manifest.Data[field] = base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	return manifest, nil
+}
+
+// manifestName derives a Secret name, an RFC 1123 subdomain, from the path of a secret
+func manifestName(secretPath, rootPath, prefix string) (string, error) {
+	relative := relativePath(secretPath, rootPath)
+	if relative == "" {
+		relative = path.Base(secretPath)
+	}
+
+	name := invalidName.ReplaceAllString(strings.ToLower(prefix+relative), "-")
+	name = strings.Trim(name, "-")
+	if name == "" || len(name) > 253 {
+		return "", fmt.Errorf("can't derive a Secret name from %s", secretPath)
+	}
+	return name, nil
+}
+
+// Validate checks the namespace and the labels against the Kubernetes naming rules
+func (opts ManifestOptions) Validate() error {
+	if opts.Namespace != "" && (len(opts.Namespace) > 63 || !dnsLabel.MatchString(opts.Namespace)) {
+		return fmt.Errorf("invalid namespace %q", opts.Namespace)
+	}
+
+	for key, value := range opts.Labels {
+		name := key
+		if i := strings.LastIndex(key, "/"); i >= 0 {
+			name = key[i+1:]
+		}
+		if len(name) > 63 || !labelName.MatchString(name) {
+			return fmt.Errorf("invalid label key %q", key)
+		}
+		if len(value) > 63 || !labelValue.MatchString(value) {
+			return fmt.Errorf("invalid value %q of label %s", value, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"vault-copy/internal/vault"
+)
+
+func TestManifests(t *testing.T) {
+	manifests, err := Manifests(testSecrets(), "secret/data/apps", ManifestOptions{
+		Namespace:  "payments",
+		NamePrefix: "apps/",
+		Labels:     map[string]string{"team": "payments"},
+		KVVersion:  2,
+	})
+	if err != nil {
+		t.Fatalf("Manifests() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteManifests(&buf, manifests); err != nil {
+		t.Fatalf("WriteManifests() error = %v", err)
+	}
+
+	want := `apiVersion: v1
+kind: Secret
+metadata:
+  name: apps-db
+  namespace: payments
+  labels:
+    app.kubernetes.io/managed-by: vault-copy
+    team: payments
+    vault-copy/kv-version: "2"
+  annotations:
+    vault-copy/kv-version: "2"
+    vault-copy/source-path: secret/data/apps/db
+type: Opaque
+data:
+  password: cEBzcyB3b3Jk
+  port: NTQzMg==
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: apps-team-cache
+  namespace: payments
+  labels:
+    app.kubernetes.io/managed-by: vault-copy
+    team: payments
+    vault-copy/kv-version: "2"
+  annotations:
+    vault-copy/kv-version: "2"
+    vault-copy/source-path: secret/data/apps/team/cache
+type: Opaque
+data:
+  tls: dHJ1ZQ==
+  url: cmVkaXM6Ly9jYWNoZTo2Mzc5
+`
+	if buf.String() != want {
+		t.Errorf("WriteManifests() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestManifestName(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		want   string
+	}{
+		{path: "secret/data/apps/team/Cache_Redis", want: "team-cache-redis"},
+		{path: "secret/data/apps/db.primary", prefix: "prod-", want: "prod-db-primary"},
+		// The secret at the root itself is named after its last segment
+		{path: "secret/data/apps", want: "apps"},
+	}
+
+	for _, tt := range tests {
+		name, err := manifestName(tt.path, "secret/data/apps", tt.prefix)
+		if err != nil {
+			t.Errorf("manifestName(%s) error = %v", tt.path, err)
+			continue
+		}
+		if name != tt.want {
+			t.Errorf("manifestName(%s) = %s, want %s", tt.path, name, tt.want)
+		}
+	}
+}
+
+func TestManifestsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []*vault.Secret
+		opts    ManifestOptions
+	}{
+		{
+			name: "name collision",
+			secrets: []*vault.Secret{
+				{Path: "secret/data/apps/team/db", Data: map[string]interface{}{"a": "1"}},
+				{Path: "secret/data/apps/team-db", Data: map[string]interface{}{"a": "1"}},
+			},
+		},
+		{
+			name:    "invalid key",
+			secrets: []*vault.Secret{{Path: "secret/data/apps/db", Data: map[string]interface{}{"user name": "admin"}}},
+		},
+		{
+			name:    "invalid namespace",
+			secrets: testSecrets(),
+			opts:    ManifestOptions{Namespace: "Payments"},
+		},
+		{
+			name:    "invalid label",
+			secrets: testSecrets(),
+			opts:    ManifestOptions{Labels: map[string]string{"team": "a/b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Manifests(tt.secrets, "secret/data/apps", tt.opts); err == nil {
+				t.Error("Manifests() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestManifestsWithoutKVVersion(t *testing.T) {
+	secrets := []*vault.Secret{{Path: "apps/db", Data: map[string]interface{}{"port": json.Number("5432")}}}
+	manifests, err := Manifests(secrets, "apps", ManifestOptions{})
+	if err != nil {
+		t.Fatalf("Manifests() error = %v", err)
+	}
+
+	metadata := manifests[0].Metadata
+	if metadata.Namespace != "" {
+		t.Errorf("Namespace = %q, want empty", metadata.Namespace)
+	}
+	if _, ok := metadata.Labels[LabelKVVersion]; ok {
+		t.Error("KV version label set without a known KV version")
+	}
+	if metadata.Annotations[AnnotationSourcePath] != "apps/db" {
+		t.Errorf("source path annotation = %q, want apps/db", metadata.Annotations[AnnotationSourcePath])
+	}
+}
@@ -0,0 +1,67 @@
+// Package render turns a subtree of secrets into documents for applications and deployment tools.
+package render
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"vault-copy/internal/logger"
+	"vault-copy/internal/vault"
+)
+
+// ReadSubtree reads every secret under rootPath, or the secret at rootPath itself, sorted by path.
+// Any read error aborts, so a document is never rendered from a partial subtree, and so does an empty subtree.
+func ReadSubtree(ctx context.Context, client vault.ClientInterface, rootPath string, logger *logger.Logger) ([]*vault.Secret, error) {
+	if strings.Contains(rootPath, "*") {
+		return nil, fmt.Errorf("wildcard paths can't be rendered: %s", rootPath)
+	}
+
+	// Stop the walk when giving up on the first error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var secrets []*vault.Secret
+	secretsChan, errChan := client.GetAllSecrets(ctx, rootPath, logger)
+	for secretsChan != nil || errChan != nil {
+		select {
+		case secret, ok := <-secretsChan:
+			if !ok {
+				secretsChan = nil
+				continue
+			}
+			secrets = append(secrets, secret)
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error reading secrets: %v", err)
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("no secrets found under %s", rootPath)
+	}
+
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Path < secrets[j].Path
+	})
+
+	logger.Verbose("Read %d secrets under %s", len(secrets), rootPath)
+	return secrets, nil
+}
+
+// relativePath returns the path of a secret relative to rootPath, "" for the secret at rootPath itself
+func relativePath(path, rootPath string) string {
+	rootPath = strings.TrimSuffix(rootPath, "/")
+	if path == rootPath {
+		return ""
+	}
+	return strings.TrimPrefix(path, rootPath+"/")
+}
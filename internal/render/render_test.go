@@ -0,0 +1,42 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+	"vault-copy/mocks"
+)
+
+func TestReadSubtree(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	sourceMock.AddDirectory("secret/data/apps", []string{"team/", "db"})
+	sourceMock.AddDirectory("secret/data/apps/team/", []string{"cache"})
+	sourceMock.AddSecret("secret/data/apps/db", map[string]interface{}{"password": "s3cr3t"})
+	sourceMock.AddSecret("secret/data/apps/team/cache", map[string]interface{}{"url": "redis://cache"})
+	log := logger.NewLogger(&config.Config{})
+
+	secrets, err := ReadSubtree(context.Background(), mocks.NewAdapter(sourceMock), "secret/data/apps", log)
+	if err != nil {
+		t.Fatalf("ReadSubtree() error = %v", err)
+	}
+	if len(secrets) != 2 || secrets[0].Path != "secret/data/apps/db" || secrets[1].Path != "secret/data/apps/team/cache" {
+		t.Errorf("ReadSubtree() returned unexpected secrets: %v", secrets)
+	}
+
+	sourceMock.AddDirectory("secret/data/apps", []string{"team/", "db", "broken"})
+	sourceMock.SetReadError("secret/data/apps/broken", errors.New("permission denied"))
+	if _, err := ReadSubtree(context.Background(), mocks.NewAdapter(sourceMock), "secret/data/apps", log); err == nil {
+		t.Error("ReadSubtree() with a read error expected error, got nil")
+	}
+
+	if _, err := ReadSubtree(context.Background(), mocks.NewAdapter(sourceMock), "secret/data/missing", log); err == nil {
+		t.Error("ReadSubtree() of an empty subtree expected error, got nil")
+	}
+
+	if _, err := ReadSubtree(context.Background(), mocks.NewAdapter(sourceMock), "secret/data/*", log); err == nil {
+		t.Error("ReadSubtree() of a wildcard expected error, got nil")
+	}
+}
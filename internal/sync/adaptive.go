@@ -0,0 +1,140 @@
+package sync
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"vault-copy/internal/logger"
+)
+
+const (
+	// latencySpikeFactor is how many times slower than the baseline a request must be to count as a spike
+	latencySpikeFactor = 3
+	// baselineSamples is the number of healthy requests observed before latency spikes are detected
+	baselineSamples = 10
+)
+
+// adaptiveLimiter bounds the number of workers processing a secret at the same time.
+// It halves the limit when Vault answers 429 or the request latency spikes
+// and raises it by one after a full round of healthy requests, up to the configured workers.
+type adaptiveLimiter struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// limit is the current number of workers allowed to process a secret
+	limit int
+	// max is the configured number of workers
+	max int
+	// active is the number of workers currently processing a secret
+	active int
+	// healthy counts the healthy requests since the limit last changed
+	healthy int
+	// cooldown is the number of requests still ignored after lowering the limit,
+	// they were sent before the change and must not lower it again
+	cooldown int
+	// baseline is the moving average latency of healthy requests
+	baseline time.Duration
+	// samples is the number of requests averaged into baseline
+	samples int
+
+	logger *logger.Logger
+}
+
+// newAdaptiveLimiter creates a limiter that starts with all max workers allowed
+func newAdaptiveLimiter(max int, logger *logger.Logger) *adaptiveLimiter {
+	l := &adaptiveLimiter{limit: max, max: max, logger: logger}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until the worker may process a secret. It does nothing on a nil limiter.
+func (l *adaptiveLimiter) acquire() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+}
+
+// release marks the worker as done with its secret. It does nothing on a nil limiter.
+func (l *adaptiveLimiter) release() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	l.cond.Signal()
+}
+
+// ObserveRequest adjusts the limit to the outcome of a request, it implements vault.RequestObserver
+func (l *adaptiveLimiter) ObserveRequest(latency time.Duration, status int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cooldown > 0 {
+		l.cooldown--
+		return
+	}
+
+	throttled := status == http.StatusTooManyRequests
+	spike := l.samples >= baselineSamples && latency > latencySpikeFactor*l.baseline
+
+	if throttled || spike {
+		l.decrease(throttled, latency)
+		return
+	}
+
+	l.updateBaseline(latency)
+
+	l.healthy++
+	if l.healthy >= l.limit && l.limit < l.max {
+		l.limit++
+		l.healthy = 0
+		l.logger.Verbose("Adaptive concurrency: requests are healthy again, raising workers to %d", l.limit)
+		l.cond.Broadcast()
+	}
+}
+
+// decrease halves the limit, keeping at least one worker. It must be called with mu held.
+func (l *adaptiveLimiter) decrease(throttled bool, latency time.Duration) {
+	l.healthy = 0
+	// The other active workers may each have a request in flight that was sent before the change
+	l.cooldown = max(0, l.active-1)
+	if l.limit == 1 {
+		return
+	}
+
+	l.limit = max(1, l.limit/2)
+	if throttled {
+		l.logger.Info("Adaptive concurrency: Vault is throttling requests (429), lowering workers to %d", l.limit)
+	} else {
+		l.logger.Info("Adaptive concurrency: request latency spiked to %v (baseline %v), lowering workers to %d",
+			latency, l.baseline, l.limit)
+	}
+}
+
+// updateBaseline folds the latency of a healthy request into the moving average.
+// It must be called with mu held.
+func (l *adaptiveLimiter) updateBaseline(latency time.Duration) {
+	l.samples++
+	if l.samples == 1 {
+		l.baseline = latency
+		return
+	}
+	l.baseline = (l.baseline*9 + latency) / 10
+}
+
+// currentLimit returns the number of workers currently allowed to process a secret
+func (l *adaptiveLimiter) currentLimit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
@@ -0,0 +1,117 @@
+package sync
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+)
+
+func TestAdaptiveLimiterThrottling(t *testing.T) {
+	limiter := newAdaptiveLimiter(8, logger.NewLogger(&config.Config{}))
+
+	for _, want := range []int{4, 2, 1, 1} {
+		limiter.ObserveRequest(time.Millisecond, http.StatusTooManyRequests)
+		if got := limiter.currentLimit(); got != want {
+			t.Fatalf("limit after 429 = %d, want %d", got, want)
+		}
+	}
+
+	// One round of healthy requests at the current limit raises it by one
+	for _, want := range []int{2, 3, 4} {
+		for i := 0; i < want-1; i++ {
+			limiter.ObserveRequest(time.Millisecond, http.StatusOK)
+		}
+		if got := limiter.currentLimit(); got != want {
+			t.Fatalf("limit after healthy requests = %d, want %d", got, want)
+		}
+	}
+}
+
+func TestAdaptiveLimiterNeverExceedsMax(t *testing.T) {
+	limiter := newAdaptiveLimiter(2, logger.NewLogger(&config.Config{}))
+
+	for i := 0; i < 20; i++ {
+		limiter.ObserveRequest(time.Millisecond, http.StatusOK)
+	}
+
+	if got := limiter.currentLimit(); got != 2 {
+		t.Errorf("limit = %d, want 2", got)
+	}
+}
+
+func TestAdaptiveLimiterLatencySpike(t *testing.T) {
+	limiter := newAdaptiveLimiter(4, logger.NewLogger(&config.Config{}))
+
+	for i := 0; i < baselineSamples; i++ {
+		limiter.ObserveRequest(10*time.Millisecond, http.StatusOK)
+	}
+	if got := limiter.currentLimit(); got != 4 {
+		t.Fatalf("limit before spike = %d, want 4", got)
+	}
+
+	limiter.ObserveRequest(25*time.Millisecond, http.StatusOK)
+	if got := limiter.currentLimit(); got != 4 {
+		t.Errorf("limit after a slower request = %d, want 4", got)
+	}
+
+	limiter.ObserveRequest(100*time.Millisecond, http.StatusOK)
+	if got := limiter.currentLimit(); got != 2 {
+		t.Errorf("limit after a latency spike = %d, want 2", got)
+	}
+}
+
+func TestAdaptiveLimiterCooldown(t *testing.T) {
+	limiter := newAdaptiveLimiter(8, logger.NewLogger(&config.Config{}))
+	limiter.acquire()
+	limiter.acquire()
+	limiter.acquire()
+
+	// The requests of the other two active workers were sent before the limit was lowered
+	limiter.ObserveRequest(time.Millisecond, http.StatusTooManyRequests)
+	limiter.ObserveRequest(time.Millisecond, http.StatusTooManyRequests)
+	limiter.ObserveRequest(time.Millisecond, http.StatusTooManyRequests)
+	if got := limiter.currentLimit(); got != 4 {
+		t.Errorf("limit = %d, want 4", got)
+	}
+
+	limiter.ObserveRequest(time.Millisecond, http.StatusTooManyRequests)
+	if got := limiter.currentLimit(); got != 2 {
+		t.Errorf("limit after cooldown = %d, want 2", got)
+	}
+}
+
+func TestAdaptiveLimiterBlocksAboveLimit(t *testing.T) {
+	limiter := newAdaptiveLimiter(2, logger.NewLogger(&config.Config{}))
+	limiter.ObserveRequest(time.Millisecond, http.StatusTooManyRequests)
+
+	limiter.acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		limiter.acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquire() returned while the limit was reached")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	limiter.release()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("acquire() still blocked after release()")
+	}
+}
+
+func TestAdaptiveLimiterNil(t *testing.T) {
+	var limiter *adaptiveLimiter
+	limiter.acquire()
+	limiter.release()
+}
@@ -0,0 +1,275 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"vault-copy/internal/vault"
+	"vault-copy/pkg/utils"
+)
+
+// Kinds of key-level differences
+const (
+	// KeyAdded means the key exists only in the source secret
+	KeyAdded = "added"
+	// KeyRemoved means the key exists only in the destination secret
+	KeyRemoved = "removed"
+	// KeyChanged means the key exists in both secrets with different values
+	KeyChanged = "changed"
+)
+
+// maskedValue replaces secret values in diff output unless values are shown
+const maskedValue = "***"
+
+// DiffResult holds the differences between a source and a destination subtree.
+// All paths are destination paths, source secrets are mapped with TransformPath.
+type DiffResult struct {
+	// OnlyInSource lists secrets missing from the destination
+	OnlyInSource []string
+	// OnlyInDestination lists destination secrets without a source counterpart
+	OnlyInDestination []string
+	// Changed lists secrets present on both sides with different data
+	Changed []*SecretDiff
+	// Identical is the number of secrets with the same data on both sides
+	Identical int
+}
+
+// SecretDiff holds the key-level differences of one secret.
+type SecretDiff struct {
+	// SourcePath is the path of the secret in the source
+	SourcePath string
+	// DestPath is the path of the secret in the destination
+	DestPath string
+	// Keys lists the differing keys, sorted by name
+	Keys []*KeyDiff
+}
+
+// KeyDiff is a difference in a single key of a secret.
+type KeyDiff struct {
+	// Key is the name of the key
+	Key string
+	// Change is KeyAdded, KeyRemoved or KeyChanged
+	Change string
+	// SourceValue is the value in the source, nil for removed keys
+	SourceValue interface{}
+	// DestValue is the value in the destination, nil for added keys
+	DestValue interface{}
+}
+
+// HasDifferences reports whether the subtrees differ
+func (r *DiffResult) HasDifferences() bool {
+	return len(r.OnlyInSource) > 0 || len(r.OnlyInDestination) > 0 || len(r.Changed) > 0
+}
+
+// Diff compares the source path with the destination path without writing anything.
+// Source secrets are mapped to destination paths the same way Sync maps them.
+func (m *SyncManager) Diff(ctx context.Context) (*DiffResult, error) {
+	m.logger.Info("Comparing %s with %s", m.config.SourcePath, m.config.DestinationPath)
+
+	sourcePaths := []string{m.config.SourcePath}
+	if strings.Contains(m.config.SourcePath, "*") {
+		expandedPaths, err := m.sourceClient.ExpandWildcardPath(m.config.SourcePath, m.logger)
+		if err != nil {
+			return nil, fmt.Errorf("error expanding wildcard path: %v", err)
+		}
+		sourcePaths = expandedPaths
+	}
+
+	sourceSecrets := make(map[string]*vault.Secret)
+	for _, path := range sourcePaths {
+		secrets, err := m.collectSecrets(ctx, m.sourceClient, path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading source secrets from %s: %v", path, err)
+		}
+		for _, secret := range secrets {
+			sourceSecrets[strings.Trim(m.TransformPath(secret.Path, m.config.DestinationPath), "/")] = secret
+		}
+	}
+
+	destSecrets, err := m.collectSecrets(ctx, m.destClient, m.config.DestinationPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading destination secrets from %s: %v", m.config.DestinationPath, err)
+	}
+
+	result := &DiffResult{}
+	destByPath := make(map[string]*vault.Secret, len(destSecrets))
+	for _, secret := range destSecrets {
+		destPath := strings.Trim(secret.Path, "/")
+		destByPath[destPath] = secret
+		if _, ok := sourceSecrets[destPath]; !ok {
+			result.OnlyInDestination = append(result.OnlyInDestination, destPath)
+		}
+	}
+
+	for destPath, sourceSecret := range sourceSecrets {
+		destSecret, ok := destByPath[destPath]
+		if !ok {
+			result.OnlyInSource = append(result.OnlyInSource, destPath)
+			continue
+		}
+
+		keys := diffKeys(sourceSecret.Data, destSecret.Data)
+		if len(keys) == 0 {
+			result.Identical++
+			continue
+		}
+
+		result.Changed = append(result.Changed, &SecretDiff{
+			SourcePath: sourceSecret.Path,
+			DestPath:   destPath,
+			Keys:       keys,
+		})
+	}
+
+	sort.Strings(result.OnlyInSource)
+	sort.Strings(result.OnlyInDestination)
+	sort.Slice(result.Changed, func(i, j int) bool {
+		return result.Changed[i].DestPath < result.Changed[j].DestPath
+	})
+
+	return result, nil
+}
+
+// collectSecrets reads all secrets under path. A path that is neither a directory
+// nor an existing secret, or a secret whose latest version is deleted, yields no secrets.
+func (m *SyncManager) collectSecrets(ctx context.Context, client vault.ClientInterface, path string) ([]*vault.Secret, error) {
+	isDir, err := client.IsDirectory(path, m.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isDir {
+		exists, err := client.SecretExists(path, m.logger)
+		if err != nil || !exists {
+			return nil, err
+		}
+
+		secret, err := client.ReadSecret(path, m.logger)
+		if errors.Is(err, vault.ErrSecretDeleted) {
+			// Walks skip soft-deleted secrets, so a single one compares as missing too
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []*vault.Secret{secret}, nil
+	}
+
+	// Stop the walk when giving up on the first error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var secrets []*vault.Secret
+	secretsChan, errChan := client.GetAllSecrets(ctx, path, m.logger)
+
+	for secretsChan != nil || errChan != nil {
+		select {
+		case secret, ok := <-secretsChan:
+			if !ok {
+				secretsChan = nil
+				continue
+			}
+			secrets = append(secrets, secret)
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return secrets, nil
+}
+
+// diffKeys returns the key-level differences between source and destination data, sorted by key
+func diffKeys(source, dest map[string]interface{}) []*KeyDiff {
+	var keys []*KeyDiff
+
+	for key, sourceValue := range source {
+		destValue, ok := dest[key]
+		switch {
+		case !ok:
+			keys = append(keys, &KeyDiff{Key: key, Change: KeyAdded, SourceValue: sourceValue})
+		case !utils.EqualValue(sourceValue, destValue):
+			keys = append(keys, &KeyDiff{Key: key, Change: KeyChanged, SourceValue: sourceValue, DestValue: destValue})
+		}
+	}
+
+	for key, destValue := range dest {
+		if _, ok := source[key]; !ok {
+			keys = append(keys, &KeyDiff{Key: key, Change: KeyRemoved, DestValue: destValue})
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Key < keys[j].Key
+	})
+	return keys
+}
+
+// Print writes a human-readable report of the differences to w.
+// Values are masked unless showValues is set.
+func (r *DiffResult) Print(w io.Writer, showValues bool) {
+	if len(r.OnlyInSource) > 0 {
+		fmt.Fprintf(w, "Only in source:\n")
+		for _, path := range r.OnlyInSource {
+			fmt.Fprintf(w, "  + %s\n", path)
+		}
+	}
+
+	if len(r.OnlyInDestination) > 0 {
+		fmt.Fprintf(w, "Only in destination:\n")
+		for _, path := range r.OnlyInDestination {
+			fmt.Fprintf(w, "  - %s\n", path)
+		}
+	}
+
+	if len(r.Changed) > 0 {
+		fmt.Fprintf(w, "Different:\n")
+		for _, secret := range r.Changed {
+			fmt.Fprintf(w, "  ~ %s (source: %s)\n", secret.DestPath, secret.SourcePath)
+			for _, key := range secret.Keys {
+				switch key.Change {
+				case KeyAdded:
+					fmt.Fprintf(w, "      + %s: %s\n", key.Key, formatValue(key.SourceValue, showValues))
+				case KeyRemoved:
+					fmt.Fprintf(w, "      - %s: %s\n", key.Key, formatValue(key.DestValue, showValues))
+				default:
+					fmt.Fprintf(w, "      ~ %s: %s -> %s\n", key.Key,
+						formatValue(key.DestValue, showValues), formatValue(key.SourceValue, showValues))
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\nComparison summary:\n")
+	fmt.Fprintf(w, "  Only in source: %d\n", len(r.OnlyInSource))
+	fmt.Fprintf(w, "  Only in destination: %d\n", len(r.OnlyInDestination))
+	fmt.Fprintf(w, "  Different: %d\n", len(r.Changed))
+	fmt.Fprintf(w, "  Identical: %d\n", r.Identical)
+}
+
+// formatValue renders a value for the diff report, masked unless showValues is set
+func formatValue(value interface{}, showValues bool) string {
+	if !showValues {
+		return maskedValue
+	}
+
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(raw)
+}
@@ -0,0 +1,121 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"vault-copy/internal/config"
+	"vault-copy/mocks"
+)
+
+func newDiffManager(t *testing.T) *SyncManager {
+	t.Helper()
+
+	sourceMock := mocks.NewMockClient()
+	sourceMock.AddDirectory("secret/data/source/apps", []string{"same", "changed", "new"})
+	sourceMock.AddSecret("secret/data/source/apps/same", map[string]interface{}{"port": 5432})
+	sourceMock.AddSecret("secret/data/source/apps/changed", map[string]interface{}{
+		"password": "new-password",
+		"added":    "a",
+		"kept":     "k",
+	})
+	sourceMock.AddSecret("secret/data/source/apps/new", map[string]interface{}{"key": "value"})
+
+	destMock := mocks.NewMockClient()
+	destMock.AddDirectory("secret/data/dest/apps", []string{"same", "changed", "old"})
+	destMock.AddSecret("secret/data/dest/apps/same", map[string]interface{}{"port": float64(5432)})
+	destMock.AddSecret("secret/data/dest/apps/changed", map[string]interface{}{
+		"password": "old-password",
+		"removed":  "r",
+		"kept":     "k",
+	})
+	destMock.AddSecret("secret/data/dest/apps/old", map[string]interface{}{"key": "stale"})
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/source/apps",
+		DestinationPath: "secret/data/dest/apps",
+		ParallelWorkers: 1,
+	}
+
+	return NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+}
+
+func TestDiff(t *testing.T) {
+	result, err := newDiffManager(t).Diff(context.Background())
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	if !result.HasDifferences() {
+		t.Error("HasDifferences() = false, want true")
+	}
+
+	if len(result.OnlyInSource) != 1 || result.OnlyInSource[0] != "secret/data/dest/apps/new" {
+		t.Errorf("OnlyInSource = %v, want [secret/data/dest/apps/new]", result.OnlyInSource)
+	}
+
+	if len(result.OnlyInDestination) != 1 || result.OnlyInDestination[0] != "secret/data/dest/apps/old" {
+		t.Errorf("OnlyInDestination = %v, want [secret/data/dest/apps/old]", result.OnlyInDestination)
+	}
+
+	if result.Identical != 1 {
+		t.Errorf("Identical = %d, want 1", result.Identical)
+	}
+
+	if len(result.Changed) != 1 {
+		t.Fatalf("Changed = %d secrets, want 1", len(result.Changed))
+	}
+
+	var changes []string
+	for _, key := range result.Changed[0].Keys {
+		changes = append(changes, key.Key+":"+key.Change)
+	}
+	want := "added:added password:changed removed:removed"
+	if got := strings.Join(changes, " "); got != want {
+		t.Errorf("key changes = %q, want %q", got, want)
+	}
+}
+
+func TestDiffPrintMasksValues(t *testing.T) {
+	result, err := newDiffManager(t).Diff(context.Background())
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	var masked bytes.Buffer
+	result.Print(&masked, false)
+	if strings.Contains(masked.String(), "new-password") || !strings.Contains(masked.String(), "~ password: *** -> ***") {
+		t.Errorf("masked report leaks or misses values:\n%s", masked.String())
+	}
+
+	var shown bytes.Buffer
+	result.Print(&shown, true)
+	if !strings.Contains(shown.String(), "~ password: old-password -> new-password") {
+		t.Errorf("report with values misses the change:\n%s", shown.String())
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	sourceMock.AddSecret("secret/data/source/app", map[string]interface{}{"key": "value"})
+
+	destMock := mocks.NewMockClient()
+	destMock.AddSecret("secret/data/dest/app", map[string]interface{}{"key": "value"})
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/source/app",
+		DestinationPath: "secret/data/dest/app",
+		ParallelWorkers: 1,
+	}
+
+	result, err := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg).Diff(context.Background())
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	if result.HasDifferences() || result.Identical != 1 {
+		t.Errorf("Diff() = %+v, want one identical secret", result)
+	}
+}
@@ -2,28 +2,49 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"vault-copy/internal/config"
 	"vault-copy/internal/logger"
 	"vault-copy/internal/vault"
+	"vault-copy/pkg/utils"
 )
 
 // SyncStats holds statistics about the synchronization process.
 type SyncStats struct {
 	// SecretsRead is the number of secrets read from the source
-	SecretsRead int64
+	SecretsRead int64 `json:"secrets_read"`
 	// SecretsWritten is the number of secrets written to the destination
-	SecretsWritten int64
+	SecretsWritten int64 `json:"secrets_written"`
 	// SecretsSkipped is the number of secrets skipped (already existed)
-	SecretsSkipped int64
+	SecretsSkipped int64 `json:"secrets_skipped"`
+	// SecretsUnchanged is the number of secrets not rewritten because the destination already had the same data
+	SecretsUnchanged int64 `json:"secrets_unchanged"`
 	// Errors is the number of errors encountered during synchronization
-	Errors int64
+	Errors int64 `json:"errors"`
+	// VersionsWritten is the number of KV v2 versions replayed in all-versions mode
+	VersionsWritten int64 `json:"versions_written"`
+	// SecretsDeleted is the number of extraneous destination secrets deleted in mirror mode
+	SecretsDeleted int64 `json:"secrets_deleted"`
+	// SecretsResumed is the number of secrets skipped because a previous run completed them
+	SecretsResumed int64 `json:"secrets_resumed"`
+	// SecretsSourceDeleted is the number of source secrets skipped because their latest KV v2 version is deleted
+	SecretsSourceDeleted int64 `json:"secrets_source_deleted"`
+	// Retries is the number of requests to either Vault retried after a transient error
+	Retries int64 `json:"retries"`
+	// Results holds the outcome of every processed secret in the order they completed
+	Results []SecretResult `json:"-"`
 }
 
+// ErrInterrupted is returned by Sync when its context is cancelled before the run completes,
+// for example on SIGINT. The statistics returned with it cover the work done until then.
+var ErrInterrupted = errors.New("synchronization interrupted")
+
 // SyncManager handles the synchronization of secrets between Vault instances.
 type SyncManager struct {
 	// sourceClient is the client for the source Vault instance
@@ -34,6 +55,24 @@ type SyncManager struct {
 	config *config.Config
 	// logger is the logger instance for the manager
 	logger *logger.Logger
+	// copied holds the destination paths of all source secrets seen during the current run
+	copied *pathSet
+	// checkpoint records completed destination paths when a state file is configured
+	checkpoint *checkpoint
+	// limiter lowers the number of active workers while Vault throttles, nil unless adaptive concurrency is enabled
+	limiter *adaptiveLimiter
+	// stats holds the statistics of the current run, read by Progress while the run is going on
+	stats atomic.Pointer[SyncStats]
+	// deletedReads counts the soft-deleted secrets skipped when read directly rather than by a walk
+	deletedReads atomic.Int64
+	// stoppedEarly is set when the run leaves secrets unread or unwritten because its context is cancelled
+	stoppedEarly atomic.Bool
+	// resultHandler receives the result of every processed secret, see OnResult
+	resultHandler func(result SecretResult)
+	// results collects the result of every processed secret of the current run
+	results []SecretResult
+	// resultsMu guards resultHandler and results and serializes the handler calls
+	resultsMu sync.Mutex
 }
 
 // NewManager creates a new SyncManager instance with the provided clients and configuration.
@@ -48,8 +87,133 @@ func NewManager(sourceClient, destClient vault.ClientInterface, cfg *config.Conf
 
 // Sync synchronizes secrets from the source to the destination according to the configuration.
 // It returns statistics about the synchronization process and any errors encountered.
-func (m *SyncManager) Sync(ctx context.Context) (*SyncStats, error) {
+// Client tokens are kept alive for the whole run; if one of them can't be renewed
+// the run is cancelled and the renewal error is returned.
+// When ctx is cancelled no new secrets are started, the ones being written are finished
+// and, if work was left undone, ErrInterrupted is returned with the statistics gathered so far.
+func (m *SyncManager) Sync(parent context.Context) (*SyncStats, error) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	renewal := m.startTokenRenewal(ctx, cancel)
+
+	stats, err := m.run(ctx)
+	if stats != nil {
+		stats.Retries = m.retries()
+		stats.SecretsSourceDeleted = m.sourceDeleted()
+		stats.Results = m.collectedResults()
+	}
+
+	cancel()
+	renewErr := renewal.wait()
+
+	// A signal arriving once the work is done doesn't turn a complete run into an interrupted one
+	if parent.Err() != nil && (m.stoppedEarly.Load() || errors.Is(err, parent.Err())) {
+		return m.Progress(), ErrInterrupted
+	}
+
+	if renewErr != nil {
+		return stats, renewErr
+	}
+
+	return stats, err
+}
+
+// Progress returns a snapshot of the statistics of the current or last run.
+// It may be called while Sync is running and returns nil before the first run starts.
+func (m *SyncManager) Progress() *SyncStats {
+	stats := m.stats.Load()
+	if stats == nil {
+		return nil
+	}
+
+	return &SyncStats{
+		SecretsRead:          atomic.LoadInt64(&stats.SecretsRead),
+		SecretsWritten:       atomic.LoadInt64(&stats.SecretsWritten),
+		SecretsSkipped:       atomic.LoadInt64(&stats.SecretsSkipped),
+		SecretsUnchanged:     atomic.LoadInt64(&stats.SecretsUnchanged),
+		Errors:               atomic.LoadInt64(&stats.Errors),
+		VersionsWritten:      atomic.LoadInt64(&stats.VersionsWritten),
+		SecretsDeleted:       atomic.LoadInt64(&stats.SecretsDeleted),
+		SecretsResumed:       atomic.LoadInt64(&stats.SecretsResumed),
+		SecretsSourceDeleted: m.sourceDeleted(),
+		Retries:              m.retries(),
+		Results:              m.collectedResults(),
+	}
+}
+
+// sourceDeleted returns the number of source secrets skipped because their latest version is deleted,
+// by the walks of the source client and by direct reads of the current run
+func (m *SyncManager) sourceDeleted() int64 {
+	total := m.deletedReads.Load()
+	if counter, ok := m.sourceClient.(vault.DeletedCounter); ok {
+		total += counter.DeletedSkipped()
+	}
+	return total
+}
+
+// deletedSecret handles a secret read directly whose latest version is deleted.
+// In all-versions mode it returns the secret so that its history is replayed,
+// otherwise the secret is recorded as skipped and nil is returned.
+func (m *SyncManager) deletedSecret(path string, start time.Time) *vault.Secret {
+	if m.config.AllVersions {
+		m.logger.Verbose("Latest version of %s is deleted, replaying its history", path)
+		return &vault.Secret{Path: path, Deleted: true}
+	}
+
+	m.logger.Info("Skipping secret whose latest version is deleted: %s", path)
+	m.deletedReads.Add(1)
+	m.record(path, "", ActionSkipped, nil, start)
+	return nil
+}
+
+// retries returns the number of requests retried by the source and destination clients
+func (m *SyncManager) retries() int64 {
+	clients := []vault.ClientInterface{m.sourceClient}
+	if m.destClient != m.sourceClient {
+		clients = append(clients, m.destClient)
+	}
+
+	var total int64
+	for _, client := range clients {
+		if counter, ok := client.(vault.RetryCounter); ok {
+			total += counter.Retries()
+		}
+	}
+	return total
+}
+
+// run performs the synchronization described by the configuration.
+func (m *SyncManager) run(ctx context.Context) (*SyncStats, error) {
 	stats := &SyncStats{}
+	m.stats.Store(stats)
+	m.copied = newPathSet()
+	m.deletedReads.Store(0)
+	m.stoppedEarly.Store(false)
+	m.resetResults()
+
+	if m.config.StateFile != "" {
+		checkpoint, err := openCheckpoint(m.config.StateFile, newStateKey(m.config), m.config.Resume, m.config.DryRun)
+		if err != nil {
+			return nil, err
+		}
+		defer checkpoint.Close()
+		m.checkpoint = checkpoint
+	}
+
+	if m.config.AdaptiveConcurrency {
+		m.limiter = newAdaptiveLimiter(m.config.ParallelWorkers, m.logger)
+		for _, client := range []vault.ClientInterface{m.sourceClient, m.destClient} {
+			if reporter, ok := client.(vault.RequestReporter); ok {
+				reporter.SetRequestObserver(m.limiter)
+			}
+		}
+	}
+
+	// In all-versions mode a secret whose latest version is deleted still has a history to replay
+	if sender, ok := m.sourceClient.(vault.DeletedSender); ok {
+		sender.SendDeletedSecrets(m.config.AllVersions)
+	}
 
 	m.logger.Info("Starting synchronization from %s to %s",
 		m.config.SourcePath, m.config.DestinationPath)
@@ -65,13 +229,47 @@ func (m *SyncManager) Sync(ctx context.Context) (*SyncStats, error) {
 	m.logger.Verbose("  Recursive: %t", m.config.Recursive)
 	m.logger.Verbose("  Dry-run: %t", m.config.DryRun)
 	m.logger.Verbose("  Overwrite: %t", m.config.Overwrite)
-	m.logger.Verbose("  Parallel workers: %d", m.config.ParallelWorkers)
+	m.logger.Verbose("  Parallel workers: %d (adaptive: %t)", m.config.ParallelWorkers, m.config.AdaptiveConcurrency)
+	m.logger.Verbose("  Read workers: %d", m.config.ReadWorkers)
+	if rate := m.config.SourceRequestRate(); rate > 0 {
+		m.logger.Verbose("  Source rate limit: %g requests/s", rate)
+	}
+	if rate := m.config.DestRequestRate(); rate > 0 {
+		m.logger.Verbose("  Destination rate limit: %g requests/s", rate)
+	}
+	m.logger.Verbose("  All versions: %t", m.config.AllVersions)
+	m.logger.Verbose("  Copy metadata: %t", m.config.CopyMetadata)
+	if m.config.StateFile != "" {
+		m.logger.Verbose("  State file: %s (resume: %t)", m.config.StateFile, m.config.Resume)
+	}
+	m.logger.Verbose("  Delete extraneous: %t", m.config.DeleteExtraneous)
+	if m.config.DeleteExtraneous {
+		m.logger.Verbose("  Delete mode: %s", m.config.DeleteMode)
+		m.logger.Verbose("  Max deletes: %d", m.config.MaxDeletes)
+	}
 	m.logger.Verbose("  Source Vault: %s", m.config.SourceAddr)
 	m.logger.Verbose("  Destination Vault: %s", m.config.DestAddr)
+	if m.config.SourceNamespace != "" {
+		m.logger.Verbose("  Source namespace: %s", m.config.SourceNamespace)
+	}
+	if m.config.DestNamespace != "" {
+		m.logger.Verbose("  Destination namespace: %s", m.config.DestNamespace)
+	}
+	m.logKVVersion("Source", m.sourceClient, m.config.SourcePath)
+	m.logKVVersion("Destination", m.destClient, m.config.DestinationPath)
+
+	// Copy only the listed paths, for example the failed paths of a previous run
+	if len(m.config.Paths) > 0 {
+		m.logger.Info("Copying %d paths from the paths file", len(m.config.Paths))
+		return m.syncMultiplePaths(ctx, stats, m.config.Paths)
+	}
 
 	// Check if source path contains wildcard
 	if strings.Contains(m.config.SourcePath, "*") {
 		m.logger.Verbose("Source path contains wildcard: %s", m.config.SourcePath)
+		if m.config.DeleteExtraneous {
+			return nil, fmt.Errorf("--delete-extraneous can't be used with wildcard source paths")
+		}
 		// Expand wildcard paths
 		expandedPaths, err := m.sourceClient.ExpandWildcardPath(m.config.SourcePath, m.logger)
 		if err != nil {
@@ -100,62 +298,116 @@ func (m *SyncManager) Sync(ctx context.Context) (*SyncStats, error) {
 	}
 
 	if !isDir {
+		if m.config.DeleteExtraneous {
+			m.logger.Info("--delete-extraneous has no effect when copying a single secret")
+		}
 		// Copy single secret
 		return m.syncSingleSecret(ctx, stats)
 	}
 
 	// Copy directory
-	return m.syncDirectory(ctx, stats)
+	stats, err = m.syncDirectory(ctx, stats)
+	if err != nil || !m.config.DeleteExtraneous {
+		return stats, err
+	}
+
+	return stats, m.deleteExtraneous(ctx, stats)
+}
+
+// logKVVersion logs the detected KV version of the mount a path belongs to.
+// Reads and writes convert between KV v1 and v2 based on these versions.
+func (m *SyncManager) logKVVersion(side string, client vault.ClientInterface, path string) {
+	version, err := client.GetKVEngineVersion(path, m.logger)
+	if err != nil {
+		m.logger.Verbose("  %s KV version: unknown (%v)", side, err)
+		return
+	}
+	m.logger.Verbose("  %s KV version: %d", side, version)
 }
 
 // syncSingleSecret synchronizes a single secret from the source to the destination.
 func (m *SyncManager) syncSingleSecret(ctx context.Context, stats *SyncStats) (*SyncStats, error) {
 	m.logger.Info("Reading secret: %s", m.config.SourcePath)
 	m.logger.Verbose("Connecting to source Vault: %s", m.config.SourceAddr)
+	start := time.Now()
 
 	secret, err := m.sourceClient.ReadSecret(m.config.SourcePath, m.logger)
+	if errors.Is(err, vault.ErrSecretDeleted) {
+		if secret = m.deletedSecret(m.config.SourcePath, start); secret == nil {
+			return stats, nil
+		}
+		err = nil
+	}
 	if err != nil {
 		m.logger.Error("Error reading secret %s: %v", m.config.SourcePath, err)
+		m.record(m.config.SourcePath, "", ActionError, err, start)
 		return nil, fmt.Errorf("error reading secret: %v", err)
 	}
 
 	atomic.AddInt64(&stats.SecretsRead, 1)
 	m.logger.Verbose("Successfully read secret: %s", m.config.SourcePath)
 
-	// Check existence in destination
 	destPath := m.TransformPath(m.config.SourcePath, m.config.DestinationPath)
+	if m.isCompleted(destPath) {
+		m.logger.Info("Secret was completed by a previous run: %s", destPath)
+		atomic.AddInt64(&stats.SecretsResumed, 1)
+		m.record(secret.Path, destPath, ActionSkipped, nil, start)
+		return stats, nil
+	}
+
+	// Check existence in destination
 	m.logger.Verbose("Checking secret existence in destination: %s", destPath)
 
 	exists, err := m.destClient.SecretExists(destPath, m.logger)
 	if err != nil {
 		m.logger.Error("Error checking secret existence %s: %v", destPath, err)
+		m.record(secret.Path, destPath, ActionError, err, start)
 		return nil, fmt.Errorf("error checking secret existence: %v", err)
 	}
 
 	if exists && !m.config.Overwrite {
 		m.logger.Info("Secret already exists in destination: %s (use --overwrite)", destPath)
 		atomic.AddInt64(&stats.SecretsSkipped, 1)
+		m.markCompleted(destPath)
+		m.record(secret.Path, destPath, ActionSkipped, nil, start)
+		return stats, nil
+	}
+
+	if exists && !secret.Deleted && m.isUnchanged(secret, destPath) {
+		m.logger.Info("Secret is unchanged in destination: %s", destPath)
+		atomic.AddInt64(&stats.SecretsUnchanged, 1)
+		if err := m.copyUnchangedMetadata(secret, destPath); err != nil {
+			atomic.AddInt64(&stats.Errors, 1)
+			m.record(secret.Path, destPath, ActionError, err, start)
+			return nil, err
+		}
+		m.markCompleted(destPath)
+		m.record(secret.Path, destPath, ActionUnchanged, nil, start)
 		return stats, nil
 	}
 
 	if m.config.DryRun {
 		m.logger.Info("[DRY-RUN] Will write secret: %s", destPath)
 		atomic.AddInt64(&stats.SecretsWritten, 1)
+		m.record(secret.Path, destPath, ActionWritten, nil, start)
 		return stats, nil
 	}
 
 	// Write secret
 	m.logger.Info("Writing secret: %s", destPath)
 	m.logger.Verbose("Connecting to destination Vault: %s", m.config.DestAddr)
-	err = m.destClient.WriteSecret(destPath, secret.Data, m.logger)
+	err = m.writeSecret(secret, destPath, stats)
 	if err != nil {
 		m.logger.Error("Error writing secret %s: %v", destPath, err)
 		atomic.AddInt64(&stats.Errors, 1)
+		m.record(secret.Path, destPath, ActionError, err, start)
 		return nil, fmt.Errorf("error writing secret: %v", err)
 	}
 
 	m.logger.Verbose("Successfully wrote secret: %s", destPath)
 	atomic.AddInt64(&stats.SecretsWritten, 1)
+	m.markCompleted(destPath)
+	m.record(secret.Path, destPath, ActionWritten, nil, start)
 
 	return stats, nil
 }
@@ -180,27 +432,41 @@ func (m *SyncManager) syncDirectory(ctx context.Context, stats *SyncStats) (*Syn
 		m.logger.Verbose("Getting list of all secrets from: %s", m.config.SourcePath)
 		sourceSecrets, sourceErrChan := m.sourceClient.GetAllSecrets(ctx, m.config.SourcePath, m.logger)
 
-		for {
+		// A secret or folder that can't be read is recorded and the walk goes on with the others,
+		// so both channels are read until they are closed
+		for sourceSecrets != nil || sourceErrChan != nil {
 			select {
 			case secret, ok := <-sourceSecrets:
 				if !ok {
-					m.logger.Verbose("Finished reading secrets from: %s", m.config.SourcePath)
-					return
+					sourceSecrets = nil
+					continue
 				}
 				atomic.AddInt64(&stats.SecretsRead, 1)
 				m.logger.Verbose("Read secret: %s", secret.Path)
-				secretsChan <- secret
-			case err := <-sourceErrChan:
+				select {
+				case secretsChan <- secret:
+				case <-ctx.Done():
+					m.logger.Verbose("Context cancelled while reading secrets")
+					m.stoppedEarly.Store(true)
+					return
+				}
+			case err, ok := <-sourceErrChan:
+				if !ok {
+					sourceErrChan = nil
+					continue
+				}
 				if err != nil {
 					m.logger.Error("Error getting list of secrets: %v", err)
+					m.recordWalkError(ctx, m.config.SourcePath, err)
 					errChan <- err
 				}
-				return
 			case <-ctx.Done():
 				m.logger.Verbose("Context cancelled while reading secrets")
+				m.stoppedEarly.Store(true)
 				return
 			}
 		}
+		m.logger.Verbose("Finished reading secrets from: %s", m.config.SourcePath)
 	}()
 
 	// Start writers
@@ -224,6 +490,11 @@ func (m *SyncManager) syncDirectory(ctx context.Context, stats *SyncStats) (*Syn
 
 	// Process errors
 	for err := range errChan {
+		if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			// The walk was stopped by the interruption, it isn't a failed secret
+			m.stoppedEarly.Store(true)
+			continue
+		}
 		atomic.AddInt64(&stats.Errors, 1)
 		m.logger.Error("Error: %v", err)
 	}
@@ -242,50 +513,215 @@ func (m *SyncManager) writeWorker(ctx context.Context, workerID int,
 		select {
 		case <-ctx.Done():
 			m.logger.Verbose("Worker %d: context cancelled", workerID)
+			m.stoppedEarly.Store(true)
 			return
 		default:
 		}
 
-		destPath := m.TransformPath(secret.Path, m.config.DestinationPath)
-		m.logger.Verbose("Worker %d: processing secret %s -> %s", workerID, secret.Path, destPath)
-
-		// Check existence
-		m.logger.Verbose("Worker %d: checking secret existence: %s", workerID, destPath)
-		exists, err := m.destClient.SecretExists(destPath, m.logger)
-		if err != nil {
-			m.logger.Error("Worker %d: error checking %s: %v", workerID, destPath, err)
-			errChan <- fmt.Errorf("worker %d: error checking %s: %v", workerID, destPath, err)
-			continue
+		m.limiter.acquire()
+		if ctx.Err() != nil {
+			// Interrupted while waiting for the adaptive limiter
+			m.limiter.release()
+			m.logger.Verbose("Worker %d: context cancelled", workerID)
+			m.stoppedEarly.Store(true)
+			return
 		}
+		m.processSecret(workerID, secret, errChan, stats)
+		m.limiter.release()
+	}
 
-		if exists && !m.config.Overwrite {
-			m.logger.Info("Worker %d: skipping existing secret: %s", workerID, destPath)
-			atomic.AddInt64(&stats.SecretsSkipped, 1)
-			continue
-		}
+	m.logger.Verbose("Worker %d: finished", workerID)
+}
 
-		if m.config.DryRun {
-			m.logger.Info("[DRY-RUN] Worker %d: will write %s", workerID, destPath)
-			atomic.AddInt64(&stats.SecretsWritten, 1)
-			continue
-		}
+// processSecret copies one secret read from the source to the destination,
+// skipping it when it exists or is unchanged according to the configuration.
+func (m *SyncManager) processSecret(workerID int, secret *vault.Secret, errChan chan<- error, stats *SyncStats) {
+	start := time.Now()
+	destPath := m.TransformPath(secret.Path, m.config.DestinationPath)
+	m.copied.add(destPath)
+	m.logger.Verbose("Worker %d: processing secret %s -> %s", workerID, secret.Path, destPath)
+
+	if m.isCompleted(destPath) {
+		m.logger.Verbose("Worker %d: skipping secret completed by a previous run: %s", workerID, destPath)
+		atomic.AddInt64(&stats.SecretsResumed, 1)
+		m.record(secret.Path, destPath, ActionSkipped, nil, start)
+		return
+	}
 
-		// Write secret
-		m.logger.Verbose("Worker %d: writing secret: %s", workerID, destPath)
-		m.logger.Verbose("Worker %d: connecting to destination Vault: %s", workerID, m.config.DestAddr)
-		err = m.destClient.WriteSecret(destPath, secret.Data, m.logger)
-		if err != nil {
-			m.logger.Error("Worker %d: error writing %s: %v", workerID, destPath, err)
-			errChan <- fmt.Errorf("worker %d: error writing %s: %v", workerID, destPath, err)
-			continue
+	// Check existence
+	m.logger.Verbose("Worker %d: checking secret existence: %s", workerID, destPath)
+	exists, err := m.destClient.SecretExists(destPath, m.logger)
+	if err != nil {
+		m.logger.Error("Worker %d: error checking %s: %v", workerID, destPath, err)
+		m.record(secret.Path, destPath, ActionError, err, start)
+		errChan <- fmt.Errorf("worker %d: error checking %s: %v", workerID, destPath, err)
+		return
+	}
+
+	if exists && !m.config.Overwrite {
+		m.logger.Info("Worker %d: skipping existing secret: %s", workerID, destPath)
+		atomic.AddInt64(&stats.SecretsSkipped, 1)
+		m.markCompleted(destPath)
+		m.record(secret.Path, destPath, ActionSkipped, nil, start)
+		return
+	}
+
+	if exists && !secret.Deleted && m.isUnchanged(secret, destPath) {
+		m.logger.Info("Worker %d: skipping unchanged secret: %s", workerID, destPath)
+		atomic.AddInt64(&stats.SecretsUnchanged, 1)
+		if err := m.copyUnchangedMetadata(secret, destPath); err != nil {
+			m.record(secret.Path, destPath, ActionError, err, start)
+			errChan <- fmt.Errorf("worker %d: %v", workerID, err)
+			return
 		}
+		m.markCompleted(destPath)
+		m.record(secret.Path, destPath, ActionUnchanged, nil, start)
+		return
+	}
 
-		m.logger.Info("Worker %d: wrote secret: %s", workerID, destPath)
-		m.logger.Verbose("Worker %d: successfully wrote secret: %s", workerID, destPath)
+	if m.config.DryRun {
+		m.logger.Info("[DRY-RUN] Worker %d: will write %s", workerID, destPath)
 		atomic.AddInt64(&stats.SecretsWritten, 1)
+		m.record(secret.Path, destPath, ActionWritten, nil, start)
+		return
 	}
 
-	m.logger.Verbose("Worker %d: finished", workerID)
+	// Write secret
+	m.logger.Verbose("Worker %d: writing secret: %s", workerID, destPath)
+	m.logger.Verbose("Worker %d: connecting to destination Vault: %s", workerID, m.config.DestAddr)
+	err = m.writeSecret(secret, destPath, stats)
+	if err != nil {
+		m.logger.Error("Worker %d: error writing %s: %v", workerID, destPath, err)
+		m.record(secret.Path, destPath, ActionError, err, start)
+		errChan <- fmt.Errorf("worker %d: error writing %s: %v", workerID, destPath, err)
+		return
+	}
+
+	m.logger.Info("Worker %d: wrote secret: %s", workerID, destPath)
+	m.logger.Verbose("Worker %d: successfully wrote secret: %s", workerID, destPath)
+	atomic.AddInt64(&stats.SecretsWritten, 1)
+	m.markCompleted(destPath)
+	m.record(secret.Path, destPath, ActionWritten, nil, start)
+}
+
+// isCompleted reports whether a previous run recorded destPath as completed in the state file.
+func (m *SyncManager) isCompleted(destPath string) bool {
+	return m.checkpoint != nil && m.checkpoint.isDone(destPath)
+}
+
+// markCompleted records destPath as completed in the state file.
+// Nothing is recorded in dry-run mode, since nothing was written.
+func (m *SyncManager) markCompleted(destPath string) {
+	if m.checkpoint == nil || m.config.DryRun {
+		return
+	}
+
+	if err := m.checkpoint.markDone(destPath); err != nil {
+		m.logger.Error("Error recording %s in state file: %v", destPath, err)
+	}
+}
+
+// isUnchanged reports whether the destination secret already holds the same data as the source secret.
+// Rewriting it would only add a KV v2 version without changes. If the destination can't be read,
+// the secret is treated as changed and written.
+func (m *SyncManager) isUnchanged(secret *vault.Secret, destPath string) bool {
+	destSecret, err := m.destClient.ReadSecret(destPath, m.logger)
+	if err != nil || destSecret == nil {
+		m.logger.Verbose("Could not read destination secret %s for comparison: %v", destPath, err)
+		return false
+	}
+
+	return utils.EqualData(secret.Data, destSecret.Data)
+}
+
+// copyUnchangedMetadata copies the KV v2 metadata of a secret whose data is unchanged,
+// since metadata settings may still differ. Nothing is written in dry-run mode.
+func (m *SyncManager) copyUnchangedMetadata(secret *vault.Secret, destPath string) error {
+	if !m.config.CopyMetadata || m.config.DryRun {
+		return nil
+	}
+
+	if err := m.copyMetadata(secret.Path, destPath); err != nil {
+		m.logger.Error("Error copying metadata %s: %v", destPath, err)
+		return fmt.Errorf("error copying metadata %s: %v", destPath, err)
+	}
+	return nil
+}
+
+// writeSecret writes a source secret to destPath in the destination Vault.
+// In all-versions mode the full version history is read from the source and replayed instead.
+// With CopyMetadata the KV v2 metadata settings are copied after the data.
+func (m *SyncManager) writeSecret(secret *vault.Secret, destPath string, stats *SyncStats) error {
+	if err := m.writeSecretData(secret, destPath, stats); err != nil {
+		return err
+	}
+
+	if m.config.CopyMetadata {
+		return m.copyMetadata(secret.Path, destPath)
+	}
+
+	return nil
+}
+
+// writeSecretData writes the latest version or, in all-versions mode, the version history of a secret.
+func (m *SyncManager) writeSecretData(secret *vault.Secret, destPath string, stats *SyncStats) error {
+	if !m.config.AllVersions {
+		return m.destClient.WriteSecret(destPath, secret.Data, m.logger)
+	}
+
+	reader, ok := m.sourceClient.(vault.VersionReader)
+	if !ok {
+		return fmt.Errorf("source does not support reading secret versions")
+	}
+
+	writer, ok := m.destClient.(vault.VersionWriter)
+	if !ok {
+		return fmt.Errorf("destination does not support writing secret versions")
+	}
+
+	versions, err := reader.ReadSecretVersions(secret.Path, m.logger)
+	if err != nil {
+		return fmt.Errorf("error reading versions of %s: %v", secret.Path, err)
+	}
+
+	written, err := writer.WriteSecretVersions(destPath, versions, m.logger)
+	atomic.AddInt64(&stats.VersionsWritten, int64(written))
+	if err != nil {
+		return err
+	}
+
+	m.logger.Verbose("Replayed %d of %d versions: %s -> %s", written, len(versions), secret.Path, destPath)
+	return nil
+}
+
+// copyMetadata copies the KV v2 metadata settings of a secret from the source to the destination.
+func (m *SyncManager) copyMetadata(sourcePath, destPath string) error {
+	reader, ok := m.sourceClient.(vault.MetadataReader)
+	if !ok {
+		return fmt.Errorf("source does not support reading secret metadata")
+	}
+
+	writer, ok := m.destClient.(vault.MetadataWriter)
+	if !ok {
+		return fmt.Errorf("destination does not support writing secret metadata")
+	}
+
+	metadata, err := reader.ReadSecretMetadata(sourcePath, m.logger)
+	if err != nil {
+		return fmt.Errorf("error reading metadata of %s: %v", sourcePath, err)
+	}
+
+	if metadata == nil {
+		m.logger.Verbose("No metadata to copy for: %s", sourcePath)
+		return nil
+	}
+
+	if err := writer.WriteSecretMetadata(destPath, metadata, m.logger); err != nil {
+		return err
+	}
+
+	m.logger.Verbose("Copied metadata: %s -> %s", sourcePath, destPath)
+	return nil
 }
 
 // TransformPath transforms a source path to a destination path based on the configuration.
@@ -354,9 +790,10 @@ func (m *SyncManager) TransformPath(sourcePath, baseDestPath string) string {
 	return baseDestPath
 }
 
-// syncMultiplePaths synchronizes multiple paths (from wildcard expansion) from the source to the destination.
+// syncMultiplePaths synchronizes multiple paths (from wildcard expansion or a paths file) from the source to the destination.
+// A path that fails is counted as an error and the run goes on with the next one.
 func (m *SyncManager) syncMultiplePaths(ctx context.Context, stats *SyncStats, paths []string) (*SyncStats, error) {
-	m.logger.Info("Syncing %d paths from wildcard expansion", len(paths))
+	m.logger.Info("Syncing %d paths", len(paths))
 
 	// Create channels for parallel processing
 	secretsChan := make(chan *vault.Secret, m.config.ParallelWorkers*2)
@@ -375,47 +812,40 @@ func (m *SyncManager) syncMultiplePaths(ctx context.Context, stats *SyncStats, p
 			isDir, err := m.sourceClient.IsDirectory(path, m.logger)
 			if err != nil {
 				m.logger.Error("Error checking if path is directory %s: %v", path, err)
+				m.record(path, "", ActionError, err, time.Now())
 				errChan <- fmt.Errorf("error checking path %s: %v", path, err)
-				return
+				continue
 			}
 
 			if isDir {
-				// Get all secrets under this directory
-				sourceSecrets, sourceErrChan := m.sourceClient.GetAllSecrets(ctx, path, m.logger)
-
-				for {
-					select {
-					case secret, ok := <-sourceSecrets:
-						if !ok {
-							goto nextPath
-						}
-						atomic.AddInt64(&stats.SecretsRead, 1)
-						m.logger.Verbose("Read secret: %s", secret.Path)
-						secretsChan <- secret
-					case err := <-sourceErrChan:
-						if err != nil {
-							m.logger.Error("Error getting secrets from %s: %v", path, err)
-							errChan <- fmt.Errorf("error getting secrets from %s: %v", path, err)
-							return
-						}
-						goto nextPath
-					case <-ctx.Done():
-						m.logger.Verbose("Context cancelled while reading secrets from %s", path)
-						return
-					}
+				if !m.readDirectory(ctx, path, secretsChan, errChan, stats) {
+					return
 				}
-			nextPath:
 			} else {
 				// Single secret
+				start := time.Now()
 				secret, err := m.sourceClient.ReadSecret(path, m.logger)
+				if errors.Is(err, vault.ErrSecretDeleted) {
+					if secret = m.deletedSecret(path, start); secret == nil {
+						continue
+					}
+					err = nil
+				}
 				if err != nil {
 					m.logger.Error("Error reading secret %s: %v", path, err)
+					m.record(path, "", ActionError, err, start)
 					errChan <- fmt.Errorf("error reading secret %s: %v", path, err)
-					return
+					continue
 				}
 				atomic.AddInt64(&stats.SecretsRead, 1)
 				m.logger.Verbose("Read secret: %s", secret.Path)
-				secretsChan <- secret
+				select {
+				case secretsChan <- secret:
+				case <-ctx.Done():
+					m.logger.Verbose("Context cancelled while reading secrets")
+					m.stoppedEarly.Store(true)
+					return
+				}
 			}
 		}
 	}()
@@ -441,9 +871,62 @@ func (m *SyncManager) syncMultiplePaths(ctx context.Context, stats *SyncStats, p
 
 	// Process errors
 	for err := range errChan {
+		if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			// The walk was stopped by the interruption, it isn't a failed secret
+			m.stoppedEarly.Store(true)
+			continue
+		}
 		atomic.AddInt64(&stats.Errors, 1)
 		m.logger.Error("Error: %v", err)
 	}
 
 	return stats, nil
 }
+
+// readDirectory walks one directory of a multi-path run and sends its secrets to the secrets channel.
+// Secrets and folders that can't be read are reported to the error channel and the walk goes on;
+// a failed walk of the directory itself stops only this directory.
+// It returns false when the run is interrupted.
+func (m *SyncManager) readDirectory(ctx context.Context, path string, secretsChan chan<- *vault.Secret, errChan chan<- error, stats *SyncStats) bool {
+	// Stop the walk of this directory when giving up on it
+	walkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Get all secrets under this directory
+	sourceSecrets, sourceErrChan := m.sourceClient.GetAllSecrets(walkCtx, path, m.logger)
+
+	for sourceSecrets != nil || sourceErrChan != nil {
+		select {
+		case secret, ok := <-sourceSecrets:
+			if !ok {
+				sourceSecrets = nil
+				continue
+			}
+			atomic.AddInt64(&stats.SecretsRead, 1)
+			m.logger.Verbose("Read secret: %s", secret.Path)
+			select {
+			case secretsChan <- secret:
+			case <-ctx.Done():
+				m.logger.Verbose("Context cancelled while reading secrets from %s", path)
+				m.stoppedEarly.Store(true)
+				return false
+			}
+		case err, ok := <-sourceErrChan:
+			if !ok {
+				sourceErrChan = nil
+				continue
+			}
+			if err != nil {
+				m.logger.Error("Error getting secrets from %s: %v", path, err)
+				m.recordWalkError(ctx, path, err)
+				errChan <- fmt.Errorf("error getting secrets from %s: %v", path, err)
+			}
+		case <-ctx.Done():
+			m.logger.Verbose("Context cancelled while reading secrets from %s", path)
+			m.stoppedEarly.Store(true)
+			return false
+		}
+	}
+
+	return ctx.Err() == nil
+}
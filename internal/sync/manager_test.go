@@ -3,10 +3,12 @@ package sync
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"vault-copy/internal/config"
 	"vault-copy/internal/logger"
+	"vault-copy/internal/vault"
 	"vault-copy/mocks"
 )
 
@@ -343,6 +345,302 @@ func TestSyncErrorHandling(t *testing.T) {
 	}
 }
 
+func TestSyncAllVersions(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	destMock := mocks.NewMockClient()
+
+	sourceMock.AddSecretVersions("secret/data/source/app", []*vault.SecretVersion{
+		{Version: 1, Data: map[string]interface{}{"password": "v1"}},
+		{Version: 2, Destroyed: true},
+		{Version: 3, Deleted: true},
+		{Version: 4, Data: map[string]interface{}{"password": "v4"}},
+	})
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/source/app",
+		DestinationPath: "secret/data/dest/app",
+		ParallelWorkers: 1,
+		AllVersions:     true,
+	}
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+
+	stats, err := manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if stats.SecretsWritten != 1 {
+		t.Errorf("SecretsWritten = %d, want 1", stats.SecretsWritten)
+	}
+
+	if stats.VersionsWritten != 3 {
+		t.Errorf("VersionsWritten = %d, want 3", stats.VersionsWritten)
+	}
+
+	history := destMock.Versions["secret/data/dest/app"]
+	if len(history) != 3 {
+		t.Fatalf("destination has %d versions, want 3", len(history))
+	}
+
+	if history[0].Data["password"] != "v1" || history[2].Data["password"] != "v4" {
+		t.Errorf("destination versions were not replayed in order: %v, %v", history[0].Data, history[2].Data)
+	}
+
+	if !history[1].Deleted {
+		t.Error("soft-deleted source version was not recreated as deleted")
+	}
+}
+
+func TestSyncCopyMetadata(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	destMock := mocks.NewMockClient()
+
+	sourceMock.AddSecret("secret/data/source/app", map[string]interface{}{"key": "value"})
+	sourceMock.SetSecretMetadata("secret/data/source/app", &vault.SecretMetadata{
+		CustomMetadata:     map[string]string{"owner": "team-a", "ticket": "OPS-42"},
+		MaxVersions:        5,
+		CASRequired:        true,
+		DeleteVersionAfter: "720h0m0s",
+	})
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/source/app",
+		DestinationPath: "secret/data/dest/app",
+		ParallelWorkers: 1,
+		CopyMetadata:    true,
+	}
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+
+	if _, err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	metadata := destMock.Metadata["secret/data/dest/app"]
+	if metadata == nil {
+		t.Fatal("metadata was not copied to destination")
+	}
+
+	if metadata.CustomMetadata["owner"] != "team-a" || metadata.CustomMetadata["ticket"] != "OPS-42" {
+		t.Errorf("CustomMetadata = %v, want owner and ticket from source", metadata.CustomMetadata)
+	}
+
+	if metadata.MaxVersions != 5 || !metadata.CASRequired || metadata.DeleteVersionAfter != "720h0m0s" {
+		t.Errorf("metadata = %+v, want settings from source", metadata)
+	}
+}
+
+func TestSyncSkipsUnchangedSecrets(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	destMock := mocks.NewMockClient()
+
+	sourceMock.AddDirectory("secret/data/source/apps", []string{"same", "changed", "new"})
+	sourceMock.AddSecret("secret/data/source/apps/same", map[string]interface{}{"host": "db", "port": 5432})
+	sourceMock.AddSecret("secret/data/source/apps/changed", map[string]interface{}{"password": "new"})
+	sourceMock.AddSecret("secret/data/source/apps/new", map[string]interface{}{"key": "value"})
+
+	// The destination returns JSON numbers as float64
+	destMock.AddSecret("secret/data/dest/apps/same", map[string]interface{}{"host": "db", "port": float64(5432)})
+	destMock.AddSecret("secret/data/dest/apps/changed", map[string]interface{}{"password": "old"})
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/source/apps",
+		DestinationPath: "secret/data/dest/apps",
+		Recursive:       true,
+		Overwrite:       true,
+		ParallelWorkers: 2,
+	}
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+
+	stats, err := manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if stats.SecretsUnchanged != 1 {
+		t.Errorf("SecretsUnchanged = %d, want 1", stats.SecretsUnchanged)
+	}
+
+	if stats.SecretsWritten != 2 {
+		t.Errorf("SecretsWritten = %d, want 2", stats.SecretsWritten)
+	}
+
+	if got := destMock.Secrets["secret/data/dest/apps/same"].Data["port"]; got != float64(5432) {
+		t.Errorf("unchanged secret was rewritten, port = %#v", got)
+	}
+
+	if got := destMock.Secrets["secret/data/dest/apps/changed"].Data["password"]; got != "new" {
+		t.Errorf("changed secret password = %v, want new", got)
+	}
+}
+
+func TestSyncSingleSecretUnchanged(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	destMock := mocks.NewMockClient()
+
+	sourceMock.AddSecret("secret/data/source/app", map[string]interface{}{"key": "value"})
+	destMock.AddSecret("secret/data/dest/app", map[string]interface{}{"key": "value"})
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/source/app",
+		DestinationPath: "secret/data/dest/app",
+		Overwrite:       true,
+		ParallelWorkers: 1,
+	}
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+
+	stats, err := manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if stats.SecretsUnchanged != 1 || stats.SecretsWritten != 0 {
+		t.Errorf("SecretsUnchanged = %d, SecretsWritten = %d, want 1 and 0", stats.SecretsUnchanged, stats.SecretsWritten)
+	}
+}
+
+func TestSyncReportsRetries(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	destMock := mocks.NewMockClient()
+
+	sourceMock.AddSecret("secret/data/source/app", map[string]interface{}{"key": "value"})
+	sourceMock.RetryCount = 2
+	destMock.RetryCount = 3
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/source/app",
+		DestinationPath: "secret/data/dest/app",
+		ParallelWorkers: 1,
+	}
+
+	stats, err := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if stats.Retries != 5 {
+		t.Errorf("Retries = %d, want 5", stats.Retries)
+	}
+}
+
+func TestSyncAdaptiveConcurrency(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	destMock := mocks.NewMockClient()
+
+	sourceMock.AddDirectory("secret/data/source", []string{"app1", "app2", "app3"})
+	for _, name := range []string{"app1", "app2", "app3"} {
+		sourceMock.AddSecret("secret/data/source/"+name, map[string]interface{}{"key": name})
+	}
+
+	cfg := &config.Config{
+		SourcePath:          "secret/data/source",
+		DestinationPath:     "secret/data/dest",
+		Recursive:           true,
+		ParallelWorkers:     3,
+		AdaptiveConcurrency: true,
+	}
+
+	stats, err := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if stats.SecretsWritten != 3 {
+		t.Errorf("SecretsWritten = %d, want 3", stats.SecretsWritten)
+	}
+	if sourceMock.Observer == nil || destMock.Observer == nil {
+		t.Error("adaptive concurrency did not observe the requests of both clients")
+	}
+}
+
+// cancellingWriter cancels the run when the first secret is written, like a SIGINT during a write
+type cancellingWriter struct {
+	*mocks.Adapter
+	cancel context.CancelFunc
+}
+
+func (w *cancellingWriter) WriteSecret(path string, data map[string]interface{}, logger *logger.Logger) error {
+	w.cancel()
+	return w.Adapter.WriteSecret(path, data, logger)
+}
+
+func TestSyncInterrupted(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	destMock := mocks.NewMockClient()
+
+	names := []string{"app1", "app2", "app3", "app4", "app5"}
+	sourceMock.AddDirectory("secret/data/source", names)
+	for _, name := range names {
+		sourceMock.AddSecret("secret/data/source/"+name, map[string]interface{}{"key": name})
+	}
+
+	cfg := &config.Config{
+		SourcePath:       "secret/data/source",
+		DestinationPath:  "secret/data/dest",
+		Recursive:        true,
+		ParallelWorkers:  1,
+		DeleteExtraneous: true,
+		DeleteMode:       config.DeleteModeSoft,
+	}
+	destMock.AddSecret("secret/data/dest/stale", map[string]interface{}{"key": "stale"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), &cancellingWriter{Adapter: mocks.NewAdapter(destMock), cancel: cancel}, cfg)
+	if manager.Progress() != nil {
+		t.Error("Progress() before Sync() = non-nil, want nil")
+	}
+
+	stats, err := manager.Sync(ctx)
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("Sync() error = %v, want ErrInterrupted", err)
+	}
+
+	// The write in flight when the run was cancelled completes, nothing new is started
+	if stats.SecretsWritten != 1 {
+		t.Errorf("SecretsWritten = %d, want 1", stats.SecretsWritten)
+	}
+	if stats.Errors != 0 {
+		t.Errorf("Errors = %d, want 0: an interruption is not a failure", stats.Errors)
+	}
+	if len(destMock.Deleted) != 0 {
+		t.Errorf("Deleted = %v, want no deletions after an interruption", destMock.Deleted)
+	}
+
+	if progress := manager.Progress(); progress == nil || progress.SecretsWritten != 1 {
+		t.Errorf("Progress() = %+v, want SecretsWritten 1", progress)
+	}
+}
+
+func TestSyncSignalAfterWorkDone(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	destMock := mocks.NewMockClient()
+	sourceMock.AddSecret("secret/data/source/app1", map[string]interface{}{"key": "app1"})
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/source/app1",
+		DestinationPath: "secret/data/dest/app1",
+		ParallelWorkers: 1,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The signal arrives during the last write, nothing is left to do once it completes
+	manager := NewManager(mocks.NewAdapter(sourceMock), &cancellingWriter{Adapter: mocks.NewAdapter(destMock), cancel: cancel}, cfg)
+	stats, err := manager.Sync(ctx)
+	if err != nil {
+		t.Fatalf("Sync() error = %v, want a complete run", err)
+	}
+	if stats.SecretsWritten != 1 {
+		t.Errorf("SecretsWritten = %d, want 1", stats.SecretsWritten)
+	}
+}
+
 func TestTransformPathMethod(t *testing.T) {
 	manager := &SyncManager{
 		config: &config.Config{
@@ -395,3 +693,83 @@ func TestTransformPathMethod(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncSingleSecretDeleted(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	sourceMock.SetReadError("secret/data/source/app", fmt.Errorf("%w: secret/data/source/app", vault.ErrSecretDeleted))
+	destMock := mocks.NewMockClient()
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/source/app",
+		DestinationPath: "secret/data/dest/app",
+		ParallelWorkers: 1,
+	}
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+	stats, err := manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v, a soft-deleted secret should be skipped", err)
+	}
+
+	if stats.SecretsSourceDeleted != 1 || stats.SecretsWritten != 0 || stats.Errors != 0 {
+		t.Errorf("stats = %+v, want 1 source-deleted secret and nothing written", stats)
+	}
+	if len(stats.Results) != 1 || stats.Results[0].Action != ActionSkipped {
+		t.Errorf("Results = %+v, want one skipped secret", stats.Results)
+	}
+}
+
+func TestSyncAllVersionsDeletedLatest(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	destMock := mocks.NewMockClient()
+
+	sourceMock.AddDirectory("secret/data/source", []string{"app", "removed"})
+	sourceMock.AddSecret("secret/data/source/app", map[string]interface{}{"password": "live"})
+	sourceMock.AddSecretVersions("secret/data/source/removed", []*vault.SecretVersion{
+		{Version: 1, Data: map[string]interface{}{"password": "v1"}},
+		{Version: 2, Deleted: true},
+	})
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/source",
+		DestinationPath: "secret/data/dest",
+		Recursive:       true,
+		ParallelWorkers: 2,
+		AllVersions:     true,
+	}
+
+	stats, err := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if stats.SecretsWritten != 2 || stats.SecretsSourceDeleted != 0 {
+		t.Errorf("SecretsWritten = %d, SecretsSourceDeleted = %d, want 2 and 0", stats.SecretsWritten, stats.SecretsSourceDeleted)
+	}
+
+	// The live version and the deleted marker are both replayed
+	history := destMock.Versions["secret/data/dest/removed"]
+	if len(history) != 2 || history[0].Data["password"] != "v1" || !history[1].Deleted {
+		t.Fatalf("destination history = %+v, want v1 then a deleted version", history)
+	}
+	if _, ok := destMock.Secrets["secret/data/dest/removed"]; ok {
+		t.Error("destination secret has a current value, want its latest version deleted")
+	}
+
+	// The same secret copied on its own
+	destMock = mocks.NewMockClient()
+	cfg = &config.Config{
+		SourcePath:      "secret/data/source/removed",
+		DestinationPath: "secret/data/dest/removed",
+		ParallelWorkers: 1,
+		AllVersions:     true,
+	}
+
+	stats, err = NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("single secret Sync() error = %v", err)
+	}
+	if stats.VersionsWritten != 2 || len(destMock.Versions["secret/data/dest/removed"]) != 2 {
+		t.Errorf("VersionsWritten = %d, history = %+v, want 2 versions replayed", stats.VersionsWritten, destMock.Versions["secret/data/dest/removed"])
+	}
+}
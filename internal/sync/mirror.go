@@ -0,0 +1,139 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/vault"
+)
+
+// pathSet is a set of secret paths that is safe for concurrent use.
+type pathSet struct {
+	mu    sync.Mutex
+	paths map[string]struct{}
+}
+
+// newPathSet creates an empty pathSet
+func newPathSet() *pathSet {
+	return &pathSet{paths: make(map[string]struct{})}
+}
+
+// add records a path, leading and trailing slashes are ignored
+func (s *pathSet) add(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paths[strings.Trim(path, "/")] = struct{}{}
+}
+
+// has reports whether a path was recorded
+func (s *pathSet) has(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.paths[strings.Trim(path, "/")]
+	return ok
+}
+
+// deleteExtraneous deletes secrets under the destination path that have no counterpart in the source.
+// It runs after the copy and is skipped when the copy had errors, since a secret that failed
+// to be read from the source would otherwise look extraneous and be deleted.
+func (m *SyncManager) deleteExtraneous(ctx context.Context, stats *SyncStats) error {
+	if errors := atomic.LoadInt64(&stats.Errors); errors > 0 {
+		return fmt.Errorf("not deleting extraneous secrets: %d errors during copy", errors)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	deleter, ok := m.destClient.(vault.Deleter)
+	if !ok {
+		return fmt.Errorf("destination does not support deleting secrets")
+	}
+
+	m.logger.Verbose("Looking for extraneous secrets in destination: %s", m.config.DestinationPath)
+	extraneous, err := m.findExtraneous(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("error listing destination secrets: %v", err)
+	}
+
+	m.logger.Info("Found %d extraneous secrets in destination: %s", len(extraneous), m.config.DestinationPath)
+	limitExceeded := m.config.MaxDeletes > 0 && len(extraneous) > m.config.MaxDeletes
+
+	if m.config.DryRun {
+		for _, path := range extraneous {
+			m.logger.Info("[DRY-RUN] Will delete secret: %s", path)
+			atomic.AddInt64(&stats.SecretsDeleted, 1)
+			m.record("", path, ActionDeleted, nil, time.Now())
+		}
+		if limitExceeded {
+			m.logger.Error("%d planned deletions exceed --max-deletes=%d, a real run would abort",
+				len(extraneous), m.config.MaxDeletes)
+		}
+		return nil
+	}
+
+	if limitExceeded {
+		return fmt.Errorf("%d planned deletions exceed --max-deletes=%d, nothing was deleted",
+			len(extraneous), m.config.MaxDeletes)
+	}
+
+	destroy := m.config.DeleteMode == config.DeleteModeDestroy
+	for _, path := range extraneous {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		m.logger.Info("Deleting extraneous secret: %s", path)
+		start := time.Now()
+		if err := deleter.DeleteSecret(path, destroy, m.logger); err != nil {
+			m.logger.Error("Error deleting secret %s: %v", path, err)
+			atomic.AddInt64(&stats.Errors, 1)
+			m.record("", path, ActionError, err, start)
+			continue
+		}
+
+		atomic.AddInt64(&stats.SecretsDeleted, 1)
+		m.record("", path, ActionDeleted, nil, start)
+	}
+
+	return nil
+}
+
+// findExtraneous walks the destination path and returns, sorted, the secrets
+// that were not produced by the copy.
+func (m *SyncManager) findExtraneous(ctx context.Context) ([]string, error) {
+	isDir, err := m.destClient.IsDirectory(m.config.DestinationPath, m.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isDir {
+		m.logger.Verbose("Destination %s is not a directory, nothing to delete", m.config.DestinationPath)
+		return nil, nil
+	}
+
+	destSecrets, err := m.collectSecrets(ctx, m.destClient, m.config.DestinationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var extraneous []string
+	for _, secret := range destSecrets {
+		if !m.copied.has(secret.Path) {
+			m.logger.Verbose("Secret has no source counterpart: %s", secret.Path)
+			extraneous = append(extraneous, secret.Path)
+		}
+	}
+
+	sort.Strings(extraneous)
+	return extraneous, nil
+}
@@ -0,0 +1,149 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"vault-copy/internal/config"
+	"vault-copy/mocks"
+)
+
+// newMirrorMocks returns a source with app1 and app2 and a destination
+// that already holds app1 and the extraneous old1 and old2
+func newMirrorMocks() (*mocks.MockClient, *mocks.MockClient) {
+	sourceMock := mocks.NewMockClient()
+	sourceMock.AddDirectory("secret/data/source/apps", []string{"app1", "app2"})
+	sourceMock.AddSecret("secret/data/source/apps/app1", map[string]interface{}{"key": "value1"})
+	sourceMock.AddSecret("secret/data/source/apps/app2", map[string]interface{}{"key": "value2"})
+
+	destMock := mocks.NewMockClient()
+	destMock.AddDirectory("secret/data/dest/apps", []string{"app1", "old1", "nested/"})
+	destMock.AddDirectory("secret/data/dest/apps/nested/", []string{"old2"})
+	destMock.AddSecret("secret/data/dest/apps/app1", map[string]interface{}{"key": "value1"})
+	destMock.AddSecret("secret/data/dest/apps/old1", map[string]interface{}{"key": "stale"})
+	destMock.AddSecret("secret/data/dest/apps/nested/old2", map[string]interface{}{"key": "stale"})
+
+	return sourceMock, destMock
+}
+
+func newMirrorConfig() *config.Config {
+	return &config.Config{
+		SourcePath:       "secret/data/source/apps",
+		DestinationPath:  "secret/data/dest/apps",
+		Recursive:        true,
+		ParallelWorkers:  2,
+		DeleteExtraneous: true,
+		DeleteMode:       config.DeleteModeSoft,
+		MaxDeletes:       config.DefaultMaxDeletes,
+	}
+}
+
+func TestSyncDeleteExtraneous(t *testing.T) {
+	for _, mode := range []string{config.DeleteModeSoft, config.DeleteModeDestroy} {
+		t.Run(mode, func(t *testing.T) {
+			sourceMock, destMock := newMirrorMocks()
+			cfg := newMirrorConfig()
+			cfg.DeleteMode = mode
+
+			manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+
+			stats, err := manager.Sync(context.Background())
+			if err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+
+			if stats.SecretsDeleted != 2 {
+				t.Errorf("SecretsDeleted = %d, want 2", stats.SecretsDeleted)
+			}
+
+			for _, path := range []string{"secret/data/dest/apps/old1", "secret/data/dest/apps/nested/old2"} {
+				destroy, ok := destMock.Deleted[path]
+				if !ok {
+					t.Errorf("%s was not deleted", path)
+				}
+				if destroy != (mode == config.DeleteModeDestroy) {
+					t.Errorf("%s deleted with destroy = %t in %s mode", path, destroy, mode)
+				}
+			}
+
+			for _, path := range []string{"secret/data/dest/apps/app1", "secret/data/dest/apps/app2"} {
+				if _, ok := destMock.Deleted[path]; ok {
+					t.Errorf("%s has a source counterpart but was deleted", path)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncDeleteExtraneousDryRun(t *testing.T) {
+	sourceMock, destMock := newMirrorMocks()
+	cfg := newMirrorConfig()
+	cfg.DryRun = true
+	cfg.MaxDeletes = 1
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+
+	stats, err := manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if stats.SecretsDeleted != 2 {
+		t.Errorf("SecretsDeleted = %d, want 2 planned deletions", stats.SecretsDeleted)
+	}
+
+	if len(destMock.Deleted) != 0 {
+		t.Errorf("dry-run deleted %v", destMock.Deleted)
+	}
+}
+
+func TestSyncDeleteExtraneousLimit(t *testing.T) {
+	sourceMock, destMock := newMirrorMocks()
+	cfg := newMirrorConfig()
+	cfg.MaxDeletes = 1
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+
+	_, err := manager.Sync(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "--max-deletes") {
+		t.Fatalf("Sync() error = %v, want max deletes error", err)
+	}
+
+	if len(destMock.Deleted) != 0 {
+		t.Errorf("deleted %v despite exceeding the limit", destMock.Deleted)
+	}
+}
+
+func TestSyncDeleteExtraneousAfterErrors(t *testing.T) {
+	sourceMock, destMock := newMirrorMocks()
+	// app3 can't be read, so its destination copy would look extraneous
+	sourceMock.AddDirectory("secret/data/source/apps", []string{"app1", "app2", "app3"})
+	sourceMock.SetReadError("secret/data/source/apps/app3", errors.New("permission denied"))
+	destMock.AddDirectory("secret/data/dest/apps", []string{"app1", "app3"})
+	destMock.AddSecret("secret/data/dest/apps/app3", map[string]interface{}{"key": "value3"})
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), newMirrorConfig())
+
+	_, err := manager.Sync(context.Background())
+	if err == nil {
+		t.Fatal("Sync() expected error when the copy had errors, got nil")
+	}
+
+	if len(destMock.Deleted) != 0 {
+		t.Errorf("deleted %v after a failed copy", destMock.Deleted)
+	}
+}
+
+func TestSyncDeleteExtraneousWildcard(t *testing.T) {
+	sourceMock, destMock := newMirrorMocks()
+	cfg := newMirrorConfig()
+	cfg.SourcePath = "secret/data/source/apps/app*"
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+
+	if _, err := manager.Sync(context.Background()); err == nil {
+		t.Fatal("Sync() expected error for wildcard source path, got nil")
+	}
+}
@@ -0,0 +1,66 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"vault-copy/internal/logger"
+	"vault-copy/internal/vault"
+)
+
+// tokenRenewal tracks token renewal of the source and destination clients during a run.
+type tokenRenewal struct {
+	wg  sync.WaitGroup
+	mu  sync.Mutex
+	err error
+}
+
+// startTokenRenewal starts token renewal for every client that supports it.
+// The first renewal failure cancels the run through cancel.
+func (m *SyncManager) startTokenRenewal(ctx context.Context, cancel context.CancelFunc) *tokenRenewal {
+	r := &tokenRenewal{}
+
+	r.watch(ctx, cancel, m.logger, "source", m.sourceClient)
+	if m.destClient != m.sourceClient {
+		r.watch(ctx, cancel, m.logger, "destination", m.destClient)
+	}
+
+	return r
+}
+
+// watch starts renewal for a single client and records its failure.
+func (r *tokenRenewal) watch(ctx context.Context, cancel context.CancelFunc, logger *logger.Logger, side string, client vault.ClientInterface) {
+	renewer, ok := client.(vault.TokenRenewer)
+	if !ok {
+		return
+	}
+
+	errChan := renewer.StartTokenRenewal(ctx, logger)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		for err := range errChan {
+			logger.Error("%s Vault token renewal failed, aborting synchronization: %v", side, err)
+
+			r.mu.Lock()
+			if r.err == nil {
+				r.err = fmt.Errorf("%s Vault token renewal failed: %v", side, err)
+			}
+			r.mu.Unlock()
+
+			cancel()
+		}
+	}()
+}
+
+// wait blocks until renewal of all clients has stopped and returns the first failure.
+func (r *tokenRenewal) wait() error {
+	r.wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
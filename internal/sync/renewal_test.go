@@ -0,0 +1,79 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+	"vault-copy/internal/vault"
+	"vault-copy/mocks"
+)
+
+// renewingClient wraps a client and reports a fixed token renewal result
+type renewingClient struct {
+	vault.ClientInterface
+	renewErr error
+}
+
+func (c *renewingClient) StartTokenRenewal(ctx context.Context, logger *logger.Logger) <-chan error {
+	errChan := make(chan error, 1)
+	if c.renewErr != nil {
+		errChan <- c.renewErr
+	}
+	close(errChan)
+	return errChan
+}
+
+func TestSyncTokenRenewalFailure(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	destMock := mocks.NewMockClient()
+	sourceMock.AddSecret("secret/data/source/app", map[string]interface{}{"key": "value"})
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/source/app",
+		DestinationPath: "secret/data/dest/app",
+		ParallelWorkers: 1,
+	}
+
+	destClient := &renewingClient{
+		ClientInterface: mocks.NewAdapter(destMock),
+		renewErr:        errors.New("token is about to expire and cannot be renewed"),
+	}
+	manager := NewManager(mocks.NewAdapter(sourceMock), destClient, cfg)
+
+	_, err := manager.Sync(context.Background())
+	if err == nil {
+		t.Fatal("Sync() expected token renewal error, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "destination Vault token renewal failed") {
+		t.Errorf("Sync() error = %v, want destination token renewal failure", err)
+	}
+}
+
+func TestSyncTokenRenewalNotNeeded(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	destMock := mocks.NewMockClient()
+	sourceMock.AddSecret("secret/data/source/app", map[string]interface{}{"key": "value"})
+
+	cfg := &config.Config{
+		SourcePath:      "secret/data/source/app",
+		DestinationPath: "secret/data/dest/app",
+		ParallelWorkers: 1,
+	}
+
+	sourceClient := &renewingClient{ClientInterface: mocks.NewAdapter(sourceMock)}
+	manager := NewManager(sourceClient, mocks.NewAdapter(destMock), cfg)
+
+	stats, err := manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if stats.SecretsWritten != 1 {
+		t.Errorf("SecretsWritten = %d, want 1", stats.SecretsWritten)
+	}
+}
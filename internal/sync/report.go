@@ -0,0 +1,150 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Report formats
+const (
+	// ReportFormatJSON writes one JSON document with the statistics and all secret records
+	ReportFormatJSON = "json"
+	// ReportFormatNDJSON writes every secret record as its own JSON line as soon as it is known,
+	// followed by a summary line with the statistics
+	ReportFormatNDJSON = "ndjson"
+)
+
+// Run statuses written in a report
+const (
+	// StatusCompleted means the run went through, possibly with errors on single secrets
+	StatusCompleted = "completed"
+	// StatusInterrupted means the run was stopped by a signal before it completed
+	StatusInterrupted = "interrupted"
+	// StatusFailed means the run was aborted by an error
+	StatusFailed = "failed"
+)
+
+// reportRecord is the JSON form of a SecretResult
+type reportRecord struct {
+	Type            string  `json:"type,omitempty"`
+	SourcePath      string  `json:"source_path,omitempty"`
+	DestinationPath string  `json:"destination_path,omitempty"`
+	Action          string  `json:"action"`
+	Error           string  `json:"error,omitempty"`
+	DurationMs      float64 `json:"duration_ms"`
+}
+
+// reportSummary is the JSON form of the outcome of a run
+type reportSummary struct {
+	Type   string     `json:"type,omitempty"`
+	Status string     `json:"status"`
+	Error  string     `json:"error,omitempty"`
+	DryRun bool       `json:"dry_run"`
+	Stats  *SyncStats `json:"stats"`
+}
+
+// jsonReport is the document written in ReportFormatJSON
+type jsonReport struct {
+	reportSummary
+	Secrets []reportRecord `json:"secrets"`
+}
+
+// ReportWriter writes a machine-readable report of a run.
+// It is safe for concurrent use; records arriving after Finish are ignored.
+type ReportWriter struct {
+	mu       sync.Mutex
+	encoder  *json.Encoder
+	format   string
+	records  []reportRecord
+	finished bool
+	// err is the first error writing a streamed record, returned by Finish
+	err error
+}
+
+// NewReportWriter creates a report writer for w in ReportFormatJSON or ReportFormatNDJSON
+func NewReportWriter(w io.Writer, format string) (*ReportWriter, error) {
+	if format != ReportFormatJSON && format != ReportFormatNDJSON {
+		return nil, fmt.Errorf("unsupported report format %q, use %s or %s", format, ReportFormatJSON, ReportFormatNDJSON)
+	}
+
+	encoder := json.NewEncoder(w)
+	if format == ReportFormatJSON {
+		encoder.SetIndent("", "  ")
+	}
+
+	return &ReportWriter{
+		encoder: encoder,
+		format:  format,
+		records: []reportRecord{},
+	}, nil
+}
+
+// Record adds the result of a secret to the report
+func (r *ReportWriter) Record(result SecretResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.finished {
+		return
+	}
+
+	record := reportRecord{
+		SourcePath:      result.SourcePath,
+		DestinationPath: result.DestinationPath,
+		Action:          result.Action,
+		Error:           result.Error,
+		DurationMs:      float64(result.Duration) / float64(time.Millisecond),
+	}
+
+	if r.format == ReportFormatJSON {
+		r.records = append(r.records, record)
+		return
+	}
+
+	record.Type = "secret"
+	if err := r.encoder.Encode(record); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// Finish writes the statistics and the outcome of the run given by the error returned by Sync.
+// It returns the first error writing the report.
+func (r *ReportWriter) Finish(stats *SyncStats, runErr error, dryRun bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.finished {
+		return r.err
+	}
+	r.finished = true
+
+	summary := reportSummary{
+		Status: StatusCompleted,
+		DryRun: dryRun,
+		Stats:  stats,
+	}
+	if runErr != nil {
+		summary.Status = StatusFailed
+		if errors.Is(runErr, ErrInterrupted) {
+			summary.Status = StatusInterrupted
+		}
+		summary.Error = runErr.Error()
+	}
+
+	var err error
+	if r.format == ReportFormatJSON {
+		err = r.encoder.Encode(jsonReport{reportSummary: summary, Secrets: r.records})
+	} else {
+		summary.Type = "summary"
+		err = r.encoder.Encode(summary)
+	}
+
+	if r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
@@ -0,0 +1,175 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewReportWriterFormat(t *testing.T) {
+	for _, format := range []string{ReportFormatJSON, ReportFormatNDJSON} {
+		if _, err := NewReportWriter(&bytes.Buffer{}, format); err != nil {
+			t.Errorf("NewReportWriter(%q) error = %v", format, err)
+		}
+	}
+
+	if _, err := NewReportWriter(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("NewReportWriter(\"xml\") expected error, got nil")
+	}
+}
+
+func TestReportWriterJSON(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewReportWriter(&buf, ReportFormatJSON)
+	if err != nil {
+		t.Fatalf("NewReportWriter() error = %v", err)
+	}
+
+	writer.Record(SecretResult{
+		SourcePath:      "secret/data/source/app1",
+		DestinationPath: "secret/data/dest/app1",
+		Action:          ActionWritten,
+		Duration:        1500 * time.Microsecond,
+	})
+	writer.Record(SecretResult{SourcePath: "secret/data/source/app2", Action: ActionError, Error: "permission denied"})
+
+	// Nothing is written before the end of the run
+	if buf.Len() != 0 {
+		t.Errorf("report written before Finish: %s", buf.String())
+	}
+
+	stats := &SyncStats{SecretsRead: 2, SecretsWritten: 1, Errors: 1}
+	if err := writer.Finish(stats, nil, true); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	var report struct {
+		Status  string                   `json:"status"`
+		DryRun  bool                     `json:"dry_run"`
+		Stats   SyncStats                `json:"stats"`
+		Secrets []map[string]interface{} `json:"secrets"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("report is not a JSON document: %v\n%s", err, buf.String())
+	}
+
+	if report.Status != StatusCompleted || !report.DryRun {
+		t.Errorf("status = %s, dry_run = %t, want %s and true", report.Status, report.DryRun, StatusCompleted)
+	}
+	if !reflect.DeepEqual(report.Stats, *stats) {
+		t.Errorf("stats = %+v, want %+v", report.Stats, *stats)
+	}
+	if len(report.Secrets) != 2 {
+		t.Fatalf("got %d secret records, want 2", len(report.Secrets))
+	}
+
+	first := report.Secrets[0]
+	if first["source_path"] != "secret/data/source/app1" || first["destination_path"] != "secret/data/dest/app1" ||
+		first["action"] != ActionWritten || first["duration_ms"] != 1.5 {
+		t.Errorf("first record = %v", first)
+	}
+	if _, ok := first["error"]; ok {
+		t.Errorf("successful record has an error field: %v", first)
+	}
+	if second := report.Secrets[1]; second["action"] != ActionError || second["error"] != "permission denied" {
+		t.Errorf("second record = %v", second)
+	}
+}
+
+func TestReportWriterNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewReportWriter(&buf, ReportFormatNDJSON)
+	if err != nil {
+		t.Fatalf("NewReportWriter() error = %v", err)
+	}
+
+	writer.Record(SecretResult{SourcePath: "secret/data/source/app1", DestinationPath: "secret/data/dest/app1", Action: ActionSkipped})
+
+	// Records are streamed as soon as they are known
+	if lines := strings.Count(buf.String(), "\n"); lines != 1 {
+		t.Errorf("got %d lines before Finish, want 1", lines)
+	}
+
+	writer.Record(SecretResult{DestinationPath: "secret/data/dest/old", Action: ActionDeleted})
+	if err := writer.Finish(&SyncStats{SecretsSkipped: 1, SecretsDeleted: 1}, nil, false); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), buf.String())
+	}
+
+	wantTypes := []string{"secret", "secret", "summary"}
+	for i, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %d is not JSON: %v", i+1, err)
+		}
+		if record["type"] != wantTypes[i] {
+			t.Errorf("line %d type = %v, want %s", i+1, record["type"], wantTypes[i])
+		}
+		if i == 2 && record["status"] != StatusCompleted {
+			t.Errorf("summary status = %v, want %s", record["status"], StatusCompleted)
+		}
+	}
+}
+
+func TestReportWriterStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status string
+	}{
+		{"completed", nil, StatusCompleted},
+		{"interrupted", ErrInterrupted, StatusInterrupted},
+		{"failed", errors.New("permission denied"), StatusFailed},
+		{"wrapped interruption", fmt.Errorf("copy: %w", ErrInterrupted), StatusInterrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, _ := NewReportWriter(&buf, ReportFormatNDJSON)
+			if err := writer.Finish(&SyncStats{}, tt.err, false); err != nil {
+				t.Fatalf("Finish() error = %v", err)
+			}
+
+			var summary map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &summary); err != nil {
+				t.Fatalf("summary is not JSON: %v", err)
+			}
+			if summary["status"] != tt.status {
+				t.Errorf("status = %v, want %s", summary["status"], tt.status)
+			}
+			if tt.err != nil && summary["error"] != tt.err.Error() {
+				t.Errorf("error = %v, want %s", summary["error"], tt.err)
+			}
+		})
+	}
+}
+
+func TestReportWriterFinishOnce(t *testing.T) {
+	var buf bytes.Buffer
+	writer, _ := NewReportWriter(&buf, ReportFormatNDJSON)
+
+	if err := writer.Finish(&SyncStats{}, ErrInterrupted, false); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	written := buf.Len()
+
+	// Late records and a second Finish don't change the report
+	writer.Record(SecretResult{SourcePath: "secret/data/source/app1", Action: ActionWritten})
+	if err := writer.Finish(&SyncStats{}, nil, false); err != nil {
+		t.Fatalf("second Finish() error = %v", err)
+	}
+
+	if buf.Len() != written {
+		t.Errorf("report changed after Finish:\n%s", buf.String())
+	}
+}
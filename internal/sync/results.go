@@ -0,0 +1,135 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"vault-copy/internal/vault"
+)
+
+// Actions recorded in a SecretResult
+const (
+	// ActionWritten means the secret was written to the destination, or would be in dry-run mode
+	ActionWritten = "written"
+	// ActionSkipped means the secret already existed or was completed by a previous run
+	ActionSkipped = "skipped"
+	// ActionUnchanged means the destination already had the same data
+	ActionUnchanged = "unchanged"
+	// ActionDeleted means the extraneous destination secret was deleted in mirror mode
+	ActionDeleted = "deleted"
+	// ActionError means the secret could not be read, compared, written or deleted
+	ActionError = "error"
+)
+
+// SecretResult is the outcome of one secret processed during a run.
+// Secrets that failed to be read only have a SourcePath, deleted ones only a DestinationPath.
+type SecretResult struct {
+	// SourcePath is the path of the secret in the source Vault
+	SourcePath string
+	// DestinationPath is the path of the secret in the destination Vault
+	DestinationPath string
+	// Action is what happened to the secret, one of the Action constants
+	Action string
+	// Error is the error message when Action is ActionError
+	Error string
+	// Duration is the time spent processing the secret
+	Duration time.Duration
+}
+
+// OnResult registers a handler called with the result of every processed secret.
+// Calls are serialized, so the handler doesn't need to be safe for concurrent use.
+func (m *SyncManager) OnResult(handler func(result SecretResult)) {
+	m.resultsMu.Lock()
+	defer m.resultsMu.Unlock()
+	m.resultHandler = handler
+}
+
+// record passes the result of a secret to the registered handler.
+// err is recorded as the error message; start is when processing of the secret began.
+func (m *SyncManager) record(sourcePath, destPath, action string, err error, start time.Time) {
+	result := SecretResult{
+		SourcePath:      sourcePath,
+		DestinationPath: destPath,
+		Action:          action,
+		Duration:        time.Since(start),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	m.resultsMu.Lock()
+	defer m.resultsMu.Unlock()
+	m.results = append(m.results, result)
+	if m.resultHandler != nil {
+		m.resultHandler(result)
+	}
+}
+
+// resetResults drops the results collected by a previous run
+func (m *SyncManager) resetResults() {
+	m.resultsMu.Lock()
+	defer m.resultsMu.Unlock()
+	m.results = nil
+}
+
+// collectedResults returns a copy of the results collected so far
+func (m *SyncManager) collectedResults() []SecretResult {
+	m.resultsMu.Lock()
+	defer m.resultsMu.Unlock()
+	return append([]SecretResult(nil), m.results...)
+}
+
+// Completed returns the number of secrets that are in place in the destination:
+// written, skipped because they exist, unchanged or completed by a previous run
+func (s *SyncStats) Completed() int64 {
+	return s.SecretsWritten + s.SecretsSkipped + s.SecretsUnchanged + s.SecretsResumed
+}
+
+// Failed returns the results of the secrets that failed, in the order they completed
+func (s *SyncStats) Failed() []SecretResult {
+	var failed []SecretResult
+	for _, result := range s.Results {
+		if result.Action == ActionError {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// FailedPaths returns the sorted source paths of the secrets that failed, without duplicates.
+// Written as a paths file they restrict a retry run to these secrets, see config.ReadPathsFile.
+// Failed deletions of extraneous secrets have no source path and are not included.
+func (s *SyncStats) FailedPaths() []string {
+	seen := make(map[string]struct{})
+	var paths []string
+	for _, result := range s.Failed() {
+		if result.SourcePath == "" {
+			continue
+		}
+		if _, ok := seen[result.SourcePath]; ok {
+			continue
+		}
+		seen[result.SourcePath] = struct{}{}
+		paths = append(paths, result.SourcePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// recordWalkError records a failed walk of rootPath, at the failed path when the error carries one.
+// Errors caused by the interruption of the run are not recorded.
+func (m *SyncManager) recordWalkError(ctx context.Context, rootPath string, err error) {
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return
+	}
+
+	path := rootPath
+	var pathErr *vault.PathError
+	if errors.As(err, &pathErr) {
+		path = pathErr.Path
+	}
+
+	m.record(path, "", ActionError, err, time.Now())
+}
@@ -0,0 +1,260 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"vault-copy/mocks"
+)
+
+// collectResults registers a handler on the manager that collects the results by path
+func collectResults(manager *SyncManager) map[string]SecretResult {
+	results := make(map[string]SecretResult)
+	manager.OnResult(func(result SecretResult) {
+		path := result.SourcePath
+		if path == "" {
+			path = result.DestinationPath
+		}
+		results[path] = result
+	})
+	return results
+}
+
+func TestSyncOnResult(t *testing.T) {
+	sourceMock, destMock := newMirrorMocks()
+	cfg := newMirrorConfig()
+	cfg.Overwrite = true
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+	results := collectResults(manager)
+
+	if _, err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	want := map[string]SecretResult{
+		"secret/data/source/apps/app1": {DestinationPath: "secret/data/dest/apps/app1", Action: ActionUnchanged},
+		"secret/data/source/apps/app2": {DestinationPath: "secret/data/dest/apps/app2", Action: ActionWritten},
+		"secret/data/dest/apps/old1":   {DestinationPath: "secret/data/dest/apps/old1", Action: ActionDeleted},
+		"secret/data/dest/apps/nested/old2": {
+			DestinationPath: "secret/data/dest/apps/nested/old2",
+			Action:          ActionDeleted,
+		},
+	}
+
+	if len(results) != len(want) {
+		t.Errorf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+
+	for path, expected := range want {
+		result, ok := results[path]
+		if !ok {
+			t.Errorf("no result for %s", path)
+			continue
+		}
+		if result.DestinationPath != expected.DestinationPath || result.Action != expected.Action {
+			t.Errorf("result for %s = %+v, want destination %s and action %s",
+				path, result, expected.DestinationPath, expected.Action)
+		}
+		if result.Error != "" {
+			t.Errorf("result for %s has error %q", path, result.Error)
+		}
+	}
+}
+
+func TestSyncOnResultErrors(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	destMock := mocks.NewMockClient()
+
+	sourceMock.AddDirectory("secret/data/source/apps", []string{"app1", "app2"})
+	sourceMock.AddSecret("secret/data/source/apps/app1", map[string]interface{}{"key": "value1"})
+	sourceMock.AddSecret("secret/data/source/apps/app2", map[string]interface{}{"key": "value2"})
+
+	destMock.AddSecret("secret/data/dest/apps/app1", map[string]interface{}{"key": "old"})
+	destMock.SetWriteError("secret/data/dest/apps/app2", errors.New("read-only"))
+
+	cfg := newMirrorConfig()
+	cfg.DeleteExtraneous = false
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+	results := collectResults(manager)
+
+	stats, err := manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if stats.Errors != 1 {
+		t.Errorf("Errors = %d, want 1", stats.Errors)
+	}
+
+	if got := results["secret/data/source/apps/app1"].Action; got != ActionSkipped {
+		t.Errorf("app1 action = %s, want %s", got, ActionSkipped)
+	}
+
+	written := results["secret/data/source/apps/app2"]
+	if written.Action != ActionError || written.DestinationPath != "secret/data/dest/apps/app2" || written.Error == "" {
+		t.Errorf("app2 result = %+v, want a write error", written)
+	}
+}
+
+func TestSyncOnResultReadError(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	sourceMock.AddDirectory("secret/data/source/apps", []string{"app1"})
+	sourceMock.SetReadError("secret/data/source/apps/app1", errors.New("permission denied"))
+
+	cfg := newMirrorConfig()
+	cfg.DeleteExtraneous = false
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(mocks.NewMockClient()), cfg)
+	results := collectResults(manager)
+
+	if _, err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	// The failing secret is reported at its own path, not at the walked folder
+	result, ok := results["secret/data/source/apps/app1"]
+	if !ok {
+		t.Fatalf("no result for the unreadable secret: %+v", results)
+	}
+	if result.Action != ActionError || result.DestinationPath != "" || result.Error == "" {
+		t.Errorf("result = %+v, want a read error without destination", result)
+	}
+}
+
+func TestSyncFailedPaths(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	sourceMock.AddDirectory("secret/data/source/apps", []string{"app1", "app2", "app3"})
+	sourceMock.AddSecret("secret/data/source/apps/app1", map[string]interface{}{"key": "value1"})
+	sourceMock.AddSecret("secret/data/source/apps/app2", map[string]interface{}{"key": "value2"})
+	sourceMock.AddSecret("secret/data/source/apps/app3", map[string]interface{}{"key": "value3"})
+
+	destMock := mocks.NewMockClient()
+	destMock.SetWriteError("secret/data/dest/apps/app3", errors.New("read-only"))
+	destMock.SetWriteError("secret/data/dest/apps/app1", errors.New("read-only"))
+
+	cfg := newMirrorConfig()
+	cfg.DeleteExtraneous = false
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+
+	stats, err := manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if len(stats.Results) != 3 {
+		t.Errorf("got %d results, want 3", len(stats.Results))
+	}
+	if stats.Completed() != 1 {
+		t.Errorf("Completed() = %d, want 1", stats.Completed())
+	}
+
+	failed := stats.Failed()
+	if len(failed) != 2 {
+		t.Fatalf("got %d failed results, want 2: %+v", len(failed), failed)
+	}
+	for _, result := range failed {
+		if result.Error == "" {
+			t.Errorf("failed result without error: %+v", result)
+		}
+	}
+
+	want := []string{"secret/data/source/apps/app1", "secret/data/source/apps/app3"}
+	if got := stats.FailedPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("FailedPaths() = %v, want %v", got, want)
+	}
+
+	// A second run starts with no results
+	destMock.SetWriteError("secret/data/dest/apps/app1", nil)
+	destMock.SetWriteError("secret/data/dest/apps/app3", nil)
+	cfg.Paths = want
+
+	stats, err = manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("retry Sync() error = %v", err)
+	}
+	if len(stats.Results) != 2 || len(stats.Failed()) != 0 {
+		t.Errorf("retry results = %+v, want 2 successful results", stats.Results)
+	}
+}
+
+func TestSyncPaths(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	sourceMock.AddDirectory("secret/data/source/apps", []string{"app1", "app2", "team/"})
+	sourceMock.AddDirectory("secret/data/source/apps/team/", []string{"app3"})
+	sourceMock.AddSecret("secret/data/source/apps/app1", map[string]interface{}{"key": "value1"})
+	sourceMock.AddSecret("secret/data/source/apps/app2", map[string]interface{}{"key": "value2"})
+	sourceMock.AddSecret("secret/data/source/apps/team/app3", map[string]interface{}{"key": "value3"})
+	sourceMock.SetReadError("secret/data/source/apps/missing", errors.New("permission denied"))
+
+	destMock := mocks.NewMockClient()
+
+	cfg := newMirrorConfig()
+	cfg.DeleteExtraneous = false
+	// A path that still fails doesn't stop the others
+	cfg.Paths = []string{"secret/data/source/apps/missing", "secret/data/source/apps/app2", "secret/data/source/apps/team/app3"}
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+
+	stats, err := manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if stats.SecretsWritten != 2 || stats.Errors != 1 {
+		t.Errorf("SecretsWritten = %d, Errors = %d, want 2 and 1", stats.SecretsWritten, stats.Errors)
+	}
+
+	for _, path := range []string{"secret/data/dest/apps/app2", "secret/data/dest/apps/team/app3"} {
+		if _, ok := destMock.Secrets[path]; !ok {
+			t.Errorf("%s was not copied", path)
+		}
+	}
+	if _, ok := destMock.Secrets["secret/data/dest/apps/app1"]; ok {
+		t.Error("app1 is not in the paths file but was copied")
+	}
+
+	if got := stats.FailedPaths(); !reflect.DeepEqual(got, []string{"secret/data/source/apps/missing"}) {
+		t.Errorf("FailedPaths() = %v, want the missing secret", got)
+	}
+}
+
+func TestSyncContinuesAfterReadError(t *testing.T) {
+	sourceMock := mocks.NewMockClient()
+	sourceMock.AddDirectory("secret/data/source/apps", []string{"app1", "app2", "broken/", "team/", "app3"})
+	sourceMock.AddDirectory("secret/data/source/apps/broken/", []string{"app5"})
+	sourceMock.AddDirectory("secret/data/source/apps/team/", []string{"app4"})
+	sourceMock.AddSecret("secret/data/source/apps/app1", map[string]interface{}{"key": "value1"})
+	sourceMock.SetReadError("secret/data/source/apps/app2", errors.New("permission denied"))
+	sourceMock.SetListError("secret/data/source/apps/broken/", errors.New("permission denied"))
+	sourceMock.AddSecret("secret/data/source/apps/team/app4", map[string]interface{}{"key": "value4"})
+	sourceMock.AddSecret("secret/data/source/apps/app3", map[string]interface{}{"key": "value3"})
+	destMock := mocks.NewMockClient()
+
+	cfg := newMirrorConfig()
+	cfg.DeleteExtraneous = false
+
+	manager := NewManager(mocks.NewAdapter(sourceMock), mocks.NewAdapter(destMock), cfg)
+	stats, err := manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	// Every secret after the failing ones is still copied
+	for _, path := range []string{"secret/data/dest/apps/app1", "secret/data/dest/apps/team/app4", "secret/data/dest/apps/app3"} {
+		if _, ok := destMock.Secrets[path]; !ok {
+			t.Errorf("%s was not copied", path)
+		}
+	}
+	if stats.SecretsWritten != 3 || stats.Errors != 2 {
+		t.Errorf("SecretsWritten = %d, Errors = %d, want 3 and 2", stats.SecretsWritten, stats.Errors)
+	}
+
+	want := []string{"secret/data/source/apps/app2", "secret/data/source/apps/broken/"}
+	if got := stats.FailedPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("FailedPaths() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package sync
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"vault-copy/internal/config"
+)
+
+// stateKey identifies the source/destination pair a state file belongs to.
+// It is stored as the first line of the state file.
+type stateKey struct {
+	SourceAddr      string `json:"source_addr"`
+	SourceNamespace string `json:"source_namespace,omitempty"`
+	SourcePath      string `json:"source_path"`
+	DestAddr        string `json:"dest_addr"`
+	DestNamespace   string `json:"dest_namespace,omitempty"`
+	DestPath        string `json:"dest_path"`
+}
+
+// newStateKey builds the state key of a configuration
+func newStateKey(cfg *config.Config) stateKey {
+	return stateKey{
+		SourceAddr:      cfg.SourceAddr,
+		SourceNamespace: cfg.SourceNamespace,
+		SourcePath:      cfg.SourcePath,
+		DestAddr:        cfg.DestAddr,
+		DestNamespace:   cfg.DestNamespace,
+		DestPath:        cfg.DestinationPath,
+	}
+}
+
+// checkpoint records completed destination paths in a state file so that an
+// interrupted run can be resumed. The file holds the JSON state key on the
+// first line followed by one completed path per line.
+type checkpoint struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]struct{}
+	// size is the length of the complete lines of the loaded state file
+	size int64
+}
+
+// openCheckpoint opens the state file at path. With resume the completed paths are
+// loaded from an existing file, which must belong to the same source/destination pair;
+// otherwise the file is started over. A read-only checkpoint, used in dry-run mode,
+// never modifies the file.
+func openCheckpoint(path string, key stateKey, resume, readOnly bool) (*checkpoint, error) {
+	c := &checkpoint{done: make(map[string]struct{})}
+
+	if resume {
+		if err := c.load(path, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if readOnly {
+		return c, nil
+	}
+
+	if len(c.done) == 0 {
+		return c, c.create(path, key)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening state file %s: %v", path, err)
+	}
+
+	// Drop the line cut off by the interrupted run, the next path would be appended to it
+	if err := file.Truncate(c.size); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error truncating state file %s: %v", path, err)
+	}
+	c.file = file
+
+	return c, nil
+}
+
+// load reads the completed paths from an existing state file.
+// A missing file is not an error, the run then starts from scratch.
+func (c *checkpoint) load(path string, key stateKey) error {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading state file %s: %v", path, err)
+	}
+
+	// A line without a newline was cut off by the interrupted run
+	if i := bytes.LastIndexByte(raw, '\n'); i >= 0 {
+		raw = raw[:i+1]
+	} else {
+		raw = nil
+	}
+	c.size = int64(len(raw))
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	if !scanner.Scan() {
+		return nil
+	}
+
+	var fileKey stateKey
+	if err := json.Unmarshal(scanner.Bytes(), &fileKey); err != nil {
+		return fmt.Errorf("invalid state file %s: %v", path, err)
+	}
+
+	if fileKey != key {
+		return fmt.Errorf("state file %s was written for %s %s -> %s %s, refusing to resume a different copy",
+			path, fileKey.SourceAddr, fileKey.SourcePath, fileKey.DestAddr, fileKey.DestPath)
+	}
+
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			c.done[line] = struct{}{}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// create starts a new state file holding only the state key
+func (c *checkpoint) create(path string, key stateKey) error {
+	header, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating state file %s: %v", path, err)
+	}
+
+	if _, err := file.Write(append(header, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing state file %s: %v", path, err)
+	}
+
+	c.file = file
+	return nil
+}
+
+// isDone reports whether a destination path was completed by a previous run
+func (c *checkpoint) isDone(path string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.done[path]
+	return ok
+}
+
+// markDone records a completed destination path
+func (c *checkpoint) markDone(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.done[path]; ok {
+		return nil
+	}
+
+	if _, err := c.file.WriteString(path + "\n"); err != nil {
+		return err
+	}
+	c.done[path] = struct{}{}
+	return nil
+}
+
+// Close closes the state file
+func (c *checkpoint) Close() error {
+	if c.file == nil {
+		return nil
+	}
+	return c.file.Close()
+}
@@ -0,0 +1,155 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vault-copy/internal/config"
+	"vault-copy/mocks"
+)
+
+func newStateConfig(stateFile string) *config.Config {
+	return &config.Config{
+		SourcePath:      "secret/data/source/apps",
+		DestinationPath: "secret/data/dest/apps",
+		Recursive:       true,
+		ParallelWorkers: 2,
+		SourceAddr:      "https://vault1:8200",
+		DestAddr:        "https://vault2:8200",
+		StateFile:       stateFile,
+	}
+}
+
+func newStateSource() *mocks.MockClient {
+	sourceMock := mocks.NewMockClient()
+	sourceMock.AddDirectory("secret/data/source/apps", []string{"app1", "app2", "app3"})
+	sourceMock.AddSecret("secret/data/source/apps/app1", map[string]interface{}{"key": "value1"})
+	sourceMock.AddSecret("secret/data/source/apps/app2", map[string]interface{}{"key": "value2"})
+	sourceMock.AddSecret("secret/data/source/apps/app3", map[string]interface{}{"key": "value3"})
+	return sourceMock
+}
+
+func TestSyncStateFileResume(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state")
+
+	// The interrupted run completed app1 and app2, the last line was cut off
+	key := `{"source_addr":"https://vault1:8200","source_path":"secret/data/source/apps",` +
+		`"dest_addr":"https://vault2:8200","dest_path":"secret/data/dest/apps"}`
+	state := key + "\nsecret/data/dest/apps/app1\nsecret/data/dest/apps/app2\nsecret/data/dest/a"
+	if err := os.WriteFile(stateFile, []byte(state), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	destMock := mocks.NewMockClient()
+	cfg := newStateConfig(stateFile)
+	cfg.Resume = true
+
+	manager := NewManager(mocks.NewAdapter(newStateSource()), mocks.NewAdapter(destMock), cfg)
+
+	stats, err := manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if stats.SecretsResumed != 2 || stats.SecretsWritten != 1 {
+		t.Errorf("SecretsResumed = %d, SecretsWritten = %d, want 2 and 1", stats.SecretsResumed, stats.SecretsWritten)
+	}
+
+	if _, ok := destMock.Secrets["secret/data/dest/apps/app3"]; !ok {
+		t.Error("app3 was not written")
+	}
+
+	raw, err := os.ReadFile(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The cut-off line is dropped rather than glued to the next path
+	want := key + "\nsecret/data/dest/apps/app1\nsecret/data/dest/apps/app2\nsecret/data/dest/apps/app3\n"
+	if string(raw) != want {
+		t.Errorf("state file = %q, want %q", raw, want)
+	}
+
+	// A second resume finds all three paths completed
+	manager = NewManager(mocks.NewAdapter(newStateSource()), mocks.NewAdapter(mocks.NewMockClient()), cfg)
+	stats, err = manager.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("second Sync() error = %v", err)
+	}
+	if stats.SecretsResumed != 3 || stats.SecretsWritten != 0 {
+		t.Errorf("second run SecretsResumed = %d, SecretsWritten = %d, want 3 and 0", stats.SecretsResumed, stats.SecretsWritten)
+	}
+}
+
+func TestSyncStateFileRecordsCompletedPaths(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state")
+
+	manager := NewManager(mocks.NewAdapter(newStateSource()), mocks.NewAdapter(mocks.NewMockClient()), newStateConfig(stateFile))
+	if _, err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], `"source_path":"secret/data/source/apps"`) {
+		t.Errorf("state file = %q, want the state key and 3 paths", raw)
+	}
+
+	// Resuming the completed copy writes nothing
+	destMock := mocks.NewMockClient()
+	cfg := newStateConfig(stateFile)
+	cfg.Resume = true
+
+	stats, err := NewManager(mocks.NewAdapter(newStateSource()), mocks.NewAdapter(destMock), cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() resume error = %v", err)
+	}
+	if stats.SecretsResumed != 3 || len(destMock.Secrets) != 0 {
+		t.Errorf("SecretsResumed = %d, written = %d, want 3 and 0", stats.SecretsResumed, len(destMock.Secrets))
+	}
+}
+
+func TestSyncStateFileMismatch(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state")
+
+	manager := NewManager(mocks.NewAdapter(newStateSource()), mocks.NewAdapter(mocks.NewMockClient()), newStateConfig(stateFile))
+	if _, err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	cfg := newStateConfig(stateFile)
+	cfg.DestinationPath = "secret/data/other/apps"
+	cfg.Resume = true
+
+	destMock := mocks.NewMockClient()
+	_, err := NewManager(mocks.NewAdapter(newStateSource()), mocks.NewAdapter(destMock), cfg).Sync(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "refusing to resume") {
+		t.Fatalf("Sync() error = %v, want mismatch error", err)
+	}
+
+	if len(destMock.Secrets) != 0 {
+		t.Errorf("mismatched resume wrote %d secrets", len(destMock.Secrets))
+	}
+}
+
+func TestSyncStateFileDryRun(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state")
+	cfg := newStateConfig(stateFile)
+	cfg.DryRun = true
+
+	manager := NewManager(mocks.NewAdapter(newStateSource()), mocks.NewAdapter(mocks.NewMockClient()), cfg)
+	if _, err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	if _, err := os.Stat(stateFile); !os.IsNotExist(err) {
+		t.Errorf("dry-run created the state file: %v", err)
+	}
+}
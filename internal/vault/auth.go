@@ -0,0 +1,100 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"vault-copy/internal/config"
+)
+
+// login authenticates the client with the configured auth method.
+// For the token method the configured token is used as-is, other methods
+// perform a login request and replace the client token with the issued one.
+func (c *Client) login() error {
+	switch c.config.Auth.Method {
+	case "", config.AuthMethodToken:
+		c.client.SetToken(c.config.Token)
+		return nil
+	case config.AuthMethodAppRole:
+		return c.loginAppRole()
+	case config.AuthMethodKubernetes:
+		return c.loginKubernetes()
+	default:
+		return fmt.Errorf("unsupported auth method: %s", c.config.Auth.Method)
+	}
+}
+
+// loginAppRole logs in via auth/<mount>/login using the AppRole role_id and secret_id.
+// The secret_id file is read on every login so that rotated secret_ids are picked up.
+func (c *Client) loginAppRole() error {
+	auth := c.config.Auth
+
+	secretID := auth.SecretID
+	if secretID == "" && auth.SecretIDFile != "" {
+		content, err := os.ReadFile(auth.SecretIDFile)
+		if err != nil {
+			return fmt.Errorf("error reading AppRole secret_id file %s: %v", auth.SecretIDFile, err)
+		}
+		secretID = strings.TrimSpace(string(content))
+	}
+
+	return c.writeLogin(auth.MountPath, map[string]interface{}{
+		"role_id":   auth.RoleID,
+		"secret_id": secretID,
+	})
+}
+
+// loginKubernetes logs in via auth/<mount>/login using the pod's service account JWT.
+// The JWT is read on every login because projected service account tokens are rotated.
+func (c *Client) loginKubernetes() error {
+	auth := c.config.Auth
+
+	jwtPath := auth.JWTPath
+	if jwtPath == "" {
+		jwtPath = config.DefaultKubernetesJWTPath
+	}
+
+	jwt, err := os.ReadFile(jwtPath)
+	if err != nil {
+		return fmt.Errorf("error reading Kubernetes service account token %s: %v", jwtPath, err)
+	}
+
+	return c.writeLogin(auth.MountPath, map[string]interface{}{
+		"role": auth.KubernetesRole,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+}
+
+// canRelogin reports whether the client can obtain a fresh token by logging in again.
+func (c *Client) canRelogin() bool {
+	method := c.config.Auth.Method
+	return method != "" && method != config.AuthMethodToken
+}
+
+// writeLogin sends a login request to auth/<mount>/login and sets the returned client token.
+// The request goes through a clone of the client so that requests running concurrently
+// keep using the current token until the new one is set. Only the API client, which is safe
+// for concurrent use, is given the token: the config is read by the workers during a re-login.
+func (c *Client) writeLogin(mountPath string, data map[string]interface{}) error {
+	path := fmt.Sprintf("auth/%s/login", strings.Trim(mountPath, "/"))
+
+	loginClient, err := c.client.CloneWithHeaders()
+	if err != nil {
+		return fmt.Errorf("error preparing login request: %v", err)
+	}
+
+	// Login endpoints are unauthenticated, don't send a stale token along
+	loginClient.ClearToken()
+
+	secret, err := loginClient.Logical().Write(path, data)
+	if err != nil {
+		return fmt.Errorf("error logging in via %s: %v", path, err)
+	}
+
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return fmt.Errorf("no token returned by %s", path)
+	}
+
+	c.client.SetToken(secret.Auth.ClientToken)
+	return nil
+}
@@ -0,0 +1,134 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"vault-copy/internal/config"
+)
+
+// newLoginServer starts a fake Vault that answers health checks and
+// login requests on loginPath, recording the last login request body.
+func newLoginServer(t *testing.T, loginPath string, got *map[string]interface{}) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/health":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"initialized":true,"sealed":false,"standby":false}`))
+		case loginPath:
+			if r.Header.Get("X-Vault-Token") != "" {
+				t.Errorf("login request carried a token: %s", r.Header.Get("X-Vault-Token"))
+			}
+			json.NewDecoder(r.Body).Decode(got)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"auth":{"client_token":"issued-token","renewable":true,"lease_duration":60}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewClientWithConfigAppRole(t *testing.T) {
+	var got map[string]interface{}
+	server := newLoginServer(t, "/v1/auth/ci-approle/login", &got)
+
+	secretIDFile := filepath.Join(t.TempDir(), "secret-id")
+	if err := os.WriteFile(secretIDFile, []byte("file-secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret_id file: %v", err)
+	}
+
+	client, err := NewClientWithConfig(&ClientConfig{
+		Addr: server.URL,
+		Auth: config.AuthConfig{
+			Method:       config.AuthMethodAppRole,
+			MountPath:    "ci-approle",
+			RoleID:       "my-role",
+			SecretIDFile: secretIDFile,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithConfig() error = %v", err)
+	}
+
+	if got["role_id"] != "my-role" {
+		t.Errorf("login role_id = %v, want my-role", got["role_id"])
+	}
+	if got["secret_id"] != "file-secret" {
+		t.Errorf("login secret_id = %v, want file-secret", got["secret_id"])
+	}
+	if client.client.Token() != "issued-token" {
+		t.Errorf("client token = %v, want issued-token", client.client.Token())
+	}
+}
+
+func TestNewClientWithConfigKubernetes(t *testing.T) {
+	var got map[string]interface{}
+	server := newLoginServer(t, "/v1/auth/k8s-prod/login", &got)
+
+	jwtPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(jwtPath, []byte("service-account-jwt"), 0600); err != nil {
+		t.Fatalf("Failed to write JWT file: %v", err)
+	}
+
+	client, err := NewClientWithConfig(&ClientConfig{
+		Addr: server.URL,
+		Auth: config.AuthConfig{
+			Method:         config.AuthMethodKubernetes,
+			MountPath:      "k8s-prod",
+			KubernetesRole: "vault-copy",
+			JWTPath:        jwtPath,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithConfig() error = %v", err)
+	}
+
+	if got["role"] != "vault-copy" {
+		t.Errorf("login role = %v, want vault-copy", got["role"])
+	}
+	if got["jwt"] != "service-account-jwt" {
+		t.Errorf("login jwt = %v, want service-account-jwt", got["jwt"])
+	}
+	if client.client.Token() != "issued-token" {
+		t.Errorf("client token = %v, want issued-token", client.client.Token())
+	}
+}
+
+func TestNewClientWithConfigKubernetesMissingJWT(t *testing.T) {
+	var got map[string]interface{}
+	server := newLoginServer(t, "/v1/auth/kubernetes/login", &got)
+
+	_, err := NewClientWithConfig(&ClientConfig{
+		Addr: server.URL,
+		Auth: config.AuthConfig{
+			Method:         config.AuthMethodKubernetes,
+			MountPath:      "kubernetes",
+			KubernetesRole: "vault-copy",
+			JWTPath:        filepath.Join(t.TempDir(), "missing"),
+		},
+	})
+	if err == nil {
+		t.Error("NewClientWithConfig() expected error for missing JWT file, got nil")
+	}
+}
+
+func TestNewClientWithConfigUnsupportedMethod(t *testing.T) {
+	var got map[string]interface{}
+	server := newLoginServer(t, "/v1/auth/approle/login", &got)
+
+	_, err := NewClientWithConfig(&ClientConfig{
+		Addr: server.URL,
+		Auth: config.AuthConfig{Method: "ldap"},
+	})
+	if err == nil {
+		t.Error("NewClientWithConfig() expected error for unsupported method, got nil")
+	}
+}
@@ -2,10 +2,15 @@ package vault
 
 import (
 	"fmt"
+	"math"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"vault-copy/internal/config"
 	"vault-copy/internal/logger"
 
 	"github.com/hashicorp/vault/api"
+	"golang.org/x/time/rate"
 )
 
 // Client is a wrapper around the Vault API client.
@@ -14,32 +19,133 @@ type Client struct {
 	client *api.Client
 	// config holds the client configuration
 	config *ClientConfig
+	// mounts caches resolved mounts by mount path
+	mounts map[string]*mountInfo
+	// mountsMu guards mounts
+	mountsMu sync.Mutex
+	// retries counts the requests retried after a transient error
+	retries atomic.Int64
+	// deleted counts the secrets skipped by walks because their latest version is deleted
+	deleted atomic.Int64
+	// sendDeleted makes walks send the secrets whose latest version is deleted, see SendDeletedSecrets
+	sendDeleted atomic.Bool
+	// observer receives the outcome of every request, see SetRequestObserver
+	observer RequestObserver
+	// observerMu guards observer
+	observerMu sync.RWMutex
 }
 
 // Ensure that Client implements ClientInterface
 var _ ClientInterface = (*Client)(nil)
 
+// Ensure that Client implements TokenRenewer
+var _ TokenRenewer = (*Client)(nil)
+
+// Ensure that Client implements VersionReader and VersionWriter
+var (
+	_ VersionReader = (*Client)(nil)
+	_ VersionWriter = (*Client)(nil)
+)
+
+// Ensure that Client implements MetadataReader and MetadataWriter
+var (
+	_ MetadataReader = (*Client)(nil)
+	_ MetadataWriter = (*Client)(nil)
+)
+
+// Ensure that Client implements Deleter
+var _ Deleter = (*Client)(nil)
+
+// Ensure that Client implements RetryCounter, DeletedCounter, DeletedSender and RequestReporter
+var (
+	_ RetryCounter    = (*Client)(nil)
+	_ DeletedCounter  = (*Client)(nil)
+	_ DeletedSender   = (*Client)(nil)
+	_ RequestReporter = (*Client)(nil)
+)
+
 // ClientConfig holds the configuration for a Vault client.
 type ClientConfig struct {
 	// Addr is the address of the Vault server
 	Addr string
-	// Token is the authentication token for the Vault server
+	// Token is the authentication token for the Vault server, used with the token auth method.
+	// Tokens obtained at login are only held by the API client, the config isn't changed after construction.
 	Token string
+	// Auth holds the auth method settings used to obtain a token
+	Auth config.AuthConfig
+	// TLS holds the TLS settings for connecting to the Vault server
+	TLS config.TLSConfig
+	// Namespace is the Vault Enterprise namespace all requests are sent to
+	Namespace string
+	// Retry is the retry policy for transient errors, the zero value selects config.DefaultRetryConfig
+	Retry config.RetryConfig
+	// Rate limits the requests per second sent to the server, 0 disables the limit
+	Rate float64
+	// Readers is the number of concurrent readers walking a folder, 0 selects config.DefaultReadWorkers
+	Readers int
 }
 
 // NewClient creates a new Vault client with the provided address and token.
 // It also verifies the connection to the Vault server by checking its health.
 func NewClient(addr, token string) (*Client, error) {
-	config := &api.Config{
-		Address: addr,
+	return NewClientWithConfig(&ClientConfig{
+		Addr:  addr,
+		Token: token,
+		Auth:  config.AuthConfig{Method: config.AuthMethodToken},
+	})
+}
+
+// NewClientWithConfig creates a new Vault client from the provided configuration.
+// It verifies the connection to the Vault server by checking its health
+// and then authenticates with the configured auth method.
+func NewClientWithConfig(cfg *ClientConfig) (*Client, error) {
+	apiConfig := &api.Config{
+		Address:    cfg.Addr,
+		HttpClient: api.DefaultConfig().HttpClient,
+	}
+
+	if !cfg.TLS.IsEmpty() {
+		err := apiConfig.ConfigureTLS(&api.TLSConfig{
+			CACert:        cfg.TLS.CACert,
+			CAPath:        cfg.TLS.CAPath,
+			ClientCert:    cfg.TLS.ClientCert,
+			ClientKey:     cfg.TLS.ClientKey,
+			TLSServerName: cfg.TLS.ServerName,
+			Insecure:      cfg.TLS.Insecure,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error configuring TLS for %s: %v", cfg.Addr, err)
+		}
+	}
+
+	c := &Client{
+		config: cfg,
+	}
+
+	// Wrap the transport only now, TLS configuration needs the original *http.Transport
+	apiConfig.HttpClient.Transport = &observedTransport{base: apiConfig.HttpClient.Transport, client: c}
+
+	// One token bucket per client, shared by every worker and the walker using it
+	if cfg.Rate > 0 {
+		apiConfig.Limiter = rate.NewLimiter(rate.Limit(cfg.Rate), int(math.Ceil(cfg.Rate)))
 	}
 
-	client, err := api.NewClient(config)
+	retry := cfg.Retry
+	if retry.MaxAttempts == 0 {
+		retry = config.DefaultRetryConfig()
+	}
+	policy := &retryPolicy{config: retry, retries: func() { c.retries.Add(1) }}
+	apiConfig.MaxRetries = retry.MaxAttempts - 1
+	apiConfig.CheckRetry = policy.checkRetry
+	apiConfig.Backoff = policy.backoff
+
+	client, err := api.NewClient(apiConfig)
 	if err != nil {
 		return nil, err
 	}
+	c.client = client
 
-	client.SetToken(token)
+	client.SetToken(cfg.Token)
 
 	// Verify connection
 	_, err = client.Sys().Health()
@@ -47,13 +153,31 @@ func NewClient(addr, token string) (*Client, error) {
 		return nil, err
 	}
 
-	return &Client{
-		client: client,
-		config: &ClientConfig{
-			Addr:  addr,
-			Token: token,
-		},
-	}, nil
+	// Every request, including login, listing and wildcard expansion, stays in the namespace
+	if cfg.Namespace != "" {
+		client.SetNamespace(cfg.Namespace)
+	}
+
+	if err := c.login(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Retries returns the number of requests retried after a transient error so far
+func (c *Client) Retries() int64 {
+	return c.retries.Load()
+}
+
+// DeletedSkipped returns the number of secrets skipped by walks so far because their latest version is deleted
+func (c *Client) DeletedSkipped() int64 {
+	return c.deleted.Load()
+}
+
+// SendDeletedSecrets makes walks send the secrets whose latest version is deleted, marked Deleted, instead of skipping them
+func (c *Client) SendDeletedSecrets(send bool) {
+	c.sendDeleted.Store(send)
 }
 
 // GetKVEngine extracts the KV engine name from a Vault path.
@@ -67,10 +191,21 @@ func (c *Client) GetKVEngine(path string) (string, error) {
 	return parts[0], nil
 }
 
-// GetKVEngineVersion gets the version of a KV engine from Vault
+// GetKVEngineVersion gets the version of a KV engine from Vault.
+// The engine may be a nested mount path such as "teams/kv" or any path inside the mount.
 func (c *Client) GetKVEngineVersion(engine string, logger *logger.Logger) (int, error) {
 	logger.Verbose("Getting KV engine version for: %s", engine)
 
+	mount := c.lookupMount(engine, logger)
+	if mount.Version == 0 {
+		return 0, fmt.Errorf("could not detect KV version of engine: %s", engine)
+	}
+
+	return mount.Version, nil
+}
+
+// readEngineVersion reads the version of a KV engine from its sys/mounts configuration
+func (c *Client) readEngineVersion(engine string, logger *logger.Logger) (int, error) {
 	// Get engine configuration
 	path := fmt.Sprintf("sys/mounts/%s", engine)
 	secret, err := c.client.Logical().Read(path)
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"context"
+	"time"
 	"vault-copy/internal/logger"
 )
 
@@ -28,3 +29,61 @@ type ClientInterface interface {
 	GetKVEngine(path string) (string, error)
 	GetKVEngineVersion(engine string, logger *logger.Logger) (int, error)
 }
+
+// TokenRenewer is implemented by clients whose token may expire during a long run
+type TokenRenewer interface {
+	StartTokenRenewal(ctx context.Context, logger *logger.Logger) <-chan error
+}
+
+// VersionReader is implemented by clients that can read the full version history of a secret
+type VersionReader interface {
+	ReadSecretVersions(path string, logger *logger.Logger) ([]*SecretVersion, error)
+}
+
+// VersionWriter is implemented by clients that can replay the version history of a secret
+type VersionWriter interface {
+	WriteSecretVersions(path string, versions []*SecretVersion, logger *logger.Logger) (int, error)
+}
+
+// MetadataReader is implemented by clients that can read KV v2 metadata settings of a secret
+type MetadataReader interface {
+	ReadSecretMetadata(path string, logger *logger.Logger) (*SecretMetadata, error)
+}
+
+// MetadataWriter is implemented by clients that can write KV v2 metadata settings of a secret
+type MetadataWriter interface {
+	WriteSecretMetadata(path string, metadata *SecretMetadata, logger *logger.Logger) error
+}
+
+// Deleter is implemented by clients that can delete secrets.
+// With destroy set, KV v2 metadata and all versions are removed; otherwise the latest version is soft-deleted.
+type Deleter interface {
+	DeleteSecret(path string, destroy bool, logger *logger.Logger) error
+}
+
+// RetryCounter is implemented by clients that retry transient errors and count the retries
+type RetryCounter interface {
+	Retries() int64
+}
+
+// DeletedCounter is implemented by clients whose walks skip KV v2 secrets with a deleted latest version and count them
+type DeletedCounter interface {
+	DeletedSkipped() int64
+}
+
+// DeletedSender is implemented by clients whose walks can send the KV v2 secrets with a deleted latest version,
+// marked Deleted, instead of skipping them, so that their version history can still be replayed
+type DeletedSender interface {
+	SendDeletedSecrets(send bool)
+}
+
+// RequestObserver receives the latency and HTTP status of every request a client sends.
+// The status is 0 when the request failed without a response.
+type RequestObserver interface {
+	ObserveRequest(latency time.Duration, status int)
+}
+
+// RequestReporter is implemented by clients that can report their requests to a RequestObserver
+type RequestReporter interface {
+	SetRequestObserver(observer RequestObserver)
+}
@@ -0,0 +1,90 @@
+package vault
+
+import (
+	"fmt"
+	"vault-copy/internal/logger"
+)
+
+// SecretMetadata holds the settings stored at the KV v2 metadata endpoint of a secret.
+type SecretMetadata struct {
+	// CustomMetadata contains user-provided key-value pairs such as owners or tickets
+	CustomMetadata map[string]string `json:"custom_metadata,omitempty"`
+	// MaxVersions is the number of versions to keep, 0 uses the mount default
+	MaxVersions int `json:"max_versions"`
+	// CASRequired indicates whether writes must use check-and-set
+	CASRequired bool `json:"cas_required"`
+	// DeleteVersionAfter is the duration after which versions are deleted, e.g. "768h0m0s"
+	DeleteVersionAfter string `json:"delete_version_after,omitempty"`
+}
+
+// ReadSecretMetadata reads the metadata settings of a KV v2 secret.
+// KV v1 secrets have no metadata, for them nil is returned.
+func (c *Client) ReadSecretMetadata(path string, logger *logger.Logger) (*SecretMetadata, error) {
+	metadataPath, version := c.apiPath(path, "metadata", logger)
+	if version != 2 {
+		logger.Verbose("Secret %s is not in a KV v2 mount, no metadata to read", path)
+		return nil, nil
+	}
+
+	logger.Verbose("Reading secret metadata from: %s", metadataPath)
+	secret, err := c.client.Logical().Read(metadataPath)
+	if err != nil {
+		logger.Error("Error reading secret metadata %s: %v", metadataPath, err)
+		return nil, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("secret metadata not found: %s", metadataPath)
+	}
+
+	metadata := &SecretMetadata{
+		CustomMetadata: map[string]string{},
+	}
+
+	if custom, ok := secret.Data["custom_metadata"].(map[string]interface{}); ok {
+		for k, v := range custom {
+			metadata.CustomMetadata[k] = fmt.Sprint(v)
+		}
+	}
+
+	if maxVersions, ok := secret.Data["max_versions"]; ok {
+		metadata.MaxVersions, err = versionNumber(maxVersions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_versions in %s: %v", metadataPath, err)
+		}
+	}
+
+	metadata.CASRequired, _ = secret.Data["cas_required"].(bool)
+	metadata.DeleteVersionAfter, _ = secret.Data["delete_version_after"].(string)
+
+	return metadata, nil
+}
+
+// WriteSecretMetadata writes the metadata settings of a KV v2 secret.
+// Metadata for KV v1 paths is dropped. Once cas_required is set, data writes
+// to the secret pass its current version as cas, so a later sync can still overwrite it.
+func (c *Client) WriteSecretMetadata(path string, metadata *SecretMetadata, logger *logger.Logger) error {
+	metadataPath, version := c.apiPath(path, "metadata", logger)
+	if version != 2 {
+		logger.Verbose("Secret %s is not in a KV v2 mount, dropping metadata", path)
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"max_versions":    metadata.MaxVersions,
+		"cas_required":    metadata.CASRequired,
+		"custom_metadata": metadata.CustomMetadata,
+	}
+	if metadata.DeleteVersionAfter != "" {
+		data["delete_version_after"] = metadata.DeleteVersionAfter
+	}
+
+	logger.Verbose("Writing secret metadata to: %s", metadataPath)
+	_, err := c.client.Logical().Write(metadataPath, data)
+	if err != nil {
+		logger.Error("Error writing secret metadata %s: %v", metadataPath, err)
+		return fmt.Errorf("error writing secret metadata %s: %v", metadataPath, err)
+	}
+
+	return nil
+}
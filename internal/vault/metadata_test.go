@@ -0,0 +1,146 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+)
+
+func TestReadSecretMetadata(t *testing.T) {
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/metadata/apps/db" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"cas_required":true,"max_versions":7,"delete_version_after":"72h0m0s",
+			"custom_metadata":{"owner":"team-a"},"versions":{"1":{}}}}`))
+	})
+
+	metadata, err := client.ReadSecretMetadata("secret/data/apps/db", logger.NewLogger(&config.Config{}))
+	if err != nil {
+		t.Fatalf("ReadSecretMetadata() error = %v", err)
+	}
+
+	if metadata.CustomMetadata["owner"] != "team-a" {
+		t.Errorf("CustomMetadata = %v, want owner team-a", metadata.CustomMetadata)
+	}
+	if metadata.MaxVersions != 7 || !metadata.CASRequired || metadata.DeleteVersionAfter != "72h0m0s" {
+		t.Errorf("metadata = %+v, want max_versions 7, cas_required, 72h0m0s", metadata)
+	}
+}
+
+func TestWriteSecretMetadata(t *testing.T) {
+	var got map[string]interface{}
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/metadata/backup/db" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.WriteSecretMetadata("kv/data/backup/db", &SecretMetadata{
+		CustomMetadata:     map[string]string{"owner": "team-a"},
+		MaxVersions:        7,
+		CASRequired:        true,
+		DeleteVersionAfter: "72h0m0s",
+	}, logger.NewLogger(&config.Config{}))
+	if err != nil {
+		t.Fatalf("WriteSecretMetadata() error = %v", err)
+	}
+
+	if got["max_versions"] != float64(7) || got["cas_required"] != true || got["delete_version_after"] != "72h0m0s" {
+		t.Errorf("metadata request = %v", got)
+	}
+	if custom, ok := got["custom_metadata"].(map[string]interface{}); !ok || custom["owner"] != "team-a" {
+		t.Errorf("custom_metadata = %v, want owner team-a", got["custom_metadata"])
+	}
+}
+
+func TestSecretMetadataKVv1(t *testing.T) {
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		http.NotFound(w, r)
+	})
+	log := logger.NewLogger(&config.Config{})
+
+	metadata, err := client.ReadSecretMetadata("kv1/apps/db", log)
+	if err != nil || metadata != nil {
+		t.Errorf("ReadSecretMetadata() = %v, %v, want nil metadata for KV v1", metadata, err)
+	}
+
+	err = client.WriteSecretMetadata("kv1/apps/db", &SecretMetadata{MaxVersions: 3}, log)
+	if err != nil {
+		t.Errorf("WriteSecretMetadata() error = %v, want metadata dropped for KV v1", err)
+	}
+}
+
+func TestWriteSecretAfterCASRequiredMetadata(t *testing.T) {
+	// A KV v2 secret at version 1 whose metadata is copied with cas_required, then synced again
+	casRequired, current := false, 1
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/kv/metadata/backup/db":
+			fmt.Fprintf(w, `{"data":{"cas_required":%t,"current_version":%d}}`, casRequired, current)
+		case r.URL.Path == "/v1/kv/metadata/backup/db":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			casRequired, _ = body["cas_required"].(bool)
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/v1/kv/data/backup/db":
+			var body struct {
+				Options map[string]interface{} `json:"options"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			cas, ok := body.Options["cas"]
+			if casRequired && !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"errors":["check-and-set parameter required for this call"]}`))
+				return
+			}
+			if ok && cas != float64(current) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"errors":["check-and-set parameter did not match the current version"]}`))
+				return
+			}
+			current++
+			fmt.Fprintf(w, `{"data":{"version":%d}}`, current)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	})
+	log := logger.NewLogger(&config.Config{})
+
+	err := client.WriteSecretMetadata("kv/data/backup/db", &SecretMetadata{MaxVersions: 5, CASRequired: true}, log)
+	if err != nil {
+		t.Fatalf("WriteSecretMetadata() error = %v", err)
+	}
+
+	if err := client.WriteSecret("kv/data/backup/db", map[string]interface{}{"password": "new"}, log); err != nil {
+		t.Fatalf("WriteSecret() after cas_required metadata error = %v", err)
+	}
+	if current != 2 {
+		t.Errorf("current version = %d, want 2", current)
+	}
+
+	written, err := client.WriteSecretVersions("kv/data/backup/db", []*SecretVersion{
+		{Version: 1, Data: map[string]interface{}{"password": "old"}},
+		{Version: 2, Data: map[string]interface{}{"password": "new"}},
+	}, log)
+	if err != nil || written != 2 {
+		t.Fatalf("WriteSecretVersions() = %d, %v, want 2 versions written", written, err)
+	}
+	if current != 4 {
+		t.Errorf("current version = %d, want 4", current)
+	}
+}
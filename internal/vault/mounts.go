@@ -0,0 +1,113 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+	"vault-copy/internal/logger"
+)
+
+// mountInfo describes the secrets engine mount a path belongs to.
+type mountInfo struct {
+	// Path is the mount path with a trailing slash, e.g. "teams/kv/"
+	Path string
+	// Version is the KV version of the mount, 0 when it couldn't be detected
+	Version int
+}
+
+// lookupMount returns the mount that path belongs to.
+// Mounts are resolved with sys/internal/ui/mounts and cached for the lifetime of the client.
+// When that endpoint isn't available (older Vault, missing permissions), the engine
+// from GetKVEngine is assumed to be the mount and its version is read from sys/mounts.
+func (c *Client) lookupMount(path string, logger *logger.Logger) *mountInfo {
+	path = strings.Trim(path, "/")
+
+	c.mountsMu.Lock()
+	defer c.mountsMu.Unlock()
+
+	var found *mountInfo
+	for mountPath, mount := range c.mounts {
+		if strings.HasPrefix(path+"/", mountPath) && (found == nil || len(mountPath) > len(found.Path)) {
+			found = mount
+		}
+	}
+	if found != nil {
+		return found
+	}
+
+	mount, err := c.readMount(path, logger)
+	if err != nil {
+		engine, _ := c.GetKVEngine(path)
+		logger.Verbose("Could not resolve mount of %s, assuming engine %s: %v", path, engine, err)
+
+		mount = &mountInfo{Path: engine + "/"}
+		if version, err := c.readEngineVersion(engine, logger); err == nil {
+			mount.Version = version
+		}
+	}
+
+	if c.mounts == nil {
+		c.mounts = make(map[string]*mountInfo)
+	}
+	c.mounts[mount.Path] = mount
+
+	logger.Verbose("Path %s belongs to mount %s (KV version %d)", path, mount.Path, mount.Version)
+	return mount
+}
+
+// readMount reads the mount of path from sys/internal/ui/mounts
+func (c *Client) readMount(path string, logger *logger.Logger) (*mountInfo, error) {
+	logger.Verbose("Resolving mount of: %s", path)
+	secret, err := c.client.Logical().Read("sys/internal/ui/mounts/" + path)
+	if err != nil {
+		return nil, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("no mount found for path: %s", path)
+	}
+
+	mountPath, _ := secret.Data["path"].(string)
+	if mountPath == "" {
+		return nil, fmt.Errorf("no mount path returned for path: %s", path)
+	}
+
+	mount := &mountInfo{
+		Path:    strings.TrimPrefix(mountPath, "/"),
+		Version: 1,
+	}
+
+	mountType, _ := secret.Data["type"].(string)
+	options, _ := secret.Data["options"].(map[string]interface{})
+	if version, _ := options["version"].(string); version == "2" && (mountType == "kv" || mountType == "generic") {
+		mount.Version = 2
+	}
+
+	return mount, nil
+}
+
+// apiPath converts a secret path to the API path of a KV endpoint such as "data" or "metadata"
+// and returns it together with the KV version of the mount.
+// For KV v2 mounts the endpoint is inserted after the mount, so both logical paths (kv/apps/x)
+// and data paths (kv/data/apps/x) are accepted. KV v1 paths are returned unchanged.
+// If the mount version is unknown, a /data/ segment in the path marks it as KV v2.
+func (c *Client) apiPath(path, endpoint string, logger *logger.Logger) (string, int) {
+	path = strings.Trim(path, "/")
+	mount := c.lookupMount(path, logger)
+
+	switch mount.Version {
+	case 2:
+		relativePath := strings.TrimSuffix(strings.TrimPrefix(path+"/", mount.Path), "/")
+		if relativePath == "data" {
+			relativePath = ""
+		}
+		relativePath = strings.TrimPrefix(relativePath, "data/")
+		return strings.TrimSuffix(mount.Path+endpoint+"/"+relativePath, "/"), 2
+	case 1:
+		return path, 1
+	default:
+		if strings.Contains(path, "/data/") {
+			return strings.Replace(path, "/data/", "/"+endpoint+"/", 1), 2
+		}
+		return path, 0
+	}
+}
@@ -0,0 +1,194 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// newMountsServer starts a fake Vault that resolves mounts from the given mount paths
+// and KV versions and passes other requests to handler
+func newMountsServer(t *testing.T, mounts map[string]string, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, ok := strings.CutPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/")
+		if !ok {
+			handler(w, r)
+			return
+		}
+
+		for mount, version := range mounts {
+			if strings.HasPrefix(path+"/", mount+"/") {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"data": map[string]interface{}{
+						"path":    mount + "/",
+						"type":    "kv",
+						"options": map[string]string{"version": version},
+					},
+				})
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	apiClient, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatalf("api.NewClient() error = %v", err)
+	}
+	apiClient.SetToken("test-token")
+
+	return &Client{
+		client: apiClient,
+		config: &ClientConfig{Addr: server.URL, Token: "test-token"},
+	}
+}
+
+func TestAPIPath(t *testing.T) {
+	client := newMountsServer(t, map[string]string{
+		"secret":   "2",
+		"teams/kv": "2",
+		"legacy":   "1",
+	}, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		http.NotFound(w, r)
+	})
+	log := logger.NewLogger(&config.Config{})
+
+	tests := []struct {
+		name     string
+		path     string
+		endpoint string
+		want     string
+		version  int
+	}{
+		{"v2 logical path", "secret/apps/x", "data", "secret/data/apps/x", 2},
+		{"v2 data path", "secret/data/apps/x", "data", "secret/data/apps/x", 2},
+		{"v2 data path to metadata", "secret/data/apps/x", "metadata", "secret/metadata/apps/x", 2},
+		{"v2 mount root", "secret", "metadata", "secret/metadata", 2},
+		{"v2 data root", "secret/data", "metadata", "secret/metadata", 2},
+		{"nested v2 mount", "teams/kv/apps/x", "data", "teams/kv/data/apps/x", 2},
+		{"nested v2 mount with data", "teams/kv/data/apps/x", "metadata", "teams/kv/metadata/apps/x", 2},
+		{"v1 path with data segment", "legacy/apps/data/x", "data", "legacy/apps/data/x", 1},
+		{"trailing slash", "secret/apps/", "metadata", "secret/metadata/apps", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, version := client.apiPath(tt.path, tt.endpoint, log)
+			if got != tt.want || version != tt.version {
+				t.Errorf("apiPath(%q, %q) = %q, %d, want %q, %d", tt.path, tt.endpoint, got, version, tt.want, tt.version)
+			}
+		})
+	}
+}
+
+func TestLookupMountFallback(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+
+	client := newMountsServer(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		if r.URL.Path != "/v1/sys/mounts/kv" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"type":"kv","options":{"version":"2"}}}`))
+	})
+	log := logger.NewLogger(&config.Config{})
+
+	for i := 0; i < 2; i++ {
+		got, version := client.apiPath("kv/apps/x", "metadata", log)
+		if got != "kv/metadata/apps/x" || version != 2 {
+			t.Errorf("apiPath() = %q, %d, want %q, 2", got, version, "kv/metadata/apps/x")
+		}
+	}
+
+	version, err := client.GetKVEngineVersion("kv", log)
+	if err != nil || version != 2 {
+		t.Errorf("GetKVEngineVersion() = %d, %v, want 2", version, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests["/v1/sys/mounts/kv"] != 1 {
+		t.Errorf("sys/mounts/kv requested %d times, want 1 (cached)", requests["/v1/sys/mounts/kv"])
+	}
+}
+
+func TestLogicalPaths(t *testing.T) {
+	var mu sync.Mutex
+	var written map[string]interface{}
+
+	client := newMountsServer(t, map[string]string{
+		"teams/kv": "2",
+		"legacy":   "1",
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.URL.Path == "/v1/teams/kv/metadata/apps" && r.URL.Query().Get("list") == "true":
+			w.Write([]byte(`{"data":{"keys":["x","sub/"]}}`))
+		case r.URL.Path == "/v1/teams/kv/data/apps/x" && r.Method == http.MethodGet:
+			w.Write([]byte(`{"data":{"data":{"key":"value"},"metadata":{"version":3}}}`))
+		case r.URL.Path == "/v1/legacy/apps/data/x" && r.Method == http.MethodGet:
+			w.Write([]byte(`{"data":{"data":{"nested":"map"}}}`))
+		case r.URL.Path == "/v1/teams/kv/data/backup/x" && r.Method == http.MethodPut:
+			mu.Lock()
+			json.NewDecoder(r.Body).Decode(&written)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	})
+	log := logger.NewLogger(&config.Config{})
+
+	keys, err := client.ListSecrets("teams/kv/apps", log)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("ListSecrets() = %v, %v, want 2 keys", keys, err)
+	}
+
+	secret, err := client.ReadSecret("teams/kv/apps/x", log)
+	if err != nil {
+		t.Fatalf("ReadSecret() error = %v", err)
+	}
+	if secret.Path != "teams/kv/apps/x" || secret.Data["key"] != "value" {
+		t.Errorf("ReadSecret() = %+v, want logical path and unwrapped data", secret)
+	}
+
+	// A KV v1 secret that has a "data" key is not unwrapped
+	v1Secret, err := client.ReadSecret("legacy/apps/data/x", log)
+	if err != nil {
+		t.Fatalf("ReadSecret() v1 error = %v", err)
+	}
+	if _, ok := v1Secret.Data["data"]; !ok {
+		t.Errorf("ReadSecret() v1 data = %v, want the data key kept", v1Secret.Data)
+	}
+
+	if err := client.WriteSecret("teams/kv/backup/x", secret.Data, log); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if data, ok := written["data"].(map[string]interface{}); !ok || data["key"] != "value" {
+		t.Errorf("written body = %v, want data wrapped for KV v2", written)
+	}
+}
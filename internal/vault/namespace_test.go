@@ -0,0 +1,61 @@
+package vault
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+)
+
+func TestNewClientWithConfigNamespace(t *testing.T) {
+	var mu sync.Mutex
+	namespaces := map[string]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		namespaces[r.URL.Path] = r.Header.Get("X-Vault-Namespace")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/v1/sys/health":
+			w.Write([]byte(`{"initialized":true,"sealed":false,"standby":false}`))
+		case "/v1/auth/approle/login":
+			w.Write([]byte(`{"auth":{"client_token":"issued-token"}}`))
+		case "/v1/secret/metadata/apps":
+			w.Write([]byte(`{"data":{"keys":["app1"]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClientWithConfig(&ClientConfig{
+		Addr:      server.URL,
+		Namespace: "team-b/prod",
+		Auth: config.AuthConfig{
+			Method:    config.AuthMethodAppRole,
+			MountPath: "approle",
+			RoleID:    "role",
+			SecretID:  "secret",
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithConfig() error = %v", err)
+	}
+
+	if _, err := client.ListSecrets("secret/data/apps", logger.NewLogger(&config.Config{})); err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range []string{"/v1/auth/approle/login", "/v1/secret/metadata/apps"} {
+		if namespaces[path] != "team-b/prod" {
+			t.Errorf("namespace header for %s = %q, want %q", path, namespaces[path], "team-b/prod")
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package vault
+
+import (
+	"net/http"
+	"time"
+)
+
+// observedTransport reports the latency and status of every request to the client's observer
+type observedTransport struct {
+	base   http.RoundTripper
+	client *Client
+}
+
+// RoundTrip sends the request with the wrapped transport and reports its outcome
+func (t *observedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	t.client.observeRequest(time.Since(start), status)
+
+	return resp, err
+}
+
+// SetRequestObserver makes the client report every request it sends to observer
+func (c *Client) SetRequestObserver(observer RequestObserver) {
+	c.observerMu.Lock()
+	defer c.observerMu.Unlock()
+	c.observer = observer
+}
+
+// observeRequest passes the outcome of a request to the observer, if there is one
+func (c *Client) observeRequest(latency time.Duration, status int) {
+	c.observerMu.RLock()
+	observer := c.observer
+	c.observerMu.RUnlock()
+
+	if observer != nil {
+		observer.ObserveRequest(latency, status)
+	}
+}
@@ -0,0 +1,108 @@
+package vault
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+)
+
+// recordingObserver records the statuses of the observed requests
+type recordingObserver struct {
+	mu       sync.Mutex
+	statuses []int
+}
+
+func (o *recordingObserver) ObserveRequest(latency time.Duration, status int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.statuses = append(o.statuses, status)
+}
+
+// newRateServer returns a client for a server where secret/data/app exists and secret/data/busy is rate limited
+func newRateServer(t *testing.T, rate float64) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/v1/sys/health":
+			w.Write([]byte(`{"initialized":true,"sealed":false,"standby":false}`))
+		case "/v1/secret/data/app":
+			w.Write([]byte(`{"data":{"data":{"key":"value"}}}`))
+		case "/v1/secret/data/busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"errors":["request path \"secret/data/busy\": rate limit quota exceeded"]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClientWithConfig(&ClientConfig{
+		Addr:  server.URL,
+		Token: "token",
+		Auth:  config.AuthConfig{Method: config.AuthMethodToken},
+		Retry: config.RetryConfig{MaxAttempts: 1},
+		Rate:  rate,
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithConfig() error = %v", err)
+	}
+
+	return client
+}
+
+func TestSetRequestObserver(t *testing.T) {
+	client := newRateServer(t, 0)
+	log := logger.NewLogger(&config.Config{})
+
+	observer := &recordingObserver{}
+	client.SetRequestObserver(observer)
+
+	if _, err := client.ReadSecret("secret/data/app", log); err != nil {
+		t.Fatalf("ReadSecret() error = %v", err)
+	}
+	if _, err := client.ReadSecret("secret/data/busy", log); err == nil {
+		t.Fatal("ReadSecret() expected error for a rate limited path, got nil")
+	}
+
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+
+	var ok, throttled int
+	for _, status := range observer.statuses {
+		switch status {
+		case http.StatusOK:
+			ok++
+		case http.StatusTooManyRequests:
+			throttled++
+		}
+	}
+	if ok == 0 || throttled != 1 {
+		t.Errorf("observed statuses = %v, want at least one 200 and exactly one 429", observer.statuses)
+	}
+}
+
+func TestClientRate(t *testing.T) {
+	// 10 requests per second with a burst of 10; the health check already took one token
+	client := newRateServer(t, 10)
+	log := logger.NewLogger(&config.Config{})
+
+	start := time.Now()
+	for i := 0; i < 15; i++ {
+		if _, err := client.ReadSecret("secret/data/app", log); err != nil {
+			t.Fatalf("ReadSecret() error = %v", err)
+		}
+	}
+
+	// The reads and the mount lookup need about 8 tokens more than the burst holds
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("15 reads at 10 requests/s took %v, want at least 500ms", elapsed)
+	}
+}
@@ -2,9 +2,9 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
-	"sync"
 	"vault-copy/internal/logger"
 )
 
@@ -16,13 +16,23 @@ type Secret struct {
 	Data map[string]interface{}
 	// Metadata contains the secret's metadata
 	Metadata map[string]interface{}
+	// Deleted indicates a KV v2 secret whose latest version is deleted, it has no Data.
+	// Walks only send such secrets when asked to, see DeletedSender.
+	Deleted bool
 }
 
+// ErrSecretDeleted is returned by ReadSecret for a KV v2 secret whose latest version is deleted or destroyed.
+// Walks skip such secrets and count them, see DeletedCounter, unless they are asked to send them, see DeletedSender.
+var ErrSecretDeleted = errors.New("latest version of the secret is deleted")
+
 // ReadSecret reads a secret from Vault at the specified path.
 // It handles both KV v1 and KV v2 secrets and returns the secret data and metadata.
+// The returned secret keeps the path as given, the KV v2 API path is only used for the request.
 func (c *Client) ReadSecret(path string, logger *logger.Logger) (*Secret, error) {
-	logger.Verbose("Reading secret from Vault: %s", path)
-	secret, err := c.client.Logical().Read(path)
+	readPath, version := c.apiPath(path, "data", logger)
+
+	logger.Verbose("Reading secret from Vault: %s", readPath)
+	secret, err := c.client.Logical().Read(readPath)
 	if err != nil {
 		logger.Error("Error reading secret %s: %v", path, err)
 		return nil, err
@@ -34,11 +44,16 @@ func (c *Client) ReadSecret(path string, logger *logger.Logger) (*Secret, error)
 
 	// For KV v2, data is in secret.Data["data"]
 	data, ok := secret.Data["data"].(map[string]interface{})
-	if !ok {
+	if version == 1 || (!ok && version != 2) {
 		data = secret.Data // For KV v1 or other engines
+	} else if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSecretDeleted, path)
 	}
 
-	metadata, _ := secret.Data["metadata"].(map[string]interface{})
+	var metadata map[string]interface{}
+	if version != 1 {
+		metadata, _ = secret.Data["metadata"].(map[string]interface{})
+	}
 
 	return &Secret{
 		Path:     path,
@@ -52,9 +67,9 @@ func (c *Client) ReadSecret(path string, logger *logger.Logger) (*Secret, error)
 func (c *Client) IsDirectory(path string, logger *logger.Logger) (bool, error) {
 	logger.Verbose("Checking if path is a directory: %s", path)
 	// Try to get listing
-	listPath := strings.Replace(path, "/data/", "/metadata/", 1)
-	if !strings.Contains(listPath, "/metadata/") {
-		listPath = path + "/"
+	listPath, version := c.apiPath(path, "metadata", logger)
+	if version != 2 {
+		listPath += "/"
 	}
 
 	logger.Verbose("Getting list from: %s", listPath)
@@ -81,9 +96,9 @@ func (c *Client) IsDirectory(path string, logger *logger.Logger) (bool, error) {
 func (c *Client) ListSecrets(path string, logger *logger.Logger) ([]string, error) {
 	logger.Verbose("Getting list of secrets from: %s", path)
 	// For KV v2, use metadata endpoint for listing
-	listPath := strings.Replace(path, "/data/", "/metadata/", 1)
-	if !strings.Contains(listPath, "/metadata/") {
-		listPath = path + "/"
+	listPath, version := c.apiPath(path, "metadata", logger)
+	if version != 2 {
+		listPath += "/"
 	}
 
 	logger.Verbose("Requesting list from: %s", listPath)
@@ -117,7 +132,8 @@ func (c *Client) ListSecrets(path string, logger *logger.Logger) ([]string, erro
 
 // GetAllSecrets recursively retrieves all secrets under the given root path.
 // It returns two channels: one for secrets and one for errors.
-// The caller must read from both channels until they are closed.
+// Folders and secrets below rootPath that can't be read are reported as *PathError while the walk goes on.
+// The caller must read from both channels until they are closed, or cancel ctx when it stops reading.
 func (c *Client) GetAllSecrets(ctx context.Context, rootPath string, logger *logger.Logger) (<-chan *Secret, <-chan error) {
 	secretsChan := make(chan *Secret, 100)
 	errChan := make(chan error, 1)
@@ -132,53 +148,6 @@ func (c *Client) GetAllSecrets(ctx context.Context, rootPath string, logger *log
 	return secretsChan, errChan
 }
 
-// walkSecrets recursively walks the Vault hierarchy and sends secrets to the secrets channel.
-// It sends errors to the error channel. Both channels are closed when the walk is complete.
-func (c *Client) walkSecrets(ctx context.Context, path string, secretsChan chan<- *Secret, errChan chan<- error, logger *logger.Logger) {
-	select {
-	case <-ctx.Done():
-		errChan <- ctx.Err()
-		return
-	default:
-	}
-
-	isDir, err := c.IsDirectory(path, logger)
-	if err != nil {
-		errChan <- err
-		return
-	}
-
-	if isDir {
-		items, err := c.ListSecrets(path, logger)
-		if err != nil {
-			errChan <- err
-			return
-		}
-
-		var wg sync.WaitGroup
-		for _, item := range items {
-			wg.Add(1)
-			go func(itemPath string) {
-				defer wg.Done()
-				c.walkSecrets(ctx, itemPath, secretsChan, errChan, logger)
-			}(BuildPath(path, item))
-		}
-		wg.Wait()
-	} else {
-		secret, err := c.ReadSecret(path, logger)
-		if err != nil {
-			errChan <- err
-			return
-		}
-
-		select {
-		case secretsChan <- secret:
-		case <-ctx.Done():
-			errChan <- ctx.Err()
-		}
-	}
-}
-
 // buildPath constructs a full path from a base path and an item name.
 // It ensures proper path separators are used.
 func BuildPath(base, item string) string {
@@ -0,0 +1,123 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"vault-copy/internal/logger"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// StartTokenRenewal keeps the client token valid until ctx is cancelled.
+// Renewable tokens are renewed by a lifetime watcher; when a token can no longer
+// be renewed the client logs in again if its auth method allows it.
+// An error is sent on the returned channel if the token is about to expire and
+// can't be replaced. The channel is closed when renewal stops.
+func (c *Client) StartTokenRenewal(ctx context.Context, logger *logger.Logger) <-chan error {
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(errChan)
+
+		if err := c.renewToken(ctx, logger); err != nil {
+			errChan <- err
+		}
+	}()
+
+	return errChan
+}
+
+// renewToken runs lifetime watchers for the current token until ctx is cancelled
+// or the token can't be renewed or replaced any more.
+func (c *Client) renewToken(ctx context.Context, logger *logger.Logger) error {
+	for {
+		tokenSecret, err := c.lookupToken()
+		if err != nil {
+			return err
+		}
+
+		if tokenSecret.Auth.LeaseDuration == 0 {
+			logger.Verbose("Token for %s has no TTL, renewal is not needed", c.config.Addr)
+			return nil
+		}
+
+		watcher, err := c.client.NewLifetimeWatcher(&api.LifetimeWatcherInput{
+			Secret: tokenSecret,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating token lifetime watcher: %v", err)
+		}
+
+		logger.Verbose("Watching token for %s (ttl: %ds, renewable: %t)",
+			c.config.Addr, tokenSecret.Auth.LeaseDuration, tokenSecret.Auth.Renewable)
+
+		go watcher.Start()
+
+		done, err := c.watchToken(ctx, watcher, logger)
+		watcher.Stop()
+		if done {
+			return nil
+		}
+
+		// The token reached the end of its lifetime, replace it if possible
+		if !c.canRelogin() {
+			if err != nil {
+				return fmt.Errorf("token for %s can no longer be renewed: %v", c.config.Addr, err)
+			}
+			return fmt.Errorf("token for %s is about to expire and cannot be renewed", c.config.Addr)
+		}
+
+		logger.Info("Token for %s can no longer be renewed, logging in again via %s", c.config.Addr, c.config.Auth.Method)
+		if err := c.login(); err != nil {
+			return fmt.Errorf("error logging in again to %s: %v", c.config.Addr, err)
+		}
+	}
+}
+
+// watchToken waits for the lifetime watcher to finish. It returns true when ctx was cancelled,
+// otherwise false together with the error the watcher stopped with, if any.
+func (c *Client) watchToken(ctx context.Context, watcher *api.LifetimeWatcher, logger *logger.Logger) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return true, nil
+		case err := <-watcher.DoneCh():
+			return false, err
+		case renewal := <-watcher.RenewCh():
+			if renewal.Secret != nil && renewal.Secret.Auth != nil {
+				logger.Verbose("Renewed token for %s, new ttl: %ds", c.config.Addr, renewal.Secret.Auth.LeaseDuration)
+			}
+		}
+	}
+}
+
+// lookupToken reads the current token via auth/token/lookup-self and returns it
+// in the form expected by the lifetime watcher.
+func (c *Client) lookupToken() (*api.Secret, error) {
+	secret, err := c.client.Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, fmt.Errorf("error looking up token for %s: %v", c.config.Addr, err)
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("no token information returned for %s", c.config.Addr)
+	}
+
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		return nil, fmt.Errorf("error reading token TTL for %s: %v", c.config.Addr, err)
+	}
+
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		return nil, fmt.Errorf("error reading token renewability for %s: %v", c.config.Addr, err)
+	}
+
+	return &api.Secret{
+		Auth: &api.SecretAuth{
+			ClientToken:   c.client.Token(),
+			Renewable:     renewable,
+			LeaseDuration: int(ttl / time.Second),
+		},
+	}, nil
+}
@@ -0,0 +1,169 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// newTokenClient returns a client talking to a fake Vault whose lookup-self
+// reports the given token TTL and renewability.
+func newTokenClient(t *testing.T, ttl int, renewable bool) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/lookup-self" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data":{"ttl":%d,"renewable":%t}}`, ttl, renewable)
+	}))
+	t.Cleanup(server.Close)
+
+	apiClient, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatalf("api.NewClient() error = %v", err)
+	}
+	apiClient.SetToken("test-token")
+
+	return &Client{
+		client: apiClient,
+		config: &ClientConfig{
+			Addr:  server.URL,
+			Token: "test-token",
+			Auth:  config.AuthConfig{Method: config.AuthMethodToken},
+		},
+	}
+}
+
+func TestStartTokenRenewalNoTTL(t *testing.T) {
+	client := newTokenClient(t, 0, false)
+
+	errChan := client.StartTokenRenewal(context.Background(), logger.NewLogger(&config.Config{}))
+
+	select {
+	case err, ok := <-errChan:
+		if ok && err != nil {
+			t.Errorf("StartTokenRenewal() error = %v, want none for token without TTL", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("StartTokenRenewal() did not stop for token without TTL")
+	}
+}
+
+func TestStartTokenRenewalExpiringToken(t *testing.T) {
+	client := newTokenClient(t, 1, false)
+
+	errChan := client.StartTokenRenewal(context.Background(), logger.NewLogger(&config.Config{}))
+
+	select {
+	case err := <-errChan:
+		if err == nil {
+			t.Error("StartTokenRenewal() expected error for expiring non-renewable token, got nil")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("StartTokenRenewal() did not report expiring token")
+	}
+}
+
+func TestStartTokenRenewalCancelled(t *testing.T) {
+	client := newTokenClient(t, 3600, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := client.StartTokenRenewal(ctx, logger.NewLogger(&config.Config{}))
+	cancel()
+
+	select {
+	case err, ok := <-errChan:
+		if ok && err != nil {
+			t.Errorf("StartTokenRenewal() error = %v, want none after cancellation", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("StartTokenRenewal() did not stop after cancellation")
+	}
+}
+
+func TestStartTokenRenewalRelogin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			// The initial token is about to expire, the one issued at login has no TTL
+			if r.Header.Get("X-Vault-Token") == "issued-token" {
+				w.Write([]byte(`{"data":{"ttl":0,"renewable":false}}`))
+				return
+			}
+			w.Write([]byte(`{"data":{"ttl":1,"renewable":false}}`))
+		case "/v1/auth/approle/login":
+			w.Write([]byte(`{"auth":{"client_token":"issued-token","renewable":true,"lease_duration":60}}`))
+		case "/v1/sys/internal/ui/mounts/secret/data/app":
+			w.Write([]byte(`{"data":{"path":"secret/","type":"kv","options":{"version":"2"}}}`))
+		case "/v1/secret/data/app":
+			w.Write([]byte(`{"data":{"data":{"key":"value"}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	apiClient, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatalf("api.NewClient() error = %v", err)
+	}
+	apiClient.SetToken("test-token")
+
+	client := &Client{
+		client: apiClient,
+		config: &ClientConfig{
+			Addr:  server.URL,
+			Token: "test-token",
+			Auth:  config.AuthConfig{Method: config.AuthMethodAppRole, MountPath: "approle", RoleID: "role", SecretID: "secret"},
+		},
+	}
+	log := logger.NewLogger(&config.Config{})
+
+	// Workers keep reading while the renewal goroutine logs in again
+	done := make(chan struct{})
+	workers := make(chan struct{})
+	go func() {
+		defer close(workers)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if _, err := client.ReadSecret("secret/data/app", log); err != nil {
+				t.Errorf("ReadSecret() error = %v", err)
+				return
+			}
+		}
+	}()
+
+	select {
+	case err, ok := <-client.StartTokenRenewal(context.Background(), log):
+		if ok && err != nil {
+			t.Errorf("StartTokenRenewal() error = %v, want a re-login", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("StartTokenRenewal() did not log in again")
+	}
+	close(done)
+	<-workers
+
+	if client.client.Token() != "issued-token" {
+		t.Errorf("client token = %v, want issued-token", client.client.Token())
+	}
+	if client.config.Token != "test-token" {
+		t.Errorf("config token = %v, want it unchanged", client.config.Token)
+	}
+}
@@ -0,0 +1,90 @@
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"vault-copy/internal/config"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// retryPolicy decides which failed requests are retried and how long to wait in between.
+// It plugs into the retrying HTTP client of the Vault API and counts the retries it schedules.
+type retryPolicy struct {
+	config  config.RetryConfig
+	retries func()
+}
+
+// checkRetry reports whether a request is retried after the given response or error
+func (p *retryPolicy) checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	// Never retry once the run is cancelled
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	if err != nil {
+		if !p.config.RetriesNetworkErrors() {
+			return false, nil
+		}
+		// A certificate that failed verification won't pass on the next attempt either
+		var certErr *tls.CertificateVerificationError
+		if errors.As(err, &certErr) {
+			return false, nil
+		}
+		// The default policy filters out errors a retry can't fix, such as invalid certificates
+		return retryablehttp.DefaultRetryPolicy(ctx, nil, err)
+	}
+
+	return p.config.RetriesStatus(resp.StatusCode), nil
+}
+
+// backoff returns the delay before the retry following attempt number attempt (counted from 0).
+// It is only called when a retry is about to happen, so this is where retries are counted.
+func (p *retryPolicy) backoff(_, _ time.Duration, attempt int, resp *http.Response) time.Duration {
+	p.retries()
+
+	if delay, ok := retryAfter(resp); ok {
+		return min(delay, p.config.MaxDelay)
+	}
+
+	delay := p.config.MaxDelay
+	if attempt < 32 {
+		delay = min(p.config.BaseDelay<<attempt, p.config.MaxDelay)
+	}
+
+	// Randomise the jittered part of the delay so parallel workers don't retry in lockstep
+	jitter := time.Duration(float64(delay) * p.config.Jitter)
+	if jitter > 0 {
+		delay = delay - jitter + time.Duration(rand.Int63n(int64(jitter)+1))
+	}
+
+	return delay
+}
+
+// retryAfter returns the delay requested by the Retry-After header of a 429 or 503 response
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
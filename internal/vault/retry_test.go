@@ -0,0 +1,256 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+)
+
+// newFlakyServer returns a client whose reads of secret/data/app fail with status
+// for the first failures requests and succeed afterwards
+func newFlakyServer(t *testing.T, status, failures int, header http.Header, retry config.RetryConfig) (*Client, *int64) {
+	t.Helper()
+
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/v1/sys/health":
+			w.Write([]byte(`{"initialized":true,"sealed":false,"standby":false}`))
+		case "/v1/secret/data/app":
+			if atomic.AddInt64(&requests, 1) <= int64(failures) {
+				for key, values := range header {
+					w.Header()[key] = values
+				}
+				w.WriteHeader(status)
+				w.Write([]byte(`{"errors":["transient"]}`))
+				return
+			}
+			w.Write([]byte(`{"data":{"data":{"key":"value"}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClientWithConfig(&ClientConfig{
+		Addr:  server.URL,
+		Token: "token",
+		Auth:  config.AuthConfig{Method: config.AuthMethodToken},
+		Retry: retry,
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithConfig() error = %v", err)
+	}
+
+	return client, &requests
+}
+
+func TestReadSecretRetries(t *testing.T) {
+	retry := config.RetryConfig{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+		RetryOn:     []string{"429", config.RetryOnServerErrors, config.RetryOnNetwork},
+	}
+
+	tests := []struct {
+		name         string
+		status       int
+		failures     int
+		header       http.Header
+		retry        config.RetryConfig
+		wantErr      bool
+		wantRetries  int64
+		wantRequests int64
+	}{
+		{
+			name:         "recovers from 503",
+			status:       http.StatusServiceUnavailable,
+			failures:     2,
+			retry:        retry,
+			wantRetries:  2,
+			wantRequests: 3,
+		},
+		{
+			name:         "recovers from 429 with Retry-After",
+			status:       http.StatusTooManyRequests,
+			failures:     1,
+			header:       http.Header{"Retry-After": []string{"0"}},
+			retry:        retry,
+			wantRetries:  1,
+			wantRequests: 2,
+		},
+		{
+			name:         "gives up after max attempts",
+			status:       http.StatusInternalServerError,
+			failures:     5,
+			retry:        retry,
+			wantErr:      true,
+			wantRetries:  2,
+			wantRequests: 3,
+		},
+		{
+			name:         "501 is not retried",
+			status:       http.StatusNotImplemented,
+			failures:     1,
+			retry:        retry,
+			wantErr:      true,
+			wantRequests: 1,
+		},
+		{
+			name:     "status outside retry classes is not retried",
+			status:   http.StatusServiceUnavailable,
+			failures: 1,
+			retry: config.RetryConfig{
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+				MaxDelay:    time.Millisecond,
+				RetryOn:     []string{"429"},
+			},
+			wantErr:      true,
+			wantRequests: 1,
+		},
+		{
+			name:     "one attempt disables retries",
+			status:   http.StatusServiceUnavailable,
+			failures: 1,
+			retry: config.RetryConfig{
+				MaxAttempts: 1,
+				RetryOn:     []string{config.RetryOnServerErrors},
+			},
+			wantErr:      true,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := newFlakyServer(t, tt.status, tt.failures, tt.header, tt.retry)
+
+			secret, err := client.ReadSecret("secret/data/app", logger.NewLogger(&config.Config{}))
+			if tt.wantErr && err == nil {
+				t.Errorf("ReadSecret() expected error, got nil")
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("ReadSecret() unexpected error = %v", err)
+				}
+				if secret.Data["key"] != "value" {
+					t.Errorf("ReadSecret() data = %v, want key=value", secret.Data)
+				}
+			}
+
+			if got := client.Retries(); got != tt.wantRetries {
+				t.Errorf("Retries() = %d, want %d", got, tt.wantRetries)
+			}
+			if got := atomic.LoadInt64(requests); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &retryPolicy{
+		config: config.RetryConfig{
+			BaseDelay: 10 * time.Millisecond,
+			MaxDelay:  50 * time.Millisecond,
+		},
+		retries: func() {},
+	}
+
+	want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond}
+	for attempt, expected := range want {
+		if got := policy.backoff(0, 0, attempt, nil); got != expected {
+			t.Errorf("backoff(attempt %d) = %v, want %v", attempt, got, expected)
+		}
+	}
+
+	// Large attempt numbers must not overflow the shift
+	if got := policy.backoff(0, 0, 100, nil); got != 50*time.Millisecond {
+		t.Errorf("backoff(attempt 100) = %v, want %v", got, 50*time.Millisecond)
+	}
+}
+
+func TestRetryPolicyBackoffJitter(t *testing.T) {
+	policy := &retryPolicy{
+		config: config.RetryConfig{
+			BaseDelay: 100 * time.Millisecond,
+			MaxDelay:  time.Second,
+			Jitter:    0.5,
+		},
+		retries: func() {},
+	}
+
+	for i := 0; i < 100; i++ {
+		got := policy.backoff(0, 0, 0, nil)
+		if got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Fatalf("backoff() = %v, want between 50ms and 100ms", got)
+		}
+	}
+}
+
+func TestRetryPolicyRetryAfter(t *testing.T) {
+	policy := &retryPolicy{
+		config: config.RetryConfig{
+			BaseDelay: time.Millisecond,
+			MaxDelay:  10 * time.Second,
+		},
+		retries: func() {},
+	}
+
+	response := func(status int, retryAfter string) *http.Response {
+		return &http.Response{StatusCode: status, Header: http.Header{"Retry-After": []string{retryAfter}}}
+	}
+
+	tests := []struct {
+		name string
+		resp *http.Response
+		want time.Duration
+	}{
+		{"seconds on 429", response(http.StatusTooManyRequests, "3"), 3 * time.Second},
+		{"capped at max delay", response(http.StatusTooManyRequests, "120"), 10 * time.Second},
+		{"ignored on 500", response(http.StatusInternalServerError, "3"), time.Millisecond},
+		{"invalid value", response(http.StatusTooManyRequests, "soon"), time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.backoff(0, 0, 0, tt.resp); got != tt.want {
+				t.Errorf("backoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyCheckRetry(t *testing.T) {
+	reset := &url.Error{Op: "Get", URL: "http://vault:8200", Err: syscall.ECONNRESET}
+
+	withNetwork := &retryPolicy{config: config.RetryConfig{RetryOn: []string{config.RetryOnNetwork}}}
+	if retry, _ := withNetwork.checkRetry(context.Background(), nil, reset); !retry {
+		t.Error("checkRetry() = false for a connection reset, want true")
+	}
+
+	withoutNetwork := &retryPolicy{config: config.RetryConfig{RetryOn: []string{"503"}}}
+	if retry, _ := withoutNetwork.checkRetry(context.Background(), nil, reset); retry {
+		t.Error("checkRetry() = true for a connection reset without network retries, want false")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	retry, err := withNetwork.checkRetry(ctx, nil, reset)
+	if retry || !errors.Is(err, context.Canceled) {
+		t.Errorf("checkRetry() on a cancelled context = %v, %v, want false, context.Canceled", retry, err)
+	}
+}
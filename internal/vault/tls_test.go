@@ -0,0 +1,77 @@
+package vault
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"vault-copy/internal/config"
+)
+
+func newTLSHealthServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"initialized":true,"sealed":false,"standby":false}`))
+	}))
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	return server, caFile
+}
+
+func TestNewClientWithConfigTLS(t *testing.T) {
+	server, caFile := newTLSHealthServer(t)
+
+	tests := []struct {
+		name    string
+		tls     config.TLSConfig
+		wantErr bool
+	}{
+		{
+			name:    "unknown certificate authority",
+			tls:     config.TLSConfig{},
+			wantErr: true,
+		},
+		{
+			name:    "custom CA bundle",
+			tls:     config.TLSConfig{CACert: caFile},
+			wantErr: false,
+		},
+		{
+			name:    "skip verification",
+			tls:     config.TLSConfig{Insecure: true},
+			wantErr: false,
+		},
+		{
+			name:    "missing CA bundle",
+			tls:     config.TLSConfig{CACert: filepath.Join(t.TempDir(), "missing.pem")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClientWithConfig(&ClientConfig{
+				Addr:  server.URL,
+				Token: "test-token",
+				TLS:   tt.tls,
+			})
+			if tt.wantErr && err == nil {
+				t.Error("NewClientWithConfig() expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("NewClientWithConfig() unexpected error = %v", err)
+			}
+		})
+	}
+}
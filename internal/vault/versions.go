@@ -0,0 +1,218 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+	"vault-copy/internal/logger"
+)
+
+// SecretVersion is a single version of a KV v2 secret.
+type SecretVersion struct {
+	// Version is the version number in the source
+	Version int
+	// Data contains the version's key-value pairs, nil for deleted and destroyed versions
+	Data map[string]interface{}
+	// Deleted indicates that the version is soft-deleted
+	Deleted bool
+	// Destroyed indicates that the version's data was permanently destroyed
+	Destroyed bool
+}
+
+// ReadSecretVersions reads every version of a KV v2 secret from its metadata endpoint.
+// Versions are returned in ascending order. The data of soft-deleted and destroyed
+// versions can't be read and is left empty. A version whose deletion_time lies in the
+// future, as set by delete_version_after, is still live.
+// KV v1 keeps no history, so the current value is returned as the only version.
+func (c *Client) ReadSecretVersions(path string, logger *logger.Logger) ([]*SecretVersion, error) {
+	metadataPath, version := c.apiPath(path, "metadata", logger)
+	if version != 2 {
+		logger.Verbose("Secret %s is not in a KV v2 mount, reading current value as version 1", path)
+		secret, err := c.ReadSecret(path, logger)
+		if err != nil {
+			return nil, err
+		}
+		return []*SecretVersion{{Version: 1, Data: secret.Data}}, nil
+	}
+	dataPath, _ := c.apiPath(path, "data", logger)
+
+	logger.Verbose("Reading secret versions from: %s", metadataPath)
+	metadata, err := c.client.Logical().Read(metadataPath)
+	if err != nil {
+		logger.Error("Error reading secret metadata %s: %v", metadataPath, err)
+		return nil, err
+	}
+
+	if metadata == nil || metadata.Data == nil {
+		return nil, fmt.Errorf("secret metadata not found: %s", metadataPath)
+	}
+
+	rawVersions, ok := metadata.Data["versions"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no versions in metadata: %s", metadataPath)
+	}
+
+	now := time.Now()
+	var versions []*SecretVersion
+	for key, raw := range rawVersions {
+		number, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q in metadata %s", key, metadataPath)
+		}
+
+		info, _ := raw.(map[string]interface{})
+		deletionTime, _ := info["deletion_time"].(string)
+		destroyed, _ := info["destroyed"].(bool)
+
+		deleted, err := isDeleted(deletionTime, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deletion_time of version %d in metadata %s: %v", number, metadataPath, err)
+		}
+
+		versions = append(versions, &SecretVersion{
+			Version:   number,
+			Deleted:   deleted,
+			Destroyed: destroyed,
+		})
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+
+	for _, version := range versions {
+		if version.Deleted || version.Destroyed {
+			continue
+		}
+
+		logger.Verbose("Reading version %d of secret: %s", version.Version, path)
+		secret, err := c.client.Logical().ReadWithData(dataPath, map[string][]string{
+			"version": {strconv.Itoa(version.Version)},
+		})
+		if err != nil {
+			logger.Error("Error reading version %d of secret %s: %v", version.Version, path, err)
+			return nil, err
+		}
+
+		if secret == nil {
+			return nil, fmt.Errorf("version %d of secret not found: %s", version.Version, path)
+		}
+
+		data, _ := secret.Data["data"].(map[string]interface{})
+		version.Data = data
+	}
+
+	logger.Verbose("Found %d versions of secret: %s", len(versions), path)
+	return versions, nil
+}
+
+// WriteSecretVersions replays versions of a KV v2 secret at path in the given order.
+// Destroyed versions are skipped. Soft-deleted versions are written with empty data
+// and deleted right away so that the destination history records them as deleted.
+// A KV v1 destination keeps no history, so only the latest version is written.
+// It returns the number of versions written.
+func (c *Client) WriteSecretVersions(path string, versions []*SecretVersion, logger *logger.Logger) (int, error) {
+	deletePath, version := c.apiPath(path, "delete", logger)
+	if version != 2 {
+		return c.writeLatestVersion(path, versions, logger)
+	}
+	dataPath, _ := c.apiPath(path, "data", logger)
+
+	written := 0
+	for _, version := range versions {
+		if version.Destroyed {
+			logger.Verbose("Skipping destroyed version %d of secret: %s", version.Version, path)
+			continue
+		}
+
+		data := version.Data
+		if version.Deleted || data == nil {
+			data = map[string]interface{}{}
+		}
+
+		logger.Verbose("Writing version %d of secret: %s", version.Version, path)
+		secret, err := c.writeData(path, dataPath, data, logger)
+		if err != nil {
+			logger.Error("Error writing version %d of secret %s: %v", version.Version, path, err)
+			return written, fmt.Errorf("error writing version %d of secret %s: %v", version.Version, path, err)
+		}
+		written++
+
+		if !version.Deleted {
+			continue
+		}
+
+		if secret == nil || secret.Data == nil {
+			return written, fmt.Errorf("no version returned when writing deleted version %d of %s", version.Version, path)
+		}
+
+		newVersion, err := versionNumber(secret.Data["version"])
+		if err != nil {
+			return written, fmt.Errorf("invalid version returned when writing %s: %v", path, err)
+		}
+
+		logger.Verbose("Deleting version %d of secret %s (source version %d)", newVersion, path, version.Version)
+		_, err = c.client.Logical().Write(deletePath, map[string]interface{}{
+			"versions": []int{newVersion},
+		})
+		if err != nil {
+			logger.Error("Error deleting version %d of secret %s: %v", newVersion, path, err)
+			return written, fmt.Errorf("error deleting version %d of secret %s: %v", newVersion, path, err)
+		}
+	}
+
+	logger.Verbose("Wrote %d versions of secret: %s", written, path)
+	return written, nil
+}
+
+// writeLatestVersion writes the latest version to a KV v1 path, dropping the history.
+// Nothing is written when the latest version is deleted or destroyed, since the secret has no current value.
+func (c *Client) writeLatestVersion(path string, versions []*SecretVersion, logger *logger.Logger) (int, error) {
+	if len(versions) == 0 {
+		return 0, nil
+	}
+
+	latest := versions[len(versions)-1]
+	if latest.Deleted || latest.Destroyed {
+		logger.Verbose("Latest version %d of secret %s is deleted, nothing to write to KV v1", latest.Version, path)
+		return 0, nil
+	}
+
+	logger.Verbose("Secret %s is not in a KV v2 mount, writing only version %d of %d", path, latest.Version, len(versions))
+	if err := c.WriteSecret(path, latest.Data, logger); err != nil {
+		return 0, err
+	}
+
+	return 1, nil
+}
+
+// isDeleted reports whether a version with the given deletion_time is deleted at now.
+// An empty deletion_time means the version is live, a future one that it is only scheduled for deletion.
+func isDeleted(deletionTime string, now time.Time) (bool, error) {
+	if deletionTime == "" {
+		return false, nil
+	}
+
+	deleteAt, err := time.Parse(time.RFC3339Nano, deletionTime)
+	if err != nil {
+		return false, err
+	}
+	return !deleteAt.After(now), nil
+}
+
+// versionNumber converts a version number from a Vault response to an int
+func versionNumber(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case json.Number:
+		n, err := v.Int64()
+		return int(n), err
+	case float64:
+		return int(v), nil
+	case int:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("unexpected version value %v", value)
+	}
+}
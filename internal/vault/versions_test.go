@@ -0,0 +1,280 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// testMounts are the KV versions of the mounts served by newKVServer
+var testMounts = map[string]string{"secret": "2", "kv": "2", "kv1": "1"}
+
+// newKVServer starts a fake Vault serving handler and returns a client for it.
+// Mount lookups are answered from testMounts, using the first path segment as the mount.
+func newKVServer(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/") {
+			handler(w, r)
+			return
+		}
+
+		mount := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/"), "/", 2)[0]
+		version, ok := testMounts[mount]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data":{"path":"%s/","type":"kv","options":{"version":"%s"}}}`, mount, version)
+	}))
+	t.Cleanup(server.Close)
+
+	apiClient, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatalf("api.NewClient() error = %v", err)
+	}
+	apiClient.SetToken("test-token")
+
+	return &Client{
+		client: apiClient,
+		config: &ClientConfig{Addr: server.URL, Token: "test-token"},
+	}
+}
+
+func TestReadSecretVersions(t *testing.T) {
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/secret/metadata/apps/db":
+			w.Write([]byte(`{"data":{"current_version":4,"versions":{
+				"1":{"deletion_time":"","destroyed":false},
+				"2":{"deletion_time":"","destroyed":true},
+				"3":{"deletion_time":"2024-01-01T00:00:00Z","destroyed":false},
+				"4":{"deletion_time":"","destroyed":false}}}}`))
+		case r.URL.Path == "/v1/secret/data/apps/db" && r.URL.Query().Get("version") == "1":
+			w.Write([]byte(`{"data":{"data":{"password":"v1"}}}`))
+		case r.URL.Path == "/v1/secret/data/apps/db" && r.URL.Query().Get("version") == "4":
+			w.Write([]byte(`{"data":{"data":{"password":"v4"}}}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	})
+
+	versions, err := client.ReadSecretVersions("secret/data/apps/db", logger.NewLogger(&config.Config{}))
+	if err != nil {
+		t.Fatalf("ReadSecretVersions() error = %v", err)
+	}
+
+	if len(versions) != 4 {
+		t.Fatalf("ReadSecretVersions() returned %d versions, want 4", len(versions))
+	}
+
+	for i, version := range versions {
+		if version.Version != i+1 {
+			t.Errorf("versions[%d].Version = %d, want %d", i, version.Version, i+1)
+		}
+	}
+
+	if versions[0].Data["password"] != "v1" || versions[3].Data["password"] != "v4" {
+		t.Errorf("unexpected version data: %v, %v", versions[0].Data, versions[3].Data)
+	}
+	if !versions[1].Destroyed || versions[1].Data != nil {
+		t.Errorf("version 2 = %+v, want destroyed without data", versions[1])
+	}
+	if !versions[2].Deleted || versions[2].Data != nil {
+		t.Errorf("version 3 = %+v, want deleted without data", versions[2])
+	}
+}
+
+func TestReadSecretVersionsScheduledDeletion(t *testing.T) {
+	// delete_version_after sets a future deletion_time on every live version
+	future := time.Now().Add(72 * time.Hour).UTC().Format(time.RFC3339Nano)
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/secret/metadata/apps/db":
+			fmt.Fprintf(w, `{"data":{"current_version":2,"versions":{
+				"1":{"deletion_time":"2024-01-01T00:00:00Z","destroyed":false},
+				"2":{"deletion_time":"%s","destroyed":false}}}}`, future)
+		case r.URL.Path == "/v1/secret/data/apps/db" && r.URL.Query().Get("version") == "2":
+			w.Write([]byte(`{"data":{"data":{"password":"v2"}}}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	})
+	log := logger.NewLogger(&config.Config{})
+
+	versions, err := client.ReadSecretVersions("secret/data/apps/db", log)
+	if err != nil {
+		t.Fatalf("ReadSecretVersions() error = %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("ReadSecretVersions() returned %d versions, want 2", len(versions))
+	}
+	if !versions[0].Deleted {
+		t.Errorf("version 1 = %+v, want deleted", versions[0])
+	}
+	if versions[1].Deleted || versions[1].Data["password"] != "v2" {
+		t.Errorf("version 2 = %+v, want live with its data", versions[1])
+	}
+
+	// The live version is written with its data and not deleted afterwards
+	var writes []map[string]interface{}
+	var deletes int
+	dest := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/kv/data/backup/db":
+			writes = append(writes, body["data"].(map[string]interface{}))
+			fmt.Fprintf(w, `{"data":{"version":%d}}`, len(writes))
+		case "/v1/kv/delete/backup/db":
+			deletes++
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	})
+
+	written, err := dest.WriteSecretVersions("kv/data/backup/db", versions, log)
+	if err != nil || written != 2 {
+		t.Fatalf("WriteSecretVersions() = %d, %v, want 2 versions written", written, err)
+	}
+	if deletes != 1 {
+		t.Errorf("deletes = %d, want only version 1 deleted", deletes)
+	}
+	if writes[1]["password"] != "v2" {
+		t.Errorf("version 2 written with %v, want its data", writes[1])
+	}
+}
+
+func TestWriteSecretVersions(t *testing.T) {
+	var mu sync.Mutex
+	var writes []map[string]interface{}
+	var deletes []interface{}
+	current := 10
+
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/kv/data/backup/db":
+			writes = append(writes, body["data"].(map[string]interface{}))
+			current++
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"version": current}})
+		case "/v1/kv/delete/backup/db":
+			deletes = append(deletes, body["versions"])
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	})
+
+	written, err := client.WriteSecretVersions("kv/data/backup/db", []*SecretVersion{
+		{Version: 1, Data: map[string]interface{}{"password": "v1"}},
+		{Version: 2, Destroyed: true},
+		{Version: 3, Deleted: true},
+		{Version: 4, Data: map[string]interface{}{"password": "v4"}},
+	}, logger.NewLogger(&config.Config{}))
+	if err != nil {
+		t.Fatalf("WriteSecretVersions() error = %v", err)
+	}
+
+	if written != 3 {
+		t.Errorf("WriteSecretVersions() = %d, want 3", written)
+	}
+
+	if len(writes) != 3 || writes[0]["password"] != "v1" || len(writes[1]) != 0 || writes[2]["password"] != "v4" {
+		t.Errorf("unexpected writes: %v", writes)
+	}
+
+	// The deleted source version 3 became destination version 12
+	if len(deletes) != 1 {
+		t.Fatalf("got %d delete requests, want 1", len(deletes))
+	}
+	if versions, ok := deletes[0].([]interface{}); !ok || len(versions) != 1 || versions[0] != float64(12) {
+		t.Errorf("deleted versions = %v, want [12]", deletes[0])
+	}
+}
+
+func TestWriteSecretVersionsKVv1(t *testing.T) {
+	var mu sync.Mutex
+	var writes []map[string]interface{}
+
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv1/backup/db" || r.Method != http.MethodPut {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		writes = append(writes, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	written, err := client.WriteSecretVersions("kv1/backup/db", []*SecretVersion{
+		{Version: 1, Data: map[string]interface{}{"password": "old"}},
+		{Version: 2, Data: map[string]interface{}{"password": "new"}},
+	}, logger.NewLogger(&config.Config{}))
+	if err != nil {
+		t.Fatalf("WriteSecretVersions() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if written != 1 || len(writes) != 1 {
+		t.Fatalf("written = %d, requests = %d, want only the latest version", written, len(writes))
+	}
+	if writes[0]["password"] != "new" {
+		t.Errorf("written data = %v, want latest version unwrapped", writes[0])
+	}
+}
+
+func TestReadSecretVersionsKVv1(t *testing.T) {
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv1/apps/db" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"password":"current"}}`))
+	})
+
+	versions, err := client.ReadSecretVersions("kv1/apps/db", logger.NewLogger(&config.Config{}))
+	if err != nil {
+		t.Fatalf("ReadSecretVersions() error = %v", err)
+	}
+
+	if len(versions) != 1 || versions[0].Version != 1 || versions[0].Data["password"] != "current" {
+		t.Errorf("versions = %+v, want the current value as version 1", versions)
+	}
+}
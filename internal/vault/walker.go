@@ -0,0 +1,225 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+)
+
+// PathError is the error of a walk that failed to list a folder or read a secret at Path
+type PathError struct {
+	Path string
+	Err  error
+}
+
+// Error returns the path followed by the underlying error
+func (e *PathError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// walkTask is a folder to list or a secret to read during a walk
+type walkTask struct {
+	path   string
+	folder bool
+}
+
+// walkQueue is the queue of pending walk tasks shared by the readers of a walk.
+// It is unbounded, so a reader listing a folder never blocks on the other readers.
+type walkQueue struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	tasks []walkTask
+	// pending counts the queued tasks plus the ones being processed
+	pending int
+	// stopped is set when the walk is aborted
+	stopped bool
+}
+
+// newWalkQueue creates a queue holding the given tasks
+func newWalkQueue(tasks ...walkTask) *walkQueue {
+	q := &walkQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	q.push(tasks...)
+	return q
+}
+
+// push adds tasks to the queue
+func (q *walkQueue) push(tasks ...walkTask) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.tasks = append(q.tasks, tasks...)
+	q.pending += len(tasks)
+	q.cond.Broadcast()
+}
+
+// pop waits for the next task. It returns false when the walk is complete or stopped.
+func (q *walkQueue) pop() (walkTask, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// An empty queue with pending tasks means other readers are still listing folders
+	for len(q.tasks) == 0 && q.pending > 0 && !q.stopped {
+		q.cond.Wait()
+	}
+
+	if q.stopped || len(q.tasks) == 0 {
+		return walkTask{}, false
+	}
+
+	task := q.tasks[0]
+	q.tasks = q.tasks[1:]
+	return task, true
+}
+
+// done marks a popped task as processed, after any tasks it produced were pushed
+func (q *walkQueue) done() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending--
+	if q.pending == 0 {
+		q.cond.Broadcast()
+	}
+}
+
+// stop aborts the walk, waking up all waiting readers
+func (q *walkQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stopped = true
+	q.cond.Broadcast()
+}
+
+// readers returns the number of concurrent readers used by a walk
+func (c *Client) readers() int {
+	if c.config == nil || c.config.Readers < 1 {
+		return config.DefaultReadWorkers
+	}
+	return c.config.Readers
+}
+
+// walkSecrets walks the Vault hierarchy under path and sends the secrets to the secrets channel.
+// Folders and secrets are processed from a shared queue by a bounded number of readers.
+// LIST results tell folders (keys with a trailing slash) from secrets, so only the root
+// needs an IsDirectory check. A folder or secret below the root that can't be listed or read
+// is sent to the error channel as a *PathError and the walk goes on with the others;
+// an error at the root or the cancellation of ctx stops the walk.
+// The error channel must be drained while the walk is going on.
+func (c *Client) walkSecrets(ctx context.Context, path string, secretsChan chan<- *Secret, errChan chan<- error, logger *logger.Logger) {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	isDir, err := c.IsDirectory(path, logger)
+	if err != nil {
+		errChan <- err
+		return
+	}
+
+	queue := newWalkQueue(walkTask{path: path, folder: isDir})
+
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() {
+			// Nobody may be reading anymore when the caller cancelled the walk
+			select {
+			case errChan <- err:
+			case <-parent.Done():
+			}
+			cancel()
+			queue.stop()
+		})
+	}
+
+	report := func(task walkTask, err error) {
+		var pathErr *PathError
+		if task.path == path || ctx.Err() != nil || !errors.As(err, &pathErr) {
+			fail(err)
+			return
+		}
+
+		logger.Verbose("Walk goes on after error: %v", err)
+		select {
+		case errChan <- err:
+		case <-ctx.Done():
+		}
+	}
+
+	readers := c.readers()
+	logger.Verbose("Walking %s with %d readers", path, readers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				task, ok := queue.pop()
+				if !ok {
+					return
+				}
+				if err := c.walkTask(ctx, task, queue, secretsChan, logger); err != nil {
+					report(task, err)
+				}
+				queue.done()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// walkTask lists a folder, queueing its entries, or reads a secret and sends it to the secrets channel
+func (c *Client) walkTask(ctx context.Context, task walkTask, queue *walkQueue, secretsChan chan<- *Secret, logger *logger.Logger) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !task.folder {
+		secret, err := c.ReadSecret(task.path, logger)
+		if errors.Is(err, ErrSecretDeleted) {
+			if !c.sendDeleted.Load() {
+				// A soft-deleted secret has no data to copy, it doesn't fail the walk
+				logger.Info("Skipping secret whose latest version is deleted: %s", task.path)
+				c.deleted.Add(1)
+				return nil
+			}
+			// Its earlier versions can still be replayed
+			secret, err = &Secret{Path: task.path, Deleted: true}, nil
+		}
+		if err != nil {
+			return &PathError{Path: task.path, Err: err}
+		}
+
+		select {
+		case secretsChan <- secret:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	items, err := c.ListSecrets(task.path, logger)
+	if err != nil {
+		return &PathError{Path: task.path, Err: err}
+	}
+
+	tasks := make([]walkTask, 0, len(items))
+	for _, item := range items {
+		// A key can name both a secret and a folder, LIST returns them as "name" and "name/"
+		tasks = append(tasks, walkTask{
+			path:   BuildPath(task.path, strings.TrimSuffix(item, "/")),
+			folder: strings.HasSuffix(item, "/"),
+		})
+	}
+	queue.push(tasks...)
+
+	return nil
+}
@@ -0,0 +1,268 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+)
+
+// walkTree is the KV v2 tree served by newWalkServer: folder listings and secret values
+var walkTree = map[string][]string{
+	"apps":      {"db", "web", "web/", "team/"},
+	"apps/web":  {"config"},
+	"apps/team": {"a", "b", "c", "d", "e", "f"},
+}
+
+// newWalkServer serves walkTree under secret/ and counts the LIST requests per path.
+// Every request takes a few milliseconds so concurrent readers overlap.
+func newWalkServer(t *testing.T, readers int) (*Client, *sync.Map, *int64) {
+	t.Helper()
+
+	var lists sync.Map
+	var inFlight, maxInFlight int64
+
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt64(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt64(&maxInFlight, seen, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/metadata/") && r.URL.Query().Get("list") == "true":
+			folder := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata/"), "/")
+			count, _ := lists.LoadOrStore(folder, new(int64))
+			atomic.AddInt64(count.(*int64), 1)
+
+			keys, ok := walkTree[folder]
+			if !ok {
+				// Vault answers a LIST of a secret with an empty JSON 404
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			quoted := make([]string, len(keys))
+			for i, key := range keys {
+				quoted[i] = `"` + key + `"`
+			}
+			fmt.Fprintf(w, `{"data":{"keys":[%s]}}`, strings.Join(quoted, ","))
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+			name := strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")
+			fmt.Fprintf(w, `{"data":{"data":{"name":"%s"}}}`, name)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	client.config.Readers = readers
+
+	return client, &lists, &maxInFlight
+}
+
+// walkPaths collects the paths of all secrets and all errors returned by GetAllSecrets
+func walkPaths(t *testing.T, client *Client, root string) ([]string, []error) {
+	t.Helper()
+
+	secrets, errs := client.GetAllSecrets(context.Background(), root, logger.NewLogger(&config.Config{}))
+
+	var paths []string
+	var walkErrs []error
+	for secrets != nil || errs != nil {
+		select {
+		case secret, ok := <-secrets:
+			if !ok {
+				secrets = nil
+				continue
+			}
+			paths = append(paths, secret.Path)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			walkErrs = append(walkErrs, err)
+		}
+	}
+
+	sort.Strings(paths)
+	return paths, walkErrs
+}
+
+func TestWalkSecrets(t *testing.T) {
+	client, lists, _ := newWalkServer(t, 3)
+
+	paths, errs := walkPaths(t, client, "secret/data/apps")
+	if len(errs) > 0 {
+		t.Fatalf("GetAllSecrets() errors = %v", errs)
+	}
+
+	want := []string{
+		"secret/data/apps/db",
+		"secret/data/apps/team/a",
+		"secret/data/apps/team/b",
+		"secret/data/apps/team/c",
+		"secret/data/apps/team/d",
+		"secret/data/apps/team/e",
+		"secret/data/apps/team/f",
+		"secret/data/apps/web",
+		"secret/data/apps/web/config",
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("GetAllSecrets() paths = %v, want %v", paths, want)
+	}
+
+	// Only the root is listed twice: once by the IsDirectory check and once by the walk.
+	// Secrets are told from folders by the trailing slash, never by listing them.
+	wantLists := map[string]int64{"apps": 2, "apps/web": 1, "apps/team": 1}
+	lists.Range(func(key, value interface{}) bool {
+		if got := atomic.LoadInt64(value.(*int64)); got != wantLists[key.(string)] {
+			t.Errorf("LIST %s requested %d times, want %d", key, got, wantLists[key.(string)])
+		}
+		return true
+	})
+	for folder := range wantLists {
+		if _, ok := lists.Load(folder); !ok {
+			t.Errorf("LIST %s was never requested", folder)
+		}
+	}
+}
+
+func TestWalkSecretsBoundedReaders(t *testing.T) {
+	client, _, maxInFlight := newWalkServer(t, 2)
+
+	if _, errs := walkPaths(t, client, "secret/data/apps"); len(errs) > 0 {
+		t.Fatalf("GetAllSecrets() errors = %v", errs)
+	}
+
+	if got := atomic.LoadInt64(maxInFlight); got > 2 {
+		t.Errorf("max concurrent requests = %d, want at most 2", got)
+	}
+}
+
+func TestWalkSecretsSingleSecret(t *testing.T) {
+	client, _, _ := newWalkServer(t, 2)
+
+	paths, errs := walkPaths(t, client, "secret/data/apps/db")
+	if len(errs) > 0 {
+		t.Fatalf("GetAllSecrets() errors = %v", errs)
+	}
+
+	if len(paths) != 1 || paths[0] != "secret/data/apps/db" {
+		t.Errorf("GetAllSecrets() paths = %v, want [secret/data/apps/db]", paths)
+	}
+}
+
+func TestWalkSecretsContinuesAfterError(t *testing.T) {
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/secret/metadata/apps" && r.URL.Query().Get("list") == "true":
+			w.Write([]byte(`{"data":{"keys":["a","broken/","denied","team/","z"]}}`))
+		case r.URL.Path == "/v1/secret/metadata/apps/team" && r.URL.Query().Get("list") == "true":
+			w.Write([]byte(`{"data":{"keys":["db"]}}`))
+		case r.URL.Path == "/v1/secret/metadata/apps/broken", r.URL.Path == "/v1/secret/data/apps/denied":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/data/apps/"):
+			w.Write([]byte(`{"data":{"data":{"key":"value"}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	paths, errs := walkPaths(t, client, "secret/data/apps")
+
+	// The unreadable folder and secret don't stop the walk of the others
+	want := []string{"secret/data/apps/a", "secret/data/apps/team/db", "secret/data/apps/z"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("GetAllSecrets() paths = %v, want %v", paths, want)
+	}
+
+	var failed []string
+	for _, err := range errs {
+		var pathErr *PathError
+		if !errors.As(err, &pathErr) {
+			t.Fatalf("GetAllSecrets() error = %v, want a *PathError", err)
+		}
+		failed = append(failed, pathErr.Path)
+	}
+	sort.Strings(failed)
+	if want := []string{"secret/data/apps/broken", "secret/data/apps/denied"}; strings.Join(failed, ",") != strings.Join(want, ",") {
+		t.Errorf("failed paths = %v, want %v", failed, want)
+	}
+}
+
+func TestWalkSecretsStopsOnRootError(t *testing.T) {
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errors":["permission denied"]}`))
+	})
+
+	paths, errs := walkPaths(t, client, "secret/data/apps")
+	if len(errs) != 1 || len(paths) != 0 {
+		t.Errorf("GetAllSecrets() paths = %v, errors = %v, want one error and no secrets", paths, errs)
+	}
+}
+
+func TestWalkSecretsSkipsDeletedSecrets(t *testing.T) {
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/secret/metadata/apps" && r.URL.Query().Get("list") == "true":
+			w.Write([]byte(`{"data":{"keys":["a","deleted","z"]}}`))
+		case r.URL.Path == "/v1/secret/data/apps/deleted":
+			// Vault answers a read of a soft-deleted latest version with a 404 holding the metadata only
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"data":{"data":null,"metadata":{"deletion_time":"2024-05-01T10:00:00Z","destroyed":false,"version":2}}}`))
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/data/apps/"):
+			w.Write([]byte(`{"data":{"data":{"key":"value"}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	if _, err := client.ReadSecret("secret/data/apps/deleted", logger.NewLogger(&config.Config{})); !errors.Is(err, ErrSecretDeleted) {
+		t.Fatalf("ReadSecret() error = %v, want ErrSecretDeleted", err)
+	}
+
+	paths, errs := walkPaths(t, client, "secret/data/apps")
+	if len(errs) > 0 {
+		t.Fatalf("GetAllSecrets() errors = %v", errs)
+	}
+
+	if want := []string{"secret/data/apps/a", "secret/data/apps/z"}; strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("GetAllSecrets() paths = %v, want %v", paths, want)
+	}
+	// Counted once by the walk, the direct read above isn't a walk
+	if got := client.DeletedSkipped(); got != 1 {
+		t.Errorf("DeletedSkipped() = %d, want 1", got)
+	}
+
+	// Asked to, the walk sends the deleted secret so that its history can be replayed
+	client.SendDeletedSecrets(true)
+	paths, errs = walkPaths(t, client, "secret/data/apps")
+	if len(errs) > 0 {
+		t.Fatalf("GetAllSecrets() errors = %v", errs)
+	}
+	if want := []string{"secret/data/apps/a", "secret/data/apps/deleted", "secret/data/apps/z"}; strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("GetAllSecrets() paths = %v, want %v", paths, want)
+	}
+	if got := client.DeletedSkipped(); got != 1 {
+		t.Errorf("DeletedSkipped() = %d, want still 1", got)
+	}
+}
@@ -233,8 +233,9 @@ func (c *Client) getAllPathsUnder(rootPath string, logger *logger.Logger) ([]str
 
 	var allPaths []string
 
-	// Use GetAllSecrets to get all secrets under this path
-	ctx := context.Background()
+	// Use GetAllSecrets to get all secrets under this path, the walk is stopped when returning early
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	secretsChan, errChan := c.GetAllSecrets(ctx, rootPath, logger)
 
 	// Collect all secrets
@@ -271,3 +272,39 @@ done:
 	logger.Verbose("Found %d paths under %s", len(allPaths), rootPath)
 	return allPaths, nil
 }
+
+// MatchWildcard returns the paths matching pattern for backends that know all their secret paths.
+// The pattern is matched segment by segment against the beginning of every path, so a match
+// is a secret or, when the path is longer than the pattern, a folder. Matches are returned once, in order.
+func MatchWildcard(pattern string, paths []string) ([]string, error) {
+	patternParts := strings.Split(strings.TrimSuffix(pattern, "/"), "/")
+
+	var matches []string
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		parts := strings.Split(p, "/")
+		if len(parts) < len(patternParts) {
+			continue
+		}
+
+		matched := true
+		for i, patternPart := range patternParts {
+			ok, err := filepath.Match(patternPart, parts[i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid wildcard pattern %s: %v", pattern, err)
+			}
+			if !ok {
+				matched = false
+				break
+			}
+		}
+
+		candidate := strings.Join(parts[:len(patternParts)], "/")
+		if matched && !seen[candidate] {
+			seen[candidate] = true
+			matches = append(matches, candidate)
+		}
+	}
+
+	return matches, nil
+}
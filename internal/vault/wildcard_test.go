@@ -1,10 +1,12 @@
 package vault_test
 
 import (
+	"reflect"
 	"testing"
 
 	"vault-copy/internal/config"
 	"vault-copy/internal/sync"
+	"vault-copy/internal/vault"
 	"vault-copy/mocks"
 )
 
@@ -164,3 +166,32 @@ func TestTransformPathWithWildcard(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchWildcard(t *testing.T) {
+	paths := []string{
+		"secret/apps/app1/postgres",
+		"secret/apps/app1/postgresql/main",
+		"secret/apps/app1/redis",
+		"secret/apps/app2/postgres",
+	}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"secret/apps/app1/postgre*", []string{"secret/apps/app1/postgres", "secret/apps/app1/postgresql"}},
+		{"secret/apps/*/postgres", []string{"secret/apps/app1/postgres", "secret/apps/app2/postgres"}},
+		{"secret/apps/app*", []string{"secret/apps/app1", "secret/apps/app2"}},
+		{"secret/apps/app3/*", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := vault.MatchWildcard(tt.pattern, paths)
+		if err != nil {
+			t.Fatalf("MatchWildcard(%s) error = %v", tt.pattern, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MatchWildcard(%s) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -5,27 +5,28 @@ import (
 	"fmt"
 	"strings"
 	"vault-copy/internal/logger"
+
+	"github.com/hashicorp/vault/api"
 )
 
+// casRequiredError is the error of a KV v2 write without check-and-set to a secret or mount requiring it
+const casRequiredError = "check-and-set parameter required"
+
 // WriteSecret writes a secret to Vault at the specified path.
 // For KV v2, it wraps the data in a "data" key as required by the API.
 // For KV v1, it writes the data directly.
 func (c *Client) WriteSecret(path string, data map[string]interface{}, logger *logger.Logger) error {
-	// Determine if this is a KV v2 path (contains /data/)
-	var writeData map[string]interface{}
+	writePath, version := c.apiPath(path, "data", logger)
 
-	if strings.Contains(path, "/data/") {
+	logger.Verbose("Writing secret to Vault: %s", writePath)
+	var err error
+	if version == 2 {
 		// For KV v2, we need to wrap the data
-		writeData = map[string]interface{}{
-			"data": data,
-		}
+		_, err = c.writeData(path, writePath, data, logger)
 	} else {
 		// For KV v1 or other engines, write data directly
-		writeData = data
+		_, err = c.client.Logical().Write(writePath, data)
 	}
-
-	logger.Verbose("Writing secret to Vault: %s", path)
-	_, err := c.client.Logical().Write(path, writeData)
 	if err != nil {
 		logger.Error("Error writing secret %s: %v", path, err)
 		return fmt.Errorf("error writing secret %s: %v", path, err)
@@ -35,11 +36,76 @@ func (c *Client) WriteSecret(path string, data map[string]interface{}, logger *l
 	return nil
 }
 
+// writeData writes data as a new version of the KV v2 secret at path.
+// When the secret or its mount requires check-and-set, the write is repeated with the current version as cas:
+// the destination is replaced whatever its version, as for any other secret.
+func (c *Client) writeData(path, dataPath string, data map[string]interface{}, logger *logger.Logger) (*api.Secret, error) {
+	secret, err := c.client.Logical().Write(dataPath, map[string]interface{}{
+		"data": data,
+	})
+	if err == nil || !strings.Contains(err.Error(), casRequiredError) {
+		return secret, err
+	}
+
+	current, err := c.currentVersion(path, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Verbose("Secret %s requires check-and-set, writing over version %d", path, current)
+	return c.client.Logical().Write(dataPath, map[string]interface{}{
+		"data":    data,
+		"options": map[string]interface{}{"cas": current},
+	})
+}
+
+// currentVersion returns the current version of the KV v2 secret at path, 0 when it doesn't exist
+func (c *Client) currentVersion(path string, logger *logger.Logger) (int, error) {
+	metadataPath, _ := c.apiPath(path, "metadata", logger)
+
+	secret, err := c.client.Logical().Read(metadataPath)
+	if err != nil {
+		return 0, fmt.Errorf("error reading secret metadata %s: %v", metadataPath, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return 0, nil
+	}
+
+	current, err := versionNumber(secret.Data["current_version"])
+	if err != nil {
+		return 0, fmt.Errorf("invalid current_version in %s: %v", metadataPath, err)
+	}
+	return current, nil
+}
+
+// DeleteSecret deletes the secret at the specified path in Vault.
+// For KV v2, the latest version is soft-deleted, or with destroy the metadata
+// and all versions are removed. KV v1 secrets are always deleted permanently.
+func (c *Client) DeleteSecret(path string, destroy bool, logger *logger.Logger) error {
+	endpoint := "data"
+	if destroy {
+		endpoint = "metadata"
+	}
+	deletePath, _ := c.apiPath(path, endpoint, logger)
+
+	logger.Verbose("Deleting secret from Vault: %s", deletePath)
+	_, err := c.client.Logical().Delete(deletePath)
+	if err != nil {
+		logger.Error("Error deleting secret %s: %v", path, err)
+		return fmt.Errorf("error deleting secret %s: %v", path, err)
+	}
+
+	logger.Verbose("Successfully deleted secret: %s", path)
+	return nil
+}
+
 // SecretExists checks if a secret exists at the specified path in Vault.
 // It returns true if the secret exists, false otherwise.
 func (c *Client) SecretExists(path string, logger *logger.Logger) (bool, error) {
-	logger.Verbose("Checking secret existence: %s", path)
-	secret, err := c.client.Logical().Read(path)
+	readPath, _ := c.apiPath(path, "data", logger)
+
+	logger.Verbose("Checking secret existence: %s", readPath)
+	secret, err := c.client.Logical().Read(readPath)
 	if err != nil {
 		logger.Error("Error checking secret existence %s: %v", path, err)
 		return false, err
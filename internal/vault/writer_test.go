@@ -0,0 +1,52 @@
+package vault
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"vault-copy/internal/config"
+	"vault-copy/internal/logger"
+)
+
+func TestDeleteSecret(t *testing.T) {
+	var mu sync.Mutex
+	var deleted []string
+
+	client := newKVServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		deleted = append(deleted, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	log := logger.NewLogger(&config.Config{})
+
+	tests := []struct {
+		path    string
+		destroy bool
+		want    string
+	}{
+		{"secret/apps/db", false, "/v1/secret/data/apps/db"},
+		{"secret/data/apps/db", true, "/v1/secret/metadata/apps/db"},
+		{"kv1/apps/db", true, "/v1/kv1/apps/db"},
+	}
+
+	for _, tt := range tests {
+		if err := client.DeleteSecret(tt.path, tt.destroy, log); err != nil {
+			t.Fatalf("DeleteSecret(%q, %t) error = %v", tt.path, tt.destroy, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, tt := range tests {
+		if i >= len(deleted) || deleted[i] != tt.want {
+			t.Errorf("DeleteSecret(%q, %t) requested %v, want %s", tt.path, tt.destroy, deleted, tt.want)
+		}
+	}
+}
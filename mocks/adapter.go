@@ -66,3 +66,43 @@ func (a *Adapter) GetKVEngine(path string) (string, error) {
 func (a *Adapter) GetKVEngineVersion(engine string, logger *logger.Logger) (int, error) {
 	return a.client.GetKVEngineVersion(engine, logger)
 }
+
+// ReadSecretVersions implements the vault.VersionReader interface
+func (a *Adapter) ReadSecretVersions(path string, logger *logger.Logger) ([]*vault.SecretVersion, error) {
+	return a.client.ReadSecretVersions(path, logger)
+}
+
+// WriteSecretVersions implements the vault.VersionWriter interface
+func (a *Adapter) WriteSecretVersions(path string, versions []*vault.SecretVersion, logger *logger.Logger) (int, error) {
+	return a.client.WriteSecretVersions(path, versions, logger)
+}
+
+// ReadSecretMetadata implements the vault.MetadataReader interface
+func (a *Adapter) ReadSecretMetadata(path string, logger *logger.Logger) (*vault.SecretMetadata, error) {
+	return a.client.ReadSecretMetadata(path, logger)
+}
+
+// WriteSecretMetadata implements the vault.MetadataWriter interface
+func (a *Adapter) WriteSecretMetadata(path string, metadata *vault.SecretMetadata, logger *logger.Logger) error {
+	return a.client.WriteSecretMetadata(path, metadata, logger)
+}
+
+// DeleteSecret implements the vault.Deleter interface
+func (a *Adapter) DeleteSecret(path string, destroy bool, logger *logger.Logger) error {
+	return a.client.DeleteSecret(path, destroy, logger)
+}
+
+// Retries implements the vault.RetryCounter interface
+func (a *Adapter) Retries() int64 {
+	return a.client.Retries()
+}
+
+// SendDeletedSecrets implements the vault.DeletedSender interface
+func (a *Adapter) SendDeletedSecrets(send bool) {
+	a.client.SendDeletedSecrets(send)
+}
+
+// SetRequestObserver implements the vault.RequestReporter interface
+func (a *Adapter) SetRequestObserver(observer vault.RequestObserver) {
+	a.client.SetRequestObserver(observer)
+}
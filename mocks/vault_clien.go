@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -20,6 +21,12 @@ type MockClient struct {
 	ReadErrors  map[string]error
 	ListErrors  map[string]error
 	CheckErrors map[string]error
+	Versions    map[string][]*vault.SecretVersion
+	Metadata    map[string]*vault.SecretMetadata
+	Deleted     map[string]bool
+	RetryCount  int64
+	Observer    vault.RequestObserver
+	SendDeleted bool
 
 	mu sync.RWMutex
 }
@@ -33,6 +40,9 @@ func NewMockClient() *MockClient {
 		ReadErrors:  make(map[string]error),
 		ListErrors:  make(map[string]error),
 		CheckErrors: make(map[string]error),
+		Versions:    make(map[string][]*vault.SecretVersion),
+		Metadata:    make(map[string]*vault.SecretMetadata),
+		Deleted:     make(map[string]bool),
 	}
 }
 
@@ -47,6 +57,11 @@ func (m *MockClient) ReadSecret(path string, logger *logger.Logger) (*vault.Secr
 
 	secret, ok := m.Secrets[path]
 	if !ok {
+		if versions := m.Versions[path]; len(versions) > 0 {
+			if latest := versions[len(versions)-1]; latest.Deleted || latest.Destroyed {
+				return nil, fmt.Errorf("%w: %s", vault.ErrSecretDeleted, path)
+			}
+		}
 		return nil, nil
 	}
 
@@ -153,7 +168,7 @@ func (m *MockClient) collectSecrets(ctx context.Context, rootPath string, secret
 			select {
 			case <-ctx.Done():
 				errChan <- ctx.Err()
-			case errChan <- err:
+			case errChan <- &vault.PathError{Path: rootPath, Err: err}:
 			}
 			return
 		}
@@ -174,11 +189,20 @@ func (m *MockClient) collectSecrets(ctx context.Context, rootPath string, secret
 	} else if !isSecret {
 		// If not a directory and not already sent as secret, try to read it as a secret
 		secret, err := m.ReadSecret(rootPath, nil)
+		if errors.Is(err, vault.ErrSecretDeleted) {
+			m.mu.RLock()
+			send := m.SendDeleted
+			m.mu.RUnlock()
+			if !send {
+				return
+			}
+			secret, err = &vault.Secret{Path: rootPath, Deleted: true}, nil
+		}
 		if err != nil {
 			select {
 			case <-ctx.Done():
 				errChan <- ctx.Err()
-			case errChan <- err:
+			case errChan <- &vault.PathError{Path: rootPath, Err: err}:
 			}
 			return
 		}
@@ -258,6 +282,129 @@ func (m *MockClient) BatchWriteSecrets(ctx context.Context, secrets <-chan *vaul
 	return errChan
 }
 
+// ReadSecretVersions returns the versions registered with AddSecretVersions,
+// or the current secret as version 1
+func (m *MockClient) ReadSecretVersions(path string, logger *logger.Logger) ([]*vault.SecretVersion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if err, ok := m.ReadErrors[path]; ok {
+		return nil, err
+	}
+
+	if versions, ok := m.Versions[path]; ok {
+		return versions, nil
+	}
+
+	secret, ok := m.Secrets[path]
+	if !ok {
+		return nil, fmt.Errorf("secret not found: %s", path)
+	}
+
+	return []*vault.SecretVersion{{Version: 1, Data: secret.Data}}, nil
+}
+
+// WriteSecretVersions appends the versions to the history of path, skipping destroyed ones
+func (m *MockClient) WriteSecretVersions(path string, versions []*vault.SecretVersion, logger *logger.Logger) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err, ok := m.WriteErrors[path]; ok {
+		return 0, err
+	}
+
+	written := 0
+	for _, version := range versions {
+		if version.Destroyed {
+			continue
+		}
+
+		history := m.Versions[path]
+		m.Versions[path] = append(history, &vault.SecretVersion{
+			Version: len(history) + 1,
+			Data:    version.Data,
+			Deleted: version.Deleted,
+		})
+		written++
+
+		if version.Deleted {
+			delete(m.Secrets, path)
+		} else {
+			m.Secrets[path] = &vault.Secret{
+				Path: path,
+				Data: version.Data,
+			}
+		}
+	}
+
+	return written, nil
+}
+
+// ReadSecretMetadata returns the metadata registered with SetSecretMetadata
+func (m *MockClient) ReadSecretMetadata(path string, logger *logger.Logger) (*vault.SecretMetadata, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if err, ok := m.ReadErrors[path]; ok {
+		return nil, err
+	}
+
+	metadata, ok := m.Metadata[path]
+	if !ok {
+		return nil, fmt.Errorf("secret metadata not found: %s", path)
+	}
+
+	return metadata, nil
+}
+
+// WriteSecretMetadata stores the metadata of path
+func (m *MockClient) WriteSecretMetadata(path string, metadata *vault.SecretMetadata, logger *logger.Logger) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err, ok := m.WriteErrors[path]; ok {
+		return err
+	}
+
+	m.Metadata[path] = metadata
+	return nil
+}
+
+// SetSecretMetadata registers the metadata of a secret
+func (m *MockClient) SetSecretMetadata(path string, metadata *vault.SecretMetadata) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Metadata[path] = metadata
+}
+
+// DeleteSecret removes the secret and records the deletion with its destroy flag in Deleted
+func (m *MockClient) DeleteSecret(path string, destroy bool, logger *logger.Logger) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err, ok := m.WriteErrors[path]; ok {
+		return err
+	}
+
+	delete(m.Secrets, path)
+	m.Deleted[path] = destroy
+	return nil
+}
+
+// Retries returns RetryCount, the number of retries the test pretends the client made
+func (m *MockClient) Retries() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.RetryCount
+}
+
+// SetRequestObserver records the observer in Observer
+func (m *MockClient) SetRequestObserver(observer vault.RequestObserver) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Observer = observer
+}
+
 func (m *MockClient) SetReadError(path string, err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -286,6 +433,34 @@ func (m *MockClient) AddSecret(path string, data map[string]interface{}) {
 	}
 }
 
+// AddSecretVersions registers the version history of a secret. The latest
+// version becomes the current secret; when it is deleted or destroyed, reads
+// of the secret fail with vault.ErrSecretDeleted.
+func (m *MockClient) AddSecretVersions(path string, versions []*vault.SecretVersion) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Versions[path] = versions
+	delete(m.Secrets, path)
+	if len(versions) == 0 {
+		return
+	}
+	if latest := versions[len(versions)-1]; !latest.Deleted && !latest.Destroyed {
+		m.Secrets[path] = &vault.Secret{
+			Path: path,
+			Data: latest.Data,
+		}
+	}
+}
+
+// SendDeletedSecrets implements the vault.DeletedSender interface
+func (m *MockClient) SendDeletedSecrets(send bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.SendDeleted = send
+}
+
 func (m *MockClient) AddDirectory(path string, items []string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
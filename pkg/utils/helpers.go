@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/json"
+	"reflect"
 	"strings"
 )
 
@@ -81,3 +84,82 @@ func SplitPath(path string) []string {
 		return r == '/'
 	})
 }
+
+// NormalizeJSON round-trips a value through JSON so that equal values get equal Go types,
+// e.g. json.Number, int and float64 all become float64 and typed maps become map[string]interface{}
+func NormalizeJSON(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// PlainValue round-trips a value through JSON so that it holds only plain Go values:
+// json.Number becomes int64 when it is an integer and float64 otherwise, typed maps and slices
+// become map[string]interface{} and []interface{}. Unlike NormalizeJSON, integers keep their precision.
+// It is meant for encoders such as YAML that would write json.Number as a quoted string.
+func PlainValue(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return convertNumbers(value), nil
+}
+
+// convertNumbers replaces the json.Number values of a decoded JSON value in place
+func convertNumbers(v interface{}) interface{} {
+	switch value := v.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = convertNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = convertNumbers(item)
+		}
+	}
+	return v
+}
+
+// EqualData reports whether two secret data maps are deeply equal after JSON normalization.
+// A nil map and an empty map are equal.
+func EqualData(a, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return EqualValue(a, b)
+}
+
+// EqualValue reports whether two values are deeply equal after JSON normalization
+func EqualValue(a, b interface{}) bool {
+	normalizedA, err := NormalizeJSON(a)
+	if err != nil {
+		return false
+	}
+
+	normalizedB, err := NormalizeJSON(b)
+	if err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(normalizedA, normalizedB)
+}
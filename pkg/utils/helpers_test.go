@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 )
@@ -231,3 +232,83 @@ func TestMergeMaps(t *testing.T) {
 		})
 	}
 }
+
+func TestEqualData(t *testing.T) {
+	tests := []struct {
+		name string
+		a    map[string]interface{}
+		b    map[string]interface{}
+		want bool
+	}{
+		{
+			name: "equal strings",
+			a:    map[string]interface{}{"user": "admin", "password": "secret"},
+			b:    map[string]interface{}{"password": "secret", "user": "admin"},
+			want: true,
+		},
+		{
+			name: "different value",
+			a:    map[string]interface{}{"password": "old"},
+			b:    map[string]interface{}{"password": "new"},
+			want: false,
+		},
+		{
+			name: "extra key",
+			a:    map[string]interface{}{"user": "admin"},
+			b:    map[string]interface{}{"user": "admin", "password": "secret"},
+			want: false,
+		},
+		{
+			name: "numbers of different types",
+			a:    map[string]interface{}{"port": 5432, "ratio": json.Number("0.5")},
+			b:    map[string]interface{}{"port": float64(5432), "ratio": 0.5},
+			want: true,
+		},
+		{
+			name: "nested values",
+			a:    map[string]interface{}{"hosts": []string{"a", "b"}, "opts": map[string]string{"tls": "on"}},
+			b:    map[string]interface{}{"hosts": []interface{}{"a", "b"}, "opts": map[string]interface{}{"tls": "on"}},
+			want: true,
+		},
+		{
+			name: "nil and empty",
+			a:    nil,
+			b:    map[string]interface{}{},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EqualData(tt.a, tt.b); got != tt.want {
+				t.Errorf("EqualData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainValue(t *testing.T) {
+	value := map[string]interface{}{
+		"port":  json.Number("5432"),
+		"ratio": json.Number("0.5"),
+		"big":   json.Number("9007199254740993"),
+		"list":  []string{"a", "b"},
+		"name":  "db",
+	}
+
+	got, err := PlainValue(value)
+	if err != nil {
+		t.Fatalf("PlainValue() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"port":  int64(5432),
+		"ratio": 0.5,
+		"big":   int64(9007199254740993),
+		"list":  []interface{}{"a", "b"},
+		"name":  "db",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PlainValue() = %#v, want %#v", got, want)
+	}
+}